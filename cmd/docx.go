@@ -37,11 +37,15 @@ func init() {
 	docxCmd.PersistentFlags().StringVarP(&Docx, "docx", "x", "", "Docx file")
 	docxCmd.PersistentFlags().StringVarP(&Url, "url", "u", "", "Page URL for metadata")
 	docxCmd.PersistentFlags().StringVarP(&CustomerIdDocx, "cid", "c", "docx", "Customer ID code ")
+	docxCmd.PersistentFlags().BoolVar(&docx2md.EmbedImages, "embed-images", false, "Embed images as base64 data instead of saving them in the assets folder")
+	docxCmd.PersistentFlags().StringVarP(&docx2md.Embeds, "embeds", "e", "link", "Embedded documents: link, inline or files")
+	docxCmd.PersistentFlags().BoolVarP(&docx2md.Toc, "toc", "t", false, "Regenerate a markdown table of contents from headings")
 }
 
 // getDocxDocument read docx and generate a markdown page with its metadatas
 func getDocxDocument(cmd *cobra.Command, args []string) {
 	var pages []tools.Page
+	docx2md.DescribeImages = ImgDesc
 	datas, err := docx2md.GetDocx(Docx, Url, CustomerIdDocx, ExportDir, tools.Metadata{})
 	tools.CheckError(err)
 	pages = append(pages, datas)
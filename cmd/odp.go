@@ -0,0 +1,52 @@
+// Copyright © 2024 Acquatella Stephan
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/sacquatella/tomd/docx2md"
+	"github.com/sacquatella/tomd/tools"
+	"github.com/spf13/cobra"
+)
+
+var Odp string
+var CustomerIdOdp string
+
+var odpCmd = &cobra.Command{
+	Use:   "odp",
+	Short: "Get OpenDocument presentation text content as a markdown file",
+	Long:  `Get odp text content and generate a markdown page with metadata's'.`,
+	Run:   getOdpDocument,
+}
+
+func init() {
+	rootCmd.AddCommand(odpCmd)
+	odpCmd.PersistentFlags().StringVarP(&Odp, "odp", "s", "", "Odp file")
+	odpCmd.PersistentFlags().StringVarP(&Url, "url", "u", "", "Page URL for metadata")
+	odpCmd.PersistentFlags().StringVarP(&CustomerIdOdp, "cid", "c", "odp", "Customer ID code ")
+	odpCmd.PersistentFlags().BoolVar(&docx2md.EmbedImages, "embed-images", false, "Embed images as base64 data instead of saving them in the assets folder")
+	odpCmd.PersistentFlags().BoolVarP(&docx2md.SkipHidden, "skip-hidden", "k", false, "Ignore hidden slides")
+	odpCmd.PersistentFlags().StringVarP(&docx2md.Notes, "notes", "n", "section", "Speaker notes: none, section or quote")
+	odpCmd.PersistentFlags().BoolVar(&docx2md.SlideNumbers, "slide-numbers", true, "Add slide numbers to slide headings")
+}
+
+// getOdpDocument read odp and generate a markdown page with its metadatas
+func getOdpDocument(cmd *cobra.Command, args []string) {
+	var pages []tools.Page
+	docx2md.DescribeImages = ImgDesc
+	datas, err := docx2md.GetOdp(Odp, Url, CustomerIdOdp, ExportDir, tools.Metadata{})
+	tools.CheckError(err)
+	pages = append(pages, datas)
+	tools.DisplayOnScreen(pages)
+}
@@ -0,0 +1,49 @@
+// Copyright © 2024 Acquatella Stephan
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/sacquatella/tomd/docx2md"
+	"github.com/sacquatella/tomd/tools"
+	"github.com/spf13/cobra"
+)
+
+var Ods string
+var CustomerIdOds string
+
+var odsCmd = &cobra.Command{
+	Use:   "ods",
+	Short: "Get ods sheets as markdown tables",
+	Long:  `Get ods sheets as markdown tables and generate a markdown page with metadata's'.`,
+	Run:   getOdsDocument,
+}
+
+func init() {
+	rootCmd.AddCommand(odsCmd)
+	odsCmd.PersistentFlags().StringVarP(&Ods, "ods", "l", "", "Ods file")
+	odsCmd.PersistentFlags().StringVarP(&Url, "url", "u", "", "Page URL for metadata")
+	odsCmd.PersistentFlags().StringVarP(&CustomerIdOds, "cid", "c", "ods", "Customer ID code ")
+	odsCmd.PersistentFlags().StringSliceVarP(&docx2md.Sheets, "sheets", "s", nil, "Sheets to convert, by name or number")
+	odsCmd.PersistentFlags().IntVarP(&docx2md.MaxRows, "max-rows", "m", 0, "Maximum number of rows per sheet, 0 for all")
+}
+
+// getOdsDocument read ods and generate a markdown page with its metadatas
+func getOdsDocument(cmd *cobra.Command, args []string) {
+	var pages []tools.Page
+	datas, err := docx2md.GetOds(Ods, Url, CustomerIdOds, ExportDir, tools.Metadata{})
+	tools.CheckError(err)
+	pages = append(pages, datas)
+	tools.DisplayOnScreen(pages)
+}
@@ -0,0 +1,50 @@
+// Copyright © 2024 Acquatella Stephan
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/sacquatella/tomd/docx2md"
+	"github.com/sacquatella/tomd/tools"
+	"github.com/spf13/cobra"
+)
+
+var Odt string
+var CustomerIdOdt string
+
+var odtCmd = &cobra.Command{
+	Use:   "odt",
+	Short: "Get OpenDocument text content as a markdown file",
+	Long:  `Get odt text content and generate a markdown page with metadata's'.`,
+	Run:   getOdtDocument,
+}
+
+func init() {
+	rootCmd.AddCommand(odtCmd)
+	odtCmd.PersistentFlags().StringVarP(&Odt, "odt", "o", "", "Odt file")
+	odtCmd.PersistentFlags().StringVarP(&Url, "url", "u", "", "Page URL for metadata")
+	odtCmd.PersistentFlags().StringVarP(&CustomerIdOdt, "cid", "c", "odt", "Customer ID code ")
+	odtCmd.PersistentFlags().BoolVar(&docx2md.EmbedImages, "embed-images", false, "Embed images as base64 data instead of saving them in the assets folder")
+	odtCmd.PersistentFlags().BoolVarP(&docx2md.Toc, "toc", "t", false, "Regenerate a markdown table of contents from headings")
+}
+
+// getOdtDocument read odt and generate a markdown page with its metadatas
+func getOdtDocument(cmd *cobra.Command, args []string) {
+	var pages []tools.Page
+	docx2md.DescribeImages = ImgDesc
+	datas, err := docx2md.GetOdt(Odt, Url, CustomerIdOdt, ExportDir, tools.Metadata{})
+	tools.CheckError(err)
+	pages = append(pages, datas)
+	tools.DisplayOnScreen(pages)
+}
@@ -15,12 +15,16 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/sacquatella/tomd/tools"
 	"github.com/spf13/cobra"
 )
 
 var Pdf string
 var CustomerIdPdf string
+var PdfProgress bool
 
 var pdfCmd = &cobra.Command{
 	Use:   "pdf",
@@ -34,14 +38,33 @@ func init() {
 	pdfCmd.PersistentFlags().StringVarP(&Pdf, "pdf", "p", "", "Pdf file")
 	pdfCmd.PersistentFlags().StringVarP(&Url, "url", "u", "", "Page URL for metadata")
 	pdfCmd.PersistentFlags().StringVarP(&CustomerIdPdf, "cid", "c", "pdf", "Customer ID code ")
+	pdfCmd.PersistentFlags().BoolVar(&tools.PdfPageMarkers, "page-markers", false, "Replace page headings by HTML comments")
+	pdfCmd.PersistentFlags().BoolVarP(&tools.PdfToc, "toc", "t", false, "Write a markdown table of contents from the PDF outline")
+	pdfCmd.PersistentFlags().StringVar(&tools.PdfTables, "pdf-tables", "lines", "Tables detection: off, lines (ruled tables) or stream (also aligned text)")
+	pdfCmd.PersistentFlags().StringVar(&tools.PdfPages, "pages", "", "Pages to convert, like 3-10 or 1,4,8-")
+	pdfCmd.PersistentFlags().StringVar(&tools.PdfPassword, "pdf-password", "", "Password of encrypted PDF files")
+	pdfCmd.PersistentFlags().StringVar(&tools.PdfOcr, "ocr", "auto", "OCR of scanned pages: off, auto, ollama or tesseract")
+	pdfCmd.PersistentFlags().StringVar(&tools.OcrLanguages, "ocr-lang", "", "Tesseract languages of scanned pages, like fra+eng")
+	pdfCmd.PersistentFlags().IntVar(&tools.PdfSplitPages, "split-pages", 0, "Write a markdown file per N pages")
+	pdfCmd.PersistentFlags().BoolVar(&tools.PdfSplitChapters, "split-chapters", false, "Write a markdown file per chapter of the PDF outline")
+	pdfCmd.PersistentFlags().BoolVar(&PdfProgress, "progress", false, "Display the progress of the conversion on stderr")
 }
 
 // getWebPage get a web page by its id and generate a markdown page with its metadatas
 func getPdfDocument(cmd *cobra.Command, args []string) {
 	var pages []tools.Page
+	tools.PdfDescribeImages = ImgDesc
+	if PdfProgress {
+		tools.PdfProgress = func(pass, done, total int) {
+			fmt.Fprintf(os.Stderr, "\rPass %d/2 : page %d/%d", pass, done, total)
+			if pass == 2 && done == total {
+				fmt.Fprintln(os.Stderr)
+			}
+		}
+	}
 	datas, err := tools.GetPDF(Pdf, Url, CustomerIdPdf, ExportDir, tools.Metadata{})
 	tools.CheckError(err)
-	pages = append(pages, datas)
+	pages = append(pages, datas...)
 	tools.DisplayOnScreen(pages)
 
 }
@@ -35,11 +35,17 @@ func init() {
 	pptxCmd.PersistentFlags().StringVarP(&Pptx, "pptx", "s", "", "Pptx file")
 	pptxCmd.PersistentFlags().StringVarP(&Url, "url", "u", "", "Page URL for metadata")
 	pptxCmd.PersistentFlags().StringVarP(&CustomerIdPptx, "cid", "c", "pptx", "Customer ID code ")
+	pptxCmd.PersistentFlags().BoolVar(&docx2md.EmbedImages, "embed-images", false, "Embed images as base64 data instead of saving them in the assets folder")
+	pptxCmd.PersistentFlags().StringVarP(&docx2md.Embeds, "embeds", "e", "link", "Embedded documents: link, inline or files")
+	pptxCmd.PersistentFlags().BoolVarP(&docx2md.SkipHidden, "skip-hidden", "k", false, "Ignore hidden slides")
+	pptxCmd.PersistentFlags().StringVarP(&docx2md.Notes, "notes", "n", "section", "Speaker notes: none, section or quote")
+	pptxCmd.PersistentFlags().BoolVar(&docx2md.SlideNumbers, "slide-numbers", true, "Add slide numbers to slide headings")
 }
 
 // getPptxDocument read pptx and generate a markdown page with its metadatas
 func getPptxDocument(cmd *cobra.Command, args []string) {
 	var pages []tools.Page
+	docx2md.DescribeImages = ImgDesc
 	datas, err := docx2md.GetPptx(Pptx, Url, CustomerIdPptx, ExportDir, tools.Metadata{})
 	tools.CheckError(err)
 	pages = append(pages, datas)
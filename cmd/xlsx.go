@@ -0,0 +1,50 @@
+// Copyright © 2024 Acquatella Stephan
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/sacquatella/tomd/docx2md"
+	"github.com/sacquatella/tomd/tools"
+	"github.com/spf13/cobra"
+)
+
+var Xlsx string
+var CustomerIdXlsx string
+
+var xlsxCmd = &cobra.Command{
+	Use:   "xlsx",
+	Short: "Get xlsx sheets as markdown tables",
+	Long:  `Get xlsx sheets as markdown tables and generate a markdown page with metadata's'.`,
+	Run:   getXlsxDocument,
+}
+
+func init() {
+	rootCmd.AddCommand(xlsxCmd)
+	xlsxCmd.PersistentFlags().StringVarP(&Xlsx, "xlsx", "l", "", "Xlsx file")
+	xlsxCmd.PersistentFlags().StringVarP(&Url, "url", "u", "", "Page URL for metadata")
+	xlsxCmd.PersistentFlags().StringVarP(&CustomerIdXlsx, "cid", "c", "xlsx", "Customer ID code ")
+	xlsxCmd.PersistentFlags().StringSliceVarP(&docx2md.Sheets, "sheets", "s", nil, "Sheets to convert, by name or number")
+	xlsxCmd.PersistentFlags().IntVarP(&docx2md.MaxRows, "max-rows", "m", 0, "Maximum number of rows per sheet, 0 for all")
+}
+
+// getXlsxDocument read xlsx and generate a markdown page with its metadatas
+func getXlsxDocument(cmd *cobra.Command, args []string) {
+	var pages []tools.Page
+	datas, err := docx2md.GetXlsx(Xlsx, Url, CustomerIdXlsx, ExportDir, tools.Metadata{})
+	tools.CheckError(err)
+	pages = append(pages, datas)
+	tools.DisplayOnScreen(pages)
+
+}
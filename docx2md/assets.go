@@ -0,0 +1,143 @@
+package docx2md
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/sacquatella/tomd/tools"
+)
+
+// EmbedImages inlines images as data URIs instead of saving them in the assets folder
+var EmbedImages = false
+
+// DescribeImages adds a description of the images at the end of the markdown,
+// built by the vision model when the image has no alternative text
+var DescribeImages = false
+
+// imageMimes lists image types unknown to http.DetectContentType.
+var imageMimes = map[string]string{
+	".emf":  "image/emf",
+	".wmf":  "image/wmf",
+	".tif":  "image/tiff",
+	".tiff": "image/tiff",
+	".svg":  "image/svg+xml",
+}
+
+// imageMime returns the mime type of an image from its content, or its extension.
+func imageMime(name string, b []byte) string {
+	if t := http.DetectContentType(b); strings.HasPrefix(t, "image/") {
+		return t
+	}
+	ext := strings.ToLower(path.Ext(name))
+	if t, ok := imageMimes[ext]; ok {
+		return t
+	}
+	if t := mime.TypeByExtension(ext); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// partPath resolves a relationship target relative to the part using it.
+// Targets going outside of the package are rejected.
+func (zf *file) partPath(target string) (string, bool) {
+	var name string
+	if strings.HasPrefix(target, "/") {
+		name = path.Clean(strings.TrimPrefix(target, "/"))
+	} else {
+		name = path.Join(zf.part, target)
+	}
+	if name == "." || name == ".." || strings.HasPrefix(name, "../") || path.IsAbs(name) {
+		return "", false
+	}
+	return name, true
+}
+
+// readZipFile returns the content of a file of the package.
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// sanitizeName build a safe file name from the name of a package part.
+func sanitizeName(name string) string {
+	base := path.Base(name)
+	ext := strings.ToLower(path.Ext(base))
+	stem := strings.TrimSuffix(base, path.Ext(base))
+	stem = tools.RemoveSpecialChars(tools.RemoveAccents(strings.ReplaceAll(stem, " ", "-")))
+	if stem == "" {
+		stem = "image"
+	}
+	ext = "." + tools.RemoveSpecialChars(strings.TrimPrefix(ext, "."))
+	if ext == "." {
+		ext = ""
+	}
+	return stem + ext
+}
+
+// saveAsset writes a file of the package in the assets folder, once, with a
+// name that does not collide with other assets, also those of other documents
+// sharing the folder. It returns the markdown link.
+// The file is named after filename, which differs from the part name when the
+// image has been converted. An empty assets folder disables the extraction,
+// the link is empty.
+func (zf *file) saveAsset(name, filename string, b []byte) (string, error) {
+	if zf.assetsDir == "" {
+		return "", nil
+	}
+	if link, ok := zf.assets[name]; ok {
+		return link, nil
+	}
+	filename = sanitizeName(filename)
+	stem := strings.TrimSuffix(filename, path.Ext(filename))
+	for i := 1; zf.assetNames[filename] || zf.assetTaken(filename, b); i++ {
+		filename = fmt.Sprintf("%s-%d%s", stem, i, path.Ext(filename))
+	}
+	zf.assetNames[filename] = true
+
+	if err := os.MkdirAll(zf.assetsDir, 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(zf.assetsDir, filename), b, 0644); err != nil {
+		return "", err
+	}
+	link := zf.assetsPath() + "/" + filename
+	zf.assets[name] = link
+	return link, nil
+}
+
+// assetTaken reports whether the assets folder has another file with this
+// name. The same file, from a previous conversion, is overwritten.
+func (zf *file) assetTaken(filename string, b []byte) bool {
+	old, err := os.ReadFile(filepath.Join(zf.assetsDir, filename))
+	return err == nil && !bytes.Equal(old, b)
+}
+
+// assetsPath returns the path of the assets folder used in links. The markdown
+// file is written in the parent folder of the assets folder, unless another
+// link path is given for embedded documents.
+func (zf *file) assetsPath() string {
+	if zf.assetsLink != "" {
+		return zf.assetsLink
+	}
+	return filepath.Base(zf.assetsDir)
+}
+
+// addImage records an extracted image to be described.
+func (zf *file) addImage(name string, b []byte, description string) {
+	if zf.images != nil {
+		*zf.images = append(*zf.images, tools.Image{Name: name, Data: b, Alt: description})
+	}
+}
@@ -0,0 +1,202 @@
+package docx2md
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"unicode/utf16"
+)
+
+// cfbMagic is the signature of OLE compound files (oleObject*.bin)
+var cfbMagic = []byte{0xd0, 0xcf, 0x11, 0xe0, 0xa1, 0xb1, 0x1a, 0xe1}
+
+var errCompoundFile = errors.New("invalid compound file")
+
+const (
+	cfbEndOfChain = 0xfffffffe
+	cfbFreeSect   = 0xffffffff
+)
+
+// cfbEntry is a stream or a storage of a compound file.
+type cfbEntry struct {
+	name  string
+	typ   byte
+	start uint32
+	size  uint64
+}
+
+// compoundFile is a minimal reader of OLE compound files, enough to read the
+// streams of embedded objects.
+type compoundFile struct {
+	b            []byte
+	sectorSize   int
+	miniSize     int
+	miniCutoff   uint64
+	fat          []uint32
+	miniFat      []uint32
+	entries      []cfbEntry
+	miniStream   []byte
+	miniStreamOk bool
+}
+
+// openCompoundFile parses the header, the allocation tables and the directory
+// of a compound file.
+func openCompoundFile(b []byte) (*compoundFile, error) {
+	if len(b) < 512 || !bytes.HasPrefix(b, cfbMagic) {
+		return nil, errCompoundFile
+	}
+	le := binary.LittleEndian
+	cf := &compoundFile{
+		b:          b,
+		sectorSize: 1 << le.Uint16(b[0x1e:]),
+		miniSize:   1 << le.Uint16(b[0x20:]),
+		miniCutoff: uint64(le.Uint32(b[0x38:])),
+	}
+	if (cf.sectorSize != 512 && cf.sectorSize != 4096) || cf.miniSize != 64 {
+		return nil, errCompoundFile
+	}
+
+	// sectors of the FAT are listed by the header, then by the DIFAT sectors
+	var fatSectors []uint32
+	for i := 0; i < 109; i++ {
+		if s := le.Uint32(b[0x4c+i*4:]); s != cfbFreeSect {
+			fatSectors = append(fatSectors, s)
+		}
+	}
+	difat := le.Uint32(b[0x44:])
+	for n := 0; difat != cfbEndOfChain && difat != cfbFreeSect && n < 1<<16; n++ {
+		sector := cf.sector(difat)
+		if sector == nil {
+			return nil, errCompoundFile
+		}
+		for i := 0; i < cf.sectorSize/4-1; i++ {
+			if s := le.Uint32(sector[i*4:]); s != cfbFreeSect {
+				fatSectors = append(fatSectors, s)
+			}
+		}
+		difat = le.Uint32(sector[cf.sectorSize-4:])
+	}
+	for _, s := range fatSectors {
+		sector := cf.sector(s)
+		if sector == nil {
+			return nil, errCompoundFile
+		}
+		for i := 0; i < cf.sectorSize/4; i++ {
+			cf.fat = append(cf.fat, le.Uint32(sector[i*4:]))
+		}
+	}
+
+	miniFat, err := cf.chain(le.Uint32(b[0x3c:]), 0)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i+4 <= len(miniFat); i += 4 {
+		cf.miniFat = append(cf.miniFat, le.Uint32(miniFat[i:]))
+	}
+
+	dir, err := cf.chain(le.Uint32(b[0x30:]), 0)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i+128 <= len(dir); i += 128 {
+		e := dir[i : i+128]
+		nameLen := int(le.Uint16(e[64:]))
+		if nameLen > 64 {
+			nameLen = 64
+		}
+		var name []uint16
+		for j := 0; j+1 < nameLen; j += 2 {
+			if c := le.Uint16(e[j:]); c != 0 {
+				name = append(name, c)
+			}
+		}
+		cf.entries = append(cf.entries, cfbEntry{
+			name:  string(utf16.Decode(name)),
+			typ:   e[66],
+			start: le.Uint32(e[116:]),
+			size:  le.Uint64(e[120:]),
+		})
+	}
+	if len(cf.entries) == 0 {
+		return nil, errCompoundFile
+	}
+	// version 3 files only use the low 32 bits of the size
+	if cf.sectorSize == 512 {
+		for i := range cf.entries {
+			cf.entries[i].size &= 0xffffffff
+		}
+	}
+	return cf, nil
+}
+
+// sector returns the content of a regular sector.
+func (cf *compoundFile) sector(n uint32) []byte {
+	start := (int(n) + 1) * cf.sectorSize
+	if n >= cfbEndOfChain-4 || start+cf.sectorSize > len(cf.b) {
+		return nil
+	}
+	return cf.b[start : start+cf.sectorSize]
+}
+
+// chain reads a chain of regular sectors, limited to size bytes if not 0.
+func (cf *compoundFile) chain(start uint32, size uint64) ([]byte, error) {
+	var out []byte
+	for n := start; n != cfbEndOfChain && n != cfbFreeSect; n = cf.fat[n] {
+		sector := cf.sector(n)
+		// a chain longer than the file is a loop
+		if sector == nil || int(n) >= len(cf.fat) || len(out) > len(cf.b) {
+			return nil, errCompoundFile
+		}
+		out = append(out, sector...)
+	}
+	if size > 0 && size <= uint64(len(out)) {
+		out = out[:size]
+	}
+	return out, nil
+}
+
+// stream returns the content of the first stream with the given name.
+func (cf *compoundFile) stream(name string) ([]byte, bool) {
+	for _, e := range cf.entries {
+		if e.typ != 2 || !strings.EqualFold(e.name, name) {
+			continue
+		}
+		if e.size >= cf.miniCutoff {
+			b, err := cf.chain(e.start, e.size)
+			return b, err == nil
+		}
+		// small streams are stored in the mini stream of the root entry
+		if !cf.miniStreamOk {
+			root := cf.entries[0]
+			b, err := cf.chain(root.start, root.size)
+			if err != nil {
+				return nil, false
+			}
+			cf.miniStream, cf.miniStreamOk = b, true
+		}
+		var out []byte
+		for n := e.start; n != cfbEndOfChain && n != cfbFreeSect; n = cf.miniFat[n] {
+			start := int(n) * cf.miniSize
+			if int(n) >= len(cf.miniFat) || start+cf.miniSize > len(cf.miniStream) || len(out) > len(cf.miniStream) {
+				return nil, false
+			}
+			out = append(out, cf.miniStream[start:start+cf.miniSize]...)
+		}
+		if e.size > uint64(len(out)) {
+			return nil, false
+		}
+		return out[:e.size], true
+	}
+	return nil, false
+}
+
+// hasStream reports whether the compound file contains a stream with the given name.
+func (cf *compoundFile) hasStream(name string) bool {
+	for _, e := range cf.entries {
+		if e.typ == 2 && strings.EqualFold(e.name, name) {
+			return true
+		}
+	}
+	return false
+}
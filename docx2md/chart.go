@@ -0,0 +1,250 @@
+package docx2md
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sacquatella/tomd/tools"
+)
+
+// chartSeries is a series of a chart with the cached values of its points.
+type chartSeries struct {
+	name       string
+	categories map[int]string
+	values     map[int]string
+}
+
+// cachedPoints returns the cached points (index and value) of a chart data
+// reference: string, number or multi level references, and literals.
+func cachedPoints(node *Node) map[int]string {
+	points := make(map[int]string)
+	if node == nil {
+		return points
+	}
+	var visit func(n *Node)
+	visit = func(n *Node) {
+		switch n.XMLName.Local {
+		case "f", "extLst":
+			return
+		case "multiLvlStrCache":
+			multiLevelPoints(n, points)
+			return
+		case "pt":
+			idx, _ := attr(n.Attrs, "idx")
+			i, err := strconv.Atoi(idx)
+			if v := child(n, "v"); v != nil && err == nil {
+				points[i] = strings.TrimSpace(innerText(v))
+			}
+			return
+		}
+		for i := range n.Nodes {
+			visit(&n.Nodes[i])
+		}
+	}
+	visit(node)
+	return points
+}
+
+// multiLevelPoints builds the labels of multi level categories, the first
+// level is the innermost one. Outer labels apply up to the next outer label.
+func multiLevelPoints(node *Node, points map[int]string) {
+	var levels []map[int]string
+	for i := range node.Nodes {
+		if node.Nodes[i].XMLName.Local == "lvl" {
+			levels = append(levels, cachedPoints(&node.Nodes[i]))
+		}
+	}
+	if len(levels) == 0 {
+		return
+	}
+	for idx, label := range levels[0] {
+		parts := []string{label}
+		for _, level := range levels[1:] {
+			outer, best := "", -1
+			for i, v := range level {
+				if i <= idx && i > best {
+					outer, best = v, i
+				}
+			}
+			if outer != "" {
+				parts = append([]string{outer}, parts...)
+			}
+		}
+		points[idx] = strings.Join(parts, " / ")
+	}
+}
+
+// chartType returns a readable type of a plot (barChart, lineChart...).
+func chartType(plot *Node) string {
+	name := strings.TrimSuffix(plot.XMLName.Local, "Chart")
+	name = strings.TrimSuffix(name, "3D")
+	if name == "bar" {
+		if dir := child(plot, "barDir"); dir != nil {
+			if val, _ := attr(dir.Attrs, "val"); val == "col" {
+				name = "column"
+			}
+		}
+	}
+	if name == "ofPie" {
+		name = "pie"
+	}
+	return name + " chart"
+}
+
+// chartTitle returns the title of a chart or of one of its axes.
+func chartTitle(node *Node) string {
+	title := child(node, "title")
+	if title == nil {
+		return ""
+	}
+	tx := child(title, "tx")
+	if tx == nil {
+		return ""
+	}
+	var texts []string
+	var visit func(n *Node)
+	visit = func(n *Node) {
+		switch n.XMLName.Local {
+		case "p":
+			if s := strings.TrimSpace(plainText(n)); s != "" {
+				texts = append(texts, s)
+			}
+			return
+		case "v":
+			texts = append(texts, strings.TrimSpace(innerText(n)))
+			return
+		}
+		for i := range n.Nodes {
+			visit(&n.Nodes[i])
+		}
+	}
+	visit(tx)
+	return strings.Join(texts, " ")
+}
+
+// chart writes the cached data of a chart as a markdown table, with its title
+// and type as a caption.
+func (zf *file) chart(node *Node, w io.Writer) error {
+	id, ok := attr(node.Attrs, "id")
+	if !ok {
+		return nil
+	}
+	name, ok := zf.partPath(zf.relTarget(id))
+	if !ok {
+		return nil
+	}
+	f := findFile(zf.r.File, name)
+	if f == nil {
+		log.Infof("Chart %s not found in the document", name)
+		return nil
+	}
+	space, err := readFile(f)
+	if err != nil {
+		return err
+	}
+	c := child(space, "chart")
+	if c == nil {
+		return nil
+	}
+	plotArea := child(c, "plotArea")
+	if plotArea == nil {
+		return nil
+	}
+
+	var types []string
+	var series []chartSeries
+	categoryTitle := ""
+	for i := range plotArea.Nodes {
+		plot := &plotArea.Nodes[i]
+		if !strings.HasSuffix(plot.XMLName.Local, "Chart") {
+			if plot.XMLName.Local == "catAx" || plot.XMLName.Local == "dateAx" {
+				categoryTitle = chartTitle(plot)
+			}
+			continue
+		}
+		if t := chartType(plot); len(types) == 0 || types[len(types)-1] != t {
+			types = append(types, t)
+		}
+		for j := range plot.Nodes {
+			ser := &plot.Nodes[j]
+			if ser.XMLName.Local != "ser" {
+				continue
+			}
+			s := chartSeries{name: fmt.Sprintf("Series %d", len(series)+1)}
+			if tx := child(ser, "tx"); tx != nil {
+				if v := child(tx, "v"); v != nil {
+					s.name = innerText(v)
+				} else if p := cachedPoints(tx); p[0] != "" {
+					s.name = p[0]
+				}
+			}
+			// scatter and bubble charts use x and y values
+			cat, val := child(ser, "cat"), child(ser, "val")
+			if cat == nil {
+				cat = child(ser, "xVal")
+			}
+			if val == nil {
+				val = child(ser, "yVal")
+			}
+			s.categories, s.values = cachedPoints(cat), cachedPoints(val)
+			series = append(series, s)
+		}
+	}
+	if len(series) == 0 {
+		return nil
+	}
+
+	// categories are the indexes of all the points, labelled by the first series having them
+	indexes := make(map[int]bool)
+	for _, s := range series {
+		for i := range s.values {
+			indexes[i] = true
+		}
+		for i := range s.categories {
+			indexes[i] = true
+		}
+	}
+	var sorted []int
+	for i := range indexes {
+		sorted = append(sorted, i)
+	}
+	sort.Ints(sorted)
+
+	if categoryTitle == "" {
+		categoryTitle = "Category"
+	}
+	header := []string{tools.Escape(categoryTitle, "\\*_~[]`")}
+	for _, s := range series {
+		header = append(header, tools.Escape(s.name, "\\*_~[]`"))
+	}
+	rows := [][]string{header}
+	for _, i := range sorted {
+		label := strconv.Itoa(i + 1)
+		for _, s := range series {
+			if c, ok := s.categories[i]; ok {
+				label = tools.Escape(c, "\\*_~[]`")
+				break
+			}
+		}
+		row := []string{label}
+		for _, s := range series {
+			row = append(row, s.values[i])
+		}
+		rows = append(rows, row)
+	}
+
+	caption := strings.Join(types, ", ")
+	if title := chartTitle(c); title != "" {
+		caption = title + " (" + caption + ")"
+	} else {
+		caption = strings.ToUpper(caption[:1]) + caption[1:]
+	}
+	fmt.Fprintf(w, "\n*%s*\n\n", tools.Escape(caption, "\\*_~[]`"))
+	writeTable(rows, w)
+	return nil
+}
@@ -0,0 +1,161 @@
+package docx2md
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// languageHints are content based hints used to guess the language of a code block.
+// The first matching hint wins, so more specific patterns come first.
+var languageHints = []struct {
+	lang    string
+	pattern *regexp.Regexp
+}{
+	{"go", regexp.MustCompile(`(?m)^\s*(package \w+|func (\(\w+ \*?\w+\) )?\w+\(|import \(|\w+ := )`)},
+	{"php", regexp.MustCompile(`<\?php`)},
+	{"xml", regexp.MustCompile(`^\s*<\?xml`)},
+	{"html", regexp.MustCompile(`(?i)^\s*(<!doctype html|<html|<div|<body|<head)`)},
+	{"shell", regexp.MustCompile(`(?m)^(#!/bin/(ba)?sh|\$ \w+)`)},
+	{"python", regexp.MustCompile(`(?m)^\s*(def \w+\(.*\):|class \w+(\(.*\))?:|from [\w.]+ import |import \w+$|if __name__ == )`)},
+	{"java", regexp.MustCompile(`(?m)^\s*(public |private |protected )(static )?(class|void|final|interface) `)},
+	{"c", regexp.MustCompile(`(?m)^\s*#include\s*[<"]`)},
+	{"javascript", regexp.MustCompile(`(?m)(^\s*(const|let|var) \w+ = |function\s*\w*\(.*\)\s*{|=>\s*{|console\.log\()`)},
+	{"sql", regexp.MustCompile(`(?im)^\s*(select .+ from |insert into |update \w+ set |create table |delete from )`)},
+	{"json", regexp.MustCompile(`^\s*[{\[]\s*"`)},
+	{"yaml", regexp.MustCompile(`^(---\n)?[\w-]+:( .+)?\n([\w-]+:|\s+[\w-]+:|\s+- )`)},
+}
+
+// detectLanguage guesses the language of a code snippet, or returns "" if unsure.
+func detectLanguage(code string) string {
+	for _, hint := range languageHints {
+		if hint.pattern.MatchString(code) {
+			return hint.lang
+		}
+	}
+	return ""
+}
+
+// isCodeStyle reports whether a paragraph or character style is used for source code.
+// The style is looked up by id, by name and through the styles it is based on.
+func (zf *file) isCodeStyle(id string) bool {
+	for depth := 0; id != "" && depth < 10; depth++ {
+		if codeStyles[id] || codeLanguages[id] != "" {
+			return true
+		}
+		basedOn := ""
+		for _, style := range zf.styles.Style {
+			if style.StyleID != id {
+				continue
+			}
+			if codeStyles[strings.ReplaceAll(style.Name.Val, " ", "")] {
+				return true
+			}
+			if isMonospace(style.RPr.RFonts.Ascii) || isMonospace(style.RPr.RFonts.HAnsi) {
+				return true
+			}
+			basedOn = style.BasedOn.Val
+			break
+		}
+		id = basedOn
+	}
+	return false
+}
+
+// paragraphStyle returns the style id of a paragraph.
+func paragraphStyle(node *Node) string {
+	if pPr := child(node, "pPr"); pPr != nil {
+		if pStyle := child(pPr, "pStyle"); pStyle != nil {
+			val, _ := attr(pStyle.Attrs, "val")
+			return val
+		}
+	}
+	return ""
+}
+
+// isCodeParagraph reports whether a paragraph is a line of source code: either
+// its style is a code style, or all of its text is written with a monospace font.
+func (zf *file) isCodeParagraph(node *Node) bool {
+	if zf.isCodeStyle(paragraphStyle(node)) {
+		return true
+	}
+	hasText := false
+	for i := range node.Nodes {
+		r := &node.Nodes[i]
+		if r.XMLName.Local != "r" {
+			continue
+		}
+		if strings.TrimSpace(plainText(r)) == "" {
+			continue
+		}
+		if !zf.runStyle(r).code {
+			return false
+		}
+		hasText = true
+	}
+	return hasText
+}
+
+// writeCodeBlock writes source code lines as a fenced code block.
+func writeCodeBlock(lines []string, lang string, w io.Writer) {
+	// leading and trailing blank lines are not part of the code
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return
+	}
+	// remove the indentation shared by all lines
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, " "))
+		if indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	for i, line := range lines {
+		if len(line) >= indent {
+			lines[i] = line[indent:]
+		}
+	}
+	code := strings.Join(lines, "\n")
+	if lang == "" {
+		lang = detectLanguage(code)
+	}
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	fmt.Fprintf(w, "%s%s\n%s\n%s\n", fence, lang, code, fence)
+}
+
+// walkBlocks walks the children of a container (body, cell, text box...) and
+// groups consecutive code paragraphs into fenced code blocks.
+func (zf *file) walkBlocks(nodes []Node, w io.Writer) error {
+	for i := 0; i < len(nodes); i++ {
+		if nodes[i].XMLName.Local != "p" || !zf.isCodeParagraph(&nodes[i]) {
+			if err := zf.walk(&nodes[i], w); err != nil {
+				return err
+			}
+			continue
+		}
+		var lines []string
+		lang := ""
+		for ; i < len(nodes) && nodes[i].XMLName.Local == "p" && zf.isCodeParagraph(&nodes[i]); i++ {
+			if lang == "" {
+				lang = codeLanguages[paragraphStyle(&nodes[i])]
+			}
+			lines = append(lines, plainText(&nodes[i]))
+		}
+		i--
+		writeCodeBlock(lines, lang, w)
+	}
+	return nil
+}
@@ -0,0 +1,341 @@
+package docx2md
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"path"
+	"strings"
+
+	"golang.org/x/image/tiff"
+)
+
+// legacy image formats that markdown viewers can not display
+const (
+	formatEMF  = "emf"
+	formatWMF  = "wmf"
+	formatTIFF = "tiff"
+)
+
+var errNoRaster = errors.New("no raster image found")
+
+// legacyFormat returns the format of an image that must be converted before
+// being used in markdown, or "" for web compatible images.
+func legacyFormat(name string, b []byte) string {
+	switch {
+	case bytes.HasPrefix(b, []byte("II*\x00")), bytes.HasPrefix(b, []byte("MM\x00*")):
+		return formatTIFF
+	case len(b) >= 44 && binary.LittleEndian.Uint32(b) == 1 && string(b[40:44]) == " EMF":
+		return formatEMF
+	case bytes.HasPrefix(b, []byte{0xd7, 0xcd, 0xc6, 0x9a}):
+		return formatWMF
+	}
+	switch strings.ToLower(path.Ext(name)) {
+	case ".emf", ".emz":
+		return formatEMF
+	case ".wmf", ".wmz":
+		return formatWMF
+	case ".tif", ".tiff":
+		return formatTIFF
+	}
+	return ""
+}
+
+// gunzip uncompress emz and wmz images, other images are returned as is.
+func gunzip(b []byte) []byte {
+	if !bytes.HasPrefix(b, []byte{0x1f, 0x8b}) {
+		return b
+	}
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return b
+	}
+	defer r.Close()
+	out, err := io.ReadAll(r)
+	if err != nil {
+		return b
+	}
+	return out
+}
+
+// convertImage converts a TIFF, EMF or WMF image to PNG. Only the raster part
+// of metafiles is converted: the largest bitmap drawn by the metafile is kept.
+func convertImage(format string, b []byte) ([]byte, error) {
+	var img image.Image
+	var err error
+	switch format {
+	case formatTIFF:
+		img, err = tiff.Decode(bytes.NewReader(b))
+	case formatEMF:
+		img, err = largestImage(emfBitmaps(gunzip(b)))
+	case formatWMF:
+		img, err = largestImage(wmfBitmaps(gunzip(b)))
+	default:
+		err = fmt.Errorf("unsupported image format %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// imageMetadata describes an image which can not be converted.
+func imageMetadata(format string, b []byte) string {
+	meta := fmt.Sprintf("format: %s, size: %d bytes", format, len(b))
+	b = gunzip(b)
+	switch format {
+	case formatEMF:
+		// bounds of the picture in device units
+		if len(b) >= 24 {
+			left, top := int32(binary.LittleEndian.Uint32(b[8:])), int32(binary.LittleEndian.Uint32(b[12:]))
+			right, bottom := int32(binary.LittleEndian.Uint32(b[16:])), int32(binary.LittleEndian.Uint32(b[20:]))
+			meta += fmt.Sprintf(", bounds: %dx%d", right-left+1, bottom-top+1)
+		}
+	case formatWMF:
+		// placeable header, bounding box in logical units and units per inch
+		if len(b) >= 22 && bytes.HasPrefix(b, []byte{0xd7, 0xcd, 0xc6, 0x9a}) {
+			left, top := int16(binary.LittleEndian.Uint16(b[6:])), int16(binary.LittleEndian.Uint16(b[8:]))
+			right, bottom := int16(binary.LittleEndian.Uint16(b[10:])), int16(binary.LittleEndian.Uint16(b[12:]))
+			if inch := int(binary.LittleEndian.Uint16(b[14:])); inch > 0 {
+				meta += fmt.Sprintf(", bounds: %dx%d px", (int(right)-int(left))*96/inch, (int(bottom)-int(top))*96/inch)
+			}
+		}
+	case formatTIFF:
+		if cfg, err := tiff.DecodeConfig(bytes.NewReader(b)); err == nil {
+			meta += fmt.Sprintf(", bounds: %dx%d px", cfg.Width, cfg.Height)
+		}
+	}
+	return meta
+}
+
+// largestImage decodes device independent bitmaps and returns the largest one.
+func largestImage(dibs [][]byte) (image.Image, error) {
+	var best image.Image
+	area := 0
+	for _, dib := range dibs {
+		img, err := decodeDIB(dib)
+		if err != nil {
+			continue
+		}
+		if size := img.Bounds().Dx() * img.Bounds().Dy(); size > area {
+			best, area = img, size
+		}
+	}
+	if best == nil {
+		return nil, errNoRaster
+	}
+	return best, nil
+}
+
+// emfBitmaps returns the bitmaps (header and bits) of the EMF raster records.
+func emfBitmaps(b []byte) [][]byte {
+	var dibs [][]byte
+	for pos := 0; pos+8 <= len(b); {
+		typ := binary.LittleEndian.Uint32(b[pos:])
+		size := int(binary.LittleEndian.Uint32(b[pos+4:]))
+		if size < 8 || pos+size > len(b) {
+			break
+		}
+		rec := b[pos : pos+size]
+		offset := 0
+		switch typ {
+		case 0x50, 0x51: // EMR_SETDIBITSTODEVICE, EMR_STRETCHDIBITS
+			offset = 48
+		case 0x4c, 0x4d: // EMR_BITBLT, EMR_STRETCHBLT
+			offset = 84
+		case 0x0e: // EMR_EOF
+			return dibs
+		}
+		if offset > 0 && len(rec) >= offset+16 {
+			offBmi := int(binary.LittleEndian.Uint32(rec[offset:]))
+			cbBmi := int(binary.LittleEndian.Uint32(rec[offset+4:]))
+			offBits := int(binary.LittleEndian.Uint32(rec[offset+8:]))
+			cbBits := int(binary.LittleEndian.Uint32(rec[offset+12:]))
+			if cbBmi > 0 && cbBits > 0 && offBmi+cbBmi <= len(rec) && offBits+cbBits <= len(rec) {
+				dib := append(append([]byte{}, rec[offBmi:offBmi+cbBmi]...), rec[offBits:offBits+cbBits]...)
+				dibs = append(dibs, dib)
+			}
+		}
+		pos += size
+	}
+	return dibs
+}
+
+// wmfBitmaps returns the bitmaps of the WMF raster records.
+func wmfBitmaps(b []byte) [][]byte {
+	pos := 0
+	if bytes.HasPrefix(b, []byte{0xd7, 0xcd, 0xc6, 0x9a}) {
+		pos = 22
+	}
+	if pos+18 > len(b) {
+		return nil
+	}
+	// header size is given in 16 bits words
+	pos += int(binary.LittleEndian.Uint16(b[pos+2:])) * 2
+	var dibs [][]byte
+	for pos+6 <= len(b) {
+		size := int(binary.LittleEndian.Uint32(b[pos:])) * 2
+		function := binary.LittleEndian.Uint16(b[pos+4:])
+		if size < 6 || pos+size > len(b) {
+			break
+		}
+		rec := b[pos : pos+size]
+		offset := 0
+		switch function {
+		case 0x0f43: // META_STRETCHDIB
+			offset = 28
+		case 0x0b41: // META_DIBSTRETCHBLT
+			offset = 26
+		case 0x0940: // META_DIBBITBLT
+			offset = 22
+		case 0x0d33: // META_SETDIBTODEV
+			offset = 24
+		case 0x0000: // META_EOF
+			return dibs
+		}
+		if offset > 0 && len(rec) > offset+4 {
+			dibs = append(dibs, rec[offset:])
+		}
+		pos += size
+	}
+	return dibs
+}
+
+// decodeDIB decodes a device independent bitmap: a BITMAPINFO header with its
+// color table followed by the pixels. RLE compressed bitmaps are not supported.
+func decodeDIB(dib []byte) (image.Image, error) {
+	if len(dib) < 12 {
+		return nil, errors.New("invalid bitmap header")
+	}
+	le := binary.LittleEndian
+	headerSize := int(le.Uint32(dib))
+	var width, height, bitCount, compression, colors int
+	paletteEntry := 4
+	switch {
+	case headerSize == 12:
+		// BITMAPCOREHEADER
+		width, height = int(le.Uint16(dib[4:])), int(int16(le.Uint16(dib[6:])))
+		bitCount = int(le.Uint16(dib[10:]))
+		paletteEntry = 3
+	case headerSize >= 40 && len(dib) >= headerSize:
+		width, height = int(int32(le.Uint32(dib[4:]))), int(int32(le.Uint32(dib[8:])))
+		bitCount = int(le.Uint16(dib[14:]))
+		compression = int(le.Uint32(dib[16:]))
+		colors = int(le.Uint32(dib[32:]))
+	default:
+		return nil, errors.New("invalid bitmap header")
+	}
+
+	pos := headerSize
+	// BI_JPEG and BI_PNG bitmaps embed a complete image
+	if compression == 4 || compression == 5 {
+		img, _, err := image.Decode(bytes.NewReader(dib[pos:]))
+		return img, err
+	}
+	masks := []uint32{0x7c00, 0x03e0, 0x001f}
+	if bitCount == 32 {
+		masks = []uint32{0xff0000, 0x00ff00, 0x0000ff}
+	}
+	switch compression {
+	case 0:
+	case 3: // BI_BITFIELDS
+		if headerSize == 40 {
+			if len(dib) < pos+12 {
+				return nil, errors.New("invalid bitmap masks")
+			}
+			masks = []uint32{le.Uint32(dib[pos:]), le.Uint32(dib[pos+4:]), le.Uint32(dib[pos+8:])}
+			pos += 12
+		} else if len(dib) >= 52 {
+			masks = []uint32{le.Uint32(dib[40:]), le.Uint32(dib[44:]), le.Uint32(dib[48:])}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported bitmap compression %d", compression)
+	}
+
+	var palette []color.Color
+	if bitCount <= 8 {
+		if colors == 0 || colors > 1<<bitCount {
+			colors = 1 << bitCount
+		}
+		if len(dib) < pos+colors*paletteEntry {
+			return nil, errors.New("invalid bitmap palette")
+		}
+		for i := 0; i < colors; i++ {
+			e := dib[pos+i*paletteEntry:]
+			palette = append(palette, color.NRGBA{R: e[2], G: e[1], B: e[0], A: 0xff})
+		}
+		pos += colors * paletteEntry
+	} else {
+		// optional color table of true color bitmaps
+		pos += colors * paletteEntry
+	}
+
+	topDown := height < 0
+	if topDown {
+		height = -height
+	}
+	if width <= 0 || height <= 0 || width > 1<<14 || height > 1<<14 {
+		return nil, errors.New("invalid bitmap size")
+	}
+	stride := (width*bitCount + 31) / 32 * 4
+	if len(dib) < pos+stride*height {
+		return nil, errors.New("truncated bitmap")
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		row := dib[pos+y*stride : pos+(y+1)*stride]
+		dy := height - 1 - y
+		if topDown {
+			dy = y
+		}
+		for x := 0; x < width; x++ {
+			var c color.Color
+			switch bitCount {
+			case 1, 4, 8:
+				bit := x * bitCount
+				index := int(row[bit/8]>>(8-bitCount-bit%8)) & (1<<bitCount - 1)
+				if index >= len(palette) {
+					return nil, errors.New("invalid bitmap color index")
+				}
+				c = palette[index]
+			case 16:
+				c = maskedColor(uint32(le.Uint16(row[x*2:])), masks)
+			case 24:
+				c = color.NRGBA{R: row[x*3+2], G: row[x*3+1], B: row[x*3], A: 0xff}
+			case 32:
+				c = maskedColor(le.Uint32(row[x*4:]), masks)
+			default:
+				return nil, fmt.Errorf("unsupported bitmap depth %d", bitCount)
+			}
+			img.Set(x, dy, c)
+		}
+	}
+	return img, nil
+}
+
+// maskedColor extracts an opaque color from a pixel with red, green and blue masks.
+func maskedColor(v uint32, masks []uint32) color.NRGBA {
+	channel := func(mask uint32) uint8 {
+		if mask == 0 {
+			return 0
+		}
+		shift := 0
+		for mask&1 == 0 {
+			mask >>= 1
+			shift++
+		}
+		return uint8((v >> shift & mask) * 0xff / mask)
+	}
+	return color.NRGBA{R: channel(masks[0]), G: channel(masks[1]), B: channel(masks[2]), A: 0xff}
+}
@@ -0,0 +1,127 @@
+package docx2md
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/tiff"
+)
+
+// buildEMF build an EMF with a header and a 2x1 red and blue EMR_STRETCHDIBITS record
+func buildEMF(withBitmap bool) []byte {
+	le := binary.LittleEndian
+	header := make([]byte, 88)
+	le.PutUint32(header, 1)
+	le.PutUint32(header[4:], 88)
+	le.PutUint32(header[16:], 1) // bounds 0,0,1,0
+	copy(header[40:], " EMF")
+	emf := header
+	if withBitmap {
+		bmi := make([]byte, 40)
+		le.PutUint32(bmi, 40)
+		le.PutUint32(bmi[4:], 2)
+		le.PutUint32(bmi[8:], 1)
+		le.PutUint16(bmi[12:], 1)
+		le.PutUint16(bmi[14:], 24)
+		bits := []byte{0, 0, 0xff, 0xff, 0, 0, 0, 0} // BGR red, BGR blue, padding
+		rec := make([]byte, 80)
+		le.PutUint32(rec, 0x51)
+		le.PutUint32(rec[4:], uint32(80+len(bmi)+len(bits)))
+		le.PutUint32(rec[48:], 80)
+		le.PutUint32(rec[52:], uint32(len(bmi)))
+		le.PutUint32(rec[56:], uint32(80+len(bmi)))
+		le.PutUint32(rec[60:], uint32(len(bits)))
+		emf = append(append(append(emf, rec...), bmi...), bits...)
+	}
+	eof := make([]byte, 20)
+	le.PutUint32(eof, 0x0e)
+	le.PutUint32(eof[4:], 20)
+	return append(emf, eof...)
+}
+
+// TestConvertImage test conversion of legacy images to png
+func TestConvertImage(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	src.Set(0, 0, color.NRGBA{R: 0xff, A: 0xff})
+	var tif bytes.Buffer
+	if err := tiff.Encode(&tif, src, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		format string
+		data   []byte
+		width  int
+		red    color.NRGBA
+	}{
+		{name: "tiff", format: formatTIFF, data: tif.Bytes(), width: 3, red: color.NRGBA{R: 0xff, A: 0xff}},
+		{name: "emf", format: formatEMF, data: buildEMF(true), width: 2, red: color.NRGBA{R: 0xff, A: 0xff}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := legacyFormat("image", tt.data); got != tt.format {
+				t.Errorf("expected format %s, got %s", tt.format, got)
+			}
+			b, err := convertImage(tt.format, tt.data)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			img, err := png.Decode(bytes.NewReader(b))
+			if err != nil {
+				t.Fatalf("expected a png, got %v", err)
+			}
+			if img.Bounds().Dx() != tt.width {
+				t.Errorf("expected width %d, got %d", tt.width, img.Bounds().Dx())
+			}
+			if got := color.NRGBAModel.Convert(img.At(0, 0)); got != tt.red {
+				t.Errorf("expected %v, got %v", tt.red, got)
+			}
+		})
+	}
+
+	if _, err := convertImage(formatEMF, buildEMF(false)); err != errNoRaster {
+		t.Errorf("expected %v, got %v", errNoRaster, err)
+	}
+}
+
+// TestDocxToMd_LegacyImages test emf images are converted and placeholders written for vector only images
+func TestDocxToMd_LegacyImages(t *testing.T) {
+	parts := map[string]string{
+		"word/_rels/document.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="image" Target="media/image1.emf"/>` +
+			`<Relationship Id="rId2" Type="image" Target="media/image2.emf"/></Relationships>`,
+		"word/media/image1.emf": string(buildEMF(true)),
+		"word/media/image2.emf": string(buildEMF(false)),
+	}
+	assetsDir := filepath.Join(t.TempDir(), "doc-assets")
+	result, _, err := Docx2md(buildDocx(t, picture("rId1", "raster")+picture("rId2", "vector"), parts), false, assetsDir)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := "![raster](doc-assets/image1.png)\n[Image not converted: vector](doc-assets/image2.emf)<!-- format: emf, size: 108 bytes, bounds: 2x1 -->\n"
+	if result != want {
+		t.Errorf("expected %q, got %q", want, result)
+	}
+
+	// without assets folder, the image is not linked
+	result = convertDocx(t, picture("rId2", "vector"), parts)
+	want = "[Image not converted: vector]<!-- format: emf, size: 108 bytes, bounds: 2x1 -->\n"
+	if result != want {
+		t.Errorf("expected %q, got %q", want, result)
+	}
+
+	result, _, err = Docx2md(buildDocx(t, picture("rId1", "raster"), parts), true, "")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.HasPrefix(result, "![raster](data:image/png;base64,") {
+		t.Errorf("expected png data uri, got %s", result)
+	}
+}
@@ -3,6 +3,8 @@ package docx2md
 import (
 	"archive/zip"
 	"encoding/xml"
+
+	"github.com/sacquatella/tomd/tools"
 )
 
 // Relationship is
@@ -30,15 +32,17 @@ type TextVal struct {
 
 // NumberingLvl is
 type NumberingLvl struct {
-	Text      string  `xml:",chardata"`
-	Ilvl      string  `xml:"ilvl,attr"`
-	Tplc      string  `xml:"tplc,attr"`
-	Tentative string  `xml:"tentative,attr"`
-	Start     TextVal `xml:"start"`
-	NumFmt    TextVal `xml:"numFmt"`
-	LvlText   TextVal `xml:"lvlText"`
-	LvlJc     TextVal `xml:"lvlJc"`
-	PPr       struct {
+	Text       string   `xml:",chardata"`
+	Ilvl       string   `xml:"ilvl,attr"`
+	Tplc       string   `xml:"tplc,attr"`
+	Tentative  string   `xml:"tentative,attr"`
+	Start      TextVal  `xml:"start"`
+	NumFmt     TextVal  `xml:"numFmt"`
+	LvlText    TextVal  `xml:"lvlText"`
+	LvlJc      TextVal  `xml:"lvlJc"`
+	LvlRestart *TextVal `xml:"lvlRestart"`
+	IsLgl      *TextVal `xml:"isLgl"`
+	PPr        struct {
 		Text string `xml:",chardata"`
 		Ind  struct {
 			Text    string `xml:",chardata"`
@@ -96,15 +100,76 @@ type Numbering struct {
 		Text          string  `xml:",chardata"`
 		NumID         string  `xml:"numId,attr"`
 		AbstractNumID TextVal `xml:"abstractNumId"`
+		LvlOverride   []struct {
+			Ilvl          string        `xml:"ilvl,attr"`
+			StartOverride *TextVal      `xml:"startOverride"`
+			Lvl           *NumberingLvl `xml:"lvl"`
+		} `xml:"lvlOverride"`
 	} `xml:"num"`
 }
 
+// Styles is
+type Styles struct {
+	XMLName xml.Name `xml:"styles"`
+	Style   []struct {
+		Type    string  `xml:"type,attr"`
+		StyleID string  `xml:"styleId,attr"`
+		Name    TextVal `xml:"name"`
+		BasedOn TextVal `xml:"basedOn"`
+		PPr     struct {
+			NumPr struct {
+				Ilvl  TextVal `xml:"ilvl"`
+				NumID TextVal `xml:"numId"`
+			} `xml:"numPr"`
+		} `xml:"pPr"`
+		RPr struct {
+			RFonts struct {
+				Ascii string `xml:"ascii,attr"`
+				HAnsi string `xml:"hAnsi,attr"`
+			} `xml:"rFonts"`
+		} `xml:"rPr"`
+	} `xml:"style"`
+}
+
 type file struct {
-	rels  Relationships
-	num   Numbering
-	r     *zip.ReadCloser
-	embed bool
-	list  map[string]int
+	rels             Relationships
+	num              Numbering
+	styles           Styles
+	r                *zip.ReadCloser
+	embed            bool
+	part             string
+	slidePart        string
+	layouts          []*Node // slide layout and slide master, read once per slide
+	assetsDir        string
+	assetsLink       string
+	depth            int
+	bullets          bool
+	assets           map[string]string
+	assetNames       map[string]bool
+	images           *[]tools.Image
+	list             map[string][]int
+	started          map[string][]bool
+	restarted        map[string]bool
+	listWidths       []int
+	headings         []heading
+	level            int // level of the last heading written
+	slugs            map[string]int
+	anchors          map[string]string
+	headingBookmarks map[string]bool
+	referenced       map[string]bool
+	fields           []*field
+	tocWritten       bool
+}
+
+// Presentation is the list of slides of ppt/presentation.xml
+type Presentation struct {
+	XMLName  xml.Name `xml:"presentation"`
+	SldIDLst struct {
+		SldID []struct {
+			ID  string `xml:"id,attr"`
+			RID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sldId"`
+	} `xml:"sldIdLst"`
 }
 
 // Node is
@@ -122,6 +187,19 @@ var customHeadings map[string]int = map[string]int{
 	// Ajoutez d'autres styles ici
 }
 
+// codeLanguages maps code styles to the language of the fenced code block
+var codeLanguages map[string]string = map[string]string{
+	"CodeGo":         "go",
+	"CodePython":     "python",
+	"CodeJava":       "java",
+	"CodeJavaScript": "javascript",
+	"CodeSQL":        "sql",
+	"CodeShell":      "shell",
+	"CodeXML":        "xml",
+	"CodeJSON":       "json",
+	// Ajoutez d'autres styles ici
+}
+
 type CoreProperties struct {
 	XMLName     xml.Name `xml:"coreProperties"`
 	Title       string   `xml:"title"`
@@ -142,3 +220,90 @@ type DrawingML struct {
 		} `xml:"cNvPr"`
 	} `xml:"nvPicPr"`
 }
+
+// supportedNamespaces are the mc:Choice requirements handled by the walker
+var supportedNamespaces map[string]bool = map[string]bool{
+	"wps": true, "wpg": true, "wpc": true, "wpi": true,
+	"wp14": true, "w14": true, "w15": true, "w16se": true,
+	"a14": true, "p14": true, "p15": true,
+	"m": true, "v": true, "o": true, "w10": true,
+}
+
+// callouts maps the title of a text box to a github callout
+var callouts map[string]string = map[string]string{
+	"note":          "NOTE",
+	"remarque":      "NOTE",
+	"tip":           "TIP",
+	"astuce":        "TIP",
+	"important":     "IMPORTANT",
+	"warning":       "WARNING",
+	"attention":     "WARNING",
+	"avertissement": "WARNING",
+	"caution":       "CAUTION",
+}
+
+// Workbook is the list of sheets of xl/workbook.xml
+type Workbook struct {
+	XMLName    xml.Name `xml:"workbook"`
+	WorkbookPr struct {
+		Date1904 bool `xml:"date1904,attr"`
+	} `xml:"workbookPr"`
+	Sheets struct {
+		Sheet []struct {
+			Name  string `xml:"name,attr"`
+			State string `xml:"state,attr"`
+			ID    string `xml:"id,attr"`
+		} `xml:"sheet"`
+	} `xml:"sheets"`
+}
+
+// SheetStyles is the number formats of the cells of xl/styles.xml
+type SheetStyles struct {
+	XMLName xml.Name `xml:"styleSheet"`
+	NumFmts struct {
+		NumFmt []struct {
+			NumFmtID   int    `xml:"numFmtId,attr"`
+			FormatCode string `xml:"formatCode,attr"`
+		} `xml:"numFmt"`
+	} `xml:"numFmts"`
+	CellXfs struct {
+		Xf []struct {
+			NumFmtID int `xml:"numFmtId,attr"`
+		} `xml:"xf"`
+	} `xml:"cellXfs"`
+}
+
+// SheetText is a shared string or an inline string, plain or rich text
+type SheetText struct {
+	T string `xml:"t"`
+	R []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+// SheetCell is a cell of a worksheet row
+type SheetCell struct {
+	Ref  string    `xml:"r,attr"`
+	Type string    `xml:"t,attr"`
+	S    int       `xml:"s,attr"`
+	V    string    `xml:"v"`
+	Is   SheetText `xml:"is"`
+}
+
+// SheetRow is a row of the sheetData of a worksheet
+type SheetRow struct {
+	R int         `xml:"r,attr"`
+	C []SheetCell `xml:"c"`
+}
+
+// OdfMeta is the metadata of meta.xml in OpenDocument files
+type OdfMeta struct {
+	XMLName xml.Name `xml:"document-meta"`
+	Meta    struct {
+		Title          string `xml:"title"`
+		Description    string `xml:"description"`
+		Subject        string `xml:"subject"`
+		Creator        string `xml:"creator"`
+		InitialCreator string `xml:"initial-creator"`
+	} `xml:"meta"`
+}
@@ -7,10 +7,9 @@ import (
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"html"
 	"io"
-	"os"
 	"path"
-	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -28,61 +27,85 @@ func (n *Node) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 	return d.DecodeElement((*node)(n), &start)
 }
 
-// escape escapes the characters in a string using the given set of characters.
-func escape(s, set string) string {
-	replacer := []string{}
-	for _, r := range []rune(set) {
-		rs := string(r)
-		replacer = append(replacer, rs, `\`+rs)
-	}
-	return strings.NewReplacer(replacer...).Replace(s)
-}
-
 // extract img and générate markdown image tag with it decription
 func (zf *file) extract(rel *Relationship, w io.Writer, desc string) error {
 
 	description := strings.ReplaceAll(desc, "\n", "")
 
-	err := os.MkdirAll(filepath.Dir(rel.Target), 0755)
+	// linked image, not stored in the document
+	if rel.TargetMode == "External" {
+		fmt.Fprintf(w, "![%s](%s)", description, tools.Escape(rel.Target, "()"))
+		zf.addImage(rel.Target, nil, description)
+		return nil
+	}
+	name, ok := zf.partPath(rel.Target)
+	if !ok {
+		log.Infof("Image target %s is outside of the document, ignored", rel.Target)
+		return nil
+	}
+	f := findFile(zf.r.File, name)
+	if f == nil {
+		log.Infof("Image %s not found in the document", name)
+		return nil
+	}
+	log.Infof("Match Found compute image Name: %s\n and rel.Target %s", f.Name, rel.Target)
+	b, err := readZipFile(f)
 	if err != nil {
 		return err
 	}
-	for _, f := range zf.r.File {
-		log.Infof("File: %s\n", f.Name)
-		log.Infof("Target: %s\n", rel.Target)
-		// replace ../ by ppt/ in rel.Target for pptx case
-		pptxTarget := strings.Replace(rel.Target, "../", "ppt/", 1)
-		if f.Name != "word/"+rel.Target && f.Name != pptxTarget {
-			log.Infof("Not Match")
-			continue
-		}
-		log.Infof("Match Found compute image Name: %s\n and rel.Target %s", f.Name, rel.Target)
-		rc, err := f.Open()
-		if err != nil {
-			return err
-		}
-		defer rc.Close()
-
-		b := make([]byte, f.UncompressedSize64)
-		n, err := rc.Read(b)
-		if err != nil && err != io.EOF {
-			return err
-		}
-		if zf.embed {
-			fmt.Fprintf(w, "![%s](data:image/png;base64,%s)",
-				description, base64.StdEncoding.EncodeToString(b[:n]))
+	// legacy formats are converted to png, or kept as is with a placeholder
+	filename := name
+	placeholder := ""
+	if format := legacyFormat(name, b); format != "" {
+		if converted, err := convertImage(format, b); err == nil {
+			filename = strings.TrimSuffix(name, path.Ext(name)) + ".png"
+			b = converted
 		} else {
-			err = os.WriteFile(rel.Target, b, 0644)
-			if err != nil {
+			log.Infof("Image %s can not be converted: %v", name, err)
+			placeholder = imageMetadata(format, b)
+		}
+	}
+	if placeholder != "" {
+		link := ""
+		if !zf.embed {
+			if link, err = zf.saveAsset(name, filename, b); err != nil {
 				return err
 			}
-			fmt.Fprintf(w, "![%s](%s)", description, escape(rel.Target, "()"))
 		}
-		break
+		writePlaceholder(description, link, placeholder, w)
+		return nil
+	}
+	// without assets folder, images are embedded
+	if zf.embed || zf.assetsDir == "" {
+		fmt.Fprintf(w, "![%s](data:%s;base64,%s)",
+			description, imageMime(filename, b), base64.StdEncoding.EncodeToString(b))
+		zf.addImage(sanitizeName(filename), b, description)
+		return nil
 	}
+	link, err := zf.saveAsset(name, filename, b)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "![%s](%s)", description, tools.Escape(link, "()"))
+	zf.addImage(link, b, description)
 	return nil
 }
 
+// writePlaceholder writes a link to an image which can not be displayed, with
+// its metadata in a comment.
+func writePlaceholder(description, link, metadata string, w io.Writer) {
+	text := "Image not converted"
+	if description != "" {
+		text += ": " + tools.Escape(description, "[]")
+	}
+	if link != "" {
+		fmt.Fprintf(w, "[%s](%s)", text, tools.Escape(link, "()"))
+	} else {
+		fmt.Fprintf(w, "[%s]", text)
+	}
+	fmt.Fprintf(w, "<!-- %s -->", metadata)
+}
+
 // attr returns the value of the attribute with the given name.
 func attr(attrs []xml.Attr, name string) (string, bool) {
 	for _, attr := range attrs {
@@ -93,41 +116,67 @@ func attr(attrs []xml.Attr, name string) (string, bool) {
 	return "", false
 }
 
+// innerText returns the unescaped character data of a leaf node.
+func innerText(node *Node) string {
+	return html.UnescapeString(string(node.Content))
+}
+
+// plainText returns the raw text of a node, without any markdown formatting.
+func plainText(node *Node) string {
+	switch node.XMLName.Local {
+	case "t":
+		return innerText(node)
+	case "tab":
+		return "\t"
+	case "br", "cr":
+		return "\n"
+	case "pPr", "rPr", "instrText", "delText":
+		return ""
+	}
+	var sb strings.Builder
+	for i := range node.Nodes {
+		sb.WriteString(plainText(&node.Nodes[i]))
+	}
+	return sb.String()
+}
+
 // walk traverses the XML tree and writes the content to the writer.
 func (zf *file) walk(node *Node, w io.Writer) error {
 	switch node.XMLName.Local {
 	case "hyperlink":
 		// Traitement des hyperliens
-		fmt.Fprint(w, "[")
 		var cbuf bytes.Buffer
-		for _, n := range node.Nodes {
-			if err := zf.walk(&n, &cbuf); err != nil {
-				return err
-			}
+		if err := zf.walkRuns(node.Nodes, &cbuf); err != nil {
+			return err
+		}
+		if cbuf.Len() == 0 && zf.hidden() {
+			return nil
 		}
-		fmt.Fprint(w, escape(cbuf.String(), "[]"))
+		fmt.Fprint(w, "[")
+		w.Write(cbuf.Bytes())
 		fmt.Fprint(w, "]")
 
 		fmt.Fprint(w, "(")
 		if id, ok := attr(node.Attrs, "id"); ok {
-			for _, rel := range zf.rels.Relationship {
-				if id == rel.ID {
-					fmt.Fprint(w, escape(rel.Target, "()"))
-					break
-				}
-			}
+			fmt.Fprint(w, tools.Escape(zf.relTarget(id), "()"))
+		} else if anchor, ok := attr(node.Attrs, "anchor"); ok {
+			fmt.Fprint(w, tools.Escape(zf.anchor(anchor), "()"))
 		}
 		fmt.Fprint(w, ")")
 	case "t":
 		// Traitement du texte
-		fmt.Fprint(w, string(node.Content))
+		fmt.Fprint(w, innerText(node))
 	case "pPr":
 		// Traitement des propriétés de paragraphe
-		code := false
+		if zf.hidden() {
+			return nil
+		}
+		numbered := child(node, "numPr") != nil
 		for _, n := range node.Nodes {
 			switch n.XMLName.Local {
 			case "ind":
-				if left, ok := attr(n.Attrs, "left"); ok {
+				// list items are indented by their level
+				if left, ok := attr(n.Attrs, "left"); ok && !numbered {
 					if i, err := strconv.Atoi(left); err == nil && i > 0 {
 						fmt.Fprint(w, strings.Repeat("  ", i/360))
 					}
@@ -135,87 +184,23 @@ func (zf *file) walk(node *Node, w io.Writer) error {
 			case "pStyle":
 				if val, ok := attr(n.Attrs, "val"); ok {
 					log.Infof("Style found: %s\n", val) // Debug
-					if strings.HasPrefix(val, "Heading") {
-						if i, err := strconv.Atoi(val[7:]); err == nil && i > 0 {
-							fmt.Fprint(w, strings.Repeat("#", i)+" ")
-						}
-					} else if level, found := customHeadings[val]; found {
+					if level := zf.headingLevel(val); level > 0 {
+						zf.level = level
 						fmt.Fprint(w, strings.Repeat("#", level)+" ")
+					} else if level := zf.tocLevel(val); level > 0 {
+						fmt.Fprint(w, strings.Repeat("  ", level-1)+"- ")
 					} else {
 						log.Infof("Unrecognized style: %s\n", val)
 					}
 				}
-			case "numPr":
-				numID := ""
-				ilvl := ""
-				numFmt := ""
-				start := 1
-				ind := 0
-				for _, nn := range n.Nodes {
-					if nn.XMLName.Local == "numId" {
-						if val, ok := attr(nn.Attrs, "val"); ok {
-							numID = val
-						}
-					}
-					if nn.XMLName.Local == "ilvl" {
-						if val, ok := attr(nn.Attrs, "val"); ok {
-							ilvl = val
-						}
-					}
-				}
-				for _, num := range zf.num.Num {
-					if numID != num.NumID {
-						continue
-					}
-					for _, abnum := range zf.num.AbstractNum {
-						if abnum.AbstractNumID != num.AbstractNumID.Val {
-							continue
-						}
-						for _, ablvl := range abnum.Lvl {
-							if ablvl.Ilvl != ilvl {
-								continue
-							}
-							if i, err := strconv.Atoi(ablvl.Start.Val); err == nil {
-								start = i
-							}
-							if i, err := strconv.Atoi(ablvl.PPr.Ind.Left); err == nil {
-								ind = i / 360
-							}
-							numFmt = ablvl.NumFmt.Val
-							break
-						}
-						break
-					}
-					break
-				}
-
-				fmt.Fprint(w, strings.Repeat("  ", ind))
-				switch numFmt {
-				case "decimal", "aiueoFullWidth":
-					key := fmt.Sprintf("%s:%d", numID, ind)
-					cur, ok := zf.list[key]
-					if !ok {
-						zf.list[key] = start
-					} else {
-						zf.list[key] = cur + 1
-					}
-					fmt.Fprintf(w, "%d. ", zf.list[key])
-				case "bullet":
-					fmt.Fprint(w, "* ")
-				}
 			}
 		}
-		if code {
-			fmt.Fprint(w, "`")
-		}
+		zf.paragraphNumbering(node, w)
 		for _, n := range node.Nodes {
 			if err := zf.walk(&n, w); err != nil {
 				return err
 			}
 		}
-		if code {
-			fmt.Fprint(w, "`")
-		}
 	case "tbl":
 		// Traitement des tableaux
 		var rows [][]string
@@ -233,7 +218,13 @@ func (zf *file) walk(node *Node, w io.Writer) error {
 				if err := zf.walk(&tc, &cbuf); err != nil {
 					return err
 				}
-				content := strings.TrimSpace(cbuf.String())
+				content := cellContent(cbuf.String())
+				// cellules fusionnées avec la cellule précédente (pptx)
+				for _, merge := range []string{"hMerge", "vMerge"} {
+					if v, ok := attr(tc.Attrs, merge); ok && (v == "1" || v == "true") {
+						content = ""
+					}
+				}
 
 				// Vérifiez si cette cellule appartient à une ligne d'entête
 				for _, tcPr := range tc.Nodes {
@@ -254,144 +245,46 @@ func (zf *file) walk(node *Node, w io.Writer) error {
 			rows = append(rows, cols)
 		}
 
-		// Gestion de la largeur des colonnes et affichage
-		maxcol := 0
-		for _, cols := range rows {
-			if len(cols) > maxcol {
-				maxcol = len(cols)
-			}
-		}
-		widths := make([]int, maxcol)
-		for _, row := range rows {
-			for i := 0; i < maxcol; i++ {
-				if i < len(row) {
-					width := runewidth.StringWidth(row[i])
-					if widths[i] < width {
-						widths[i] = width
-					}
-				}
-			}
-		}
-		for i, row := range rows {
-			if i == 0 {
-				// Afficher la première ligne
-				for j := 0; j < maxcol; j++ {
-					fmt.Fprint(w, "|")
-					if j < len(row) {
-						width := runewidth.StringWidth(row[j])
-						fmt.Fprint(w, escape(row[j], "|"))
-						fmt.Fprint(w, strings.Repeat(" ", widths[j]-width))
-					} else {
-						fmt.Fprint(w, strings.Repeat(" ", widths[j]))
-					}
-				}
-				fmt.Fprint(w, "|\n")
-
-				// Ligne de séparation après le header
-				for j := 0; j < maxcol; j++ {
-					fmt.Fprint(w, "|")
-					fmt.Fprint(w, strings.Repeat("-", widths[j]))
-				}
-				fmt.Fprint(w, "|\n")
-			} else {
-				// Lignes normales du tableau
-				for j := 0; j < maxcol; j++ {
-					fmt.Fprint(w, "|")
-					if j < len(row) {
-						width := runewidth.StringWidth(row[j])
-						fmt.Fprint(w, escape(row[j], "|"))
-						fmt.Fprint(w, strings.Repeat(" ", widths[j]-width))
-					} else {
-						fmt.Fprint(w, strings.Repeat(" ", widths[j]))
-					}
-				}
-				fmt.Fprint(w, "|\n")
-			}
-		}
-		fmt.Fprint(w, "\n")
+		writeTable(rows, w)
 	case "r":
-		// Traitement des chaines en gras, italique et barré
-		bold := false
-		italic := false
-		strike := false
-		link := false
-		RelationId := ""
-		for _, n := range node.Nodes {
-			if n.XMLName.Local != "rPr" {
-				continue
-			}
-			// Loop arround subnode XMLName to get bold, italic and strike for pptx context
-			if _, exist := attr(n.Attrs, "b"); exist {
-				bold = true
-			}
-			if _, exist := attr(n.Attrs, "i"); exist {
-				italic = true
-			}
-			if _, exist := attr(n.Attrs, "strike"); exist {
-				strike = true
-			}
-
-			// Loop arround subnode XMLName to get bold, italic and strike for docx context
-			for _, nn := range n.Nodes {
-				switch nn.XMLName.Local {
-				case "b":
-					bold = true
-				case "i":
-					italic = true
-				case "strike":
-					strike = true
-				case "hlinkClick":
-					link = true
-					RelationId, _ = attr(nn.Attrs, "id")
-				}
-			}
-		}
-		if strike {
-			fmt.Fprint(w, "~~")
-		}
-		if bold {
-			fmt.Fprint(w, "**")
-		}
-		if italic {
-			fmt.Fprint(w, "*")
-		}
-		if link {
-			fmt.Fprint(w, "[")
-		}
-		var cbuf bytes.Buffer
-		for _, n := range node.Nodes {
-			if err := zf.walk(&n, &cbuf); err != nil {
-				return err
-			}
-		}
-		fmt.Fprint(w, escape(cbuf.String(), `*~[\`))
-		if italic {
-			fmt.Fprint(w, "*")
-		}
-		if bold {
-			fmt.Fprint(w, "**")
-		}
-		if strike {
-			fmt.Fprint(w, "~~")
-		}
-		if link {
-			fmt.Fprint(w, "](")
-			for _, rel := range zf.rels.Relationship {
-				if RelationId == rel.ID {
-					fmt.Fprint(w, escape(rel.Target, "()"))
-					break
-				}
-			}
-			fmt.Fprint(w, ")")
+		// Traitement des chaines en gras, italique, barré, souligné...
+		if err := zf.walkRuns([]Node{*node}, w); err != nil {
+			return err
 		}
 	case "p":
 		// Traitement des paragraphes
-		for _, n := range node.Nodes {
-			if err := zf.walk(&n, w); err != nil {
-				return err
-			}
+		hidden := zf.hidden()
+		var cbuf bytes.Buffer
+		if err := zf.walkRuns(node.Nodes, &cbuf); err != nil {
+			return err
+		}
+		// paragraphs inside a hidden field result (TOC) are dropped
+		if !hidden || !zf.hidden() || cbuf.Len() > 0 {
+			// bullets of slide paragraphs
+			if marker := zf.bulletMarker(node); marker != "" && strings.TrimSpace(cbuf.String()) != "" {
+				fmt.Fprint(w, marker)
+			}
+			w.Write(cbuf.Bytes())
+			fmt.Fprintln(w)
+		}
+	case "bookmarkStart":
+		zf.bookmark(node, w)
+	case "fldChar":
+		zf.fldChar(node, w)
+	case "instrText":
+		if len(zf.fields) > 0 {
+			zf.fields[len(zf.fields)-1].instr += innerText(node)
+		}
+	case "fldSimple":
+		instr, _ := attr(node.Attrs, "instr")
+		f := &field{instr: instr}
+		zf.fields = append(zf.fields, f)
+		zf.beginFieldResult(f, w)
+		err := zf.walkRuns(node.Nodes, w)
+		zf.fields = zf.fields[:len(zf.fields)-1]
+		if err != nil {
+			return err
 		}
-		fmt.Fprintln(w)
 	case "pic":
 		// manage images get image and description
 		var imageDesc string
@@ -425,34 +318,165 @@ func (zf *file) walk(node *Node, w io.Writer) error {
 			}
 
 		}
-	case "Fallback":
-		// Traitement des fallback
+	case "imagedata":
+		// images of legacy VML shapes
+		if id, ok := attr(node.Attrs, "id"); ok {
+			title, _ := attr(node.Attrs, "title")
+			for _, rel := range zf.rels.Relationship {
+				if id == rel.ID {
+					if err := zf.extract(&rel, w, title); err != nil {
+						return err
+					}
+					break
+				}
+			}
+		}
+	case "oMathPara":
+		// Equation en mode bloc
+		for _, n := range node.Nodes {
+			if n.XMLName.Local != "oMath" {
+				continue
+			}
+			fmt.Fprintf(w, "\n$$\n%s\n$$\n", omml2latex(&n))
+		}
+	case "oMath":
+		// Equation en ligne
+		fmt.Fprintf(w, "$%s$", omml2latex(node))
+	case "AlternateContent":
+		// Traitement des contenus alternatifs (mc:Choice / mc:Fallback)
+		if err := zf.alternateContent(node, w); err != nil {
+			return err
+		}
 	case "txbxContent":
 		// Traitement du contenu des boîtes de texte
-		var cbuf bytes.Buffer
-		for _, n := range node.Nodes {
-			if err := zf.walk(&n, &cbuf); err != nil {
+		if err := zf.textBox(node, w); err != nil {
+			return err
+		}
+	case "object", "oleObj":
+		// Objets OLE incorporés, l'aperçu est utilisé si l'objet n'est pas trouvé
+		found, err := zf.embeddedObject(node, w)
+		if err != nil {
+			return err
+		}
+		if !found {
+			if err := zf.walkBlocks(node.Nodes, w); err != nil {
 				return err
 			}
 		}
-		fmt.Fprintln(w, "\n```\n"+cbuf.String()+"```")
+	case "spTree":
+		// Formes des slides dans l'ordre de lecture
+		if err := zf.shapeTree(node, w); err != nil {
+			return err
+		}
+	case "sp":
+		// Formes des slides
+		if err := zf.shape(node, w); err != nil {
+			return err
+		}
+	case "chart":
+		// Graphiques, convertis en tableau de données
+		if err := zf.chart(node, w); err != nil {
+			return err
+		}
+	case "relIds":
+		// Diagrammes SmartArt
+		if err := zf.smartArt(node, w); err != nil {
+			return err
+		}
 	default:
-		for _, n := range node.Nodes {
-			if err := zf.walk(&n, w); err != nil {
-				return err
-			}
+		if err := zf.walkBlocks(node.Nodes, w); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
+// cellContent returns the content of a table cell on a single line, the
+// paragraphs of the cell are separated by line breaks. Code blocks can't be
+// written in a cell, their lines are inline code.
+func cellContent(s string) string {
+	var lines []string
+	fence := "" // fence of the code block being read
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case fence == "" && strings.HasPrefix(line, "```"):
+			fence = line[:len(line)-len(strings.TrimLeft(line, "`"))]
+		case fence != "" && line == fence:
+			fence = ""
+		case line == "":
+		case fence != "":
+			lines = append(lines, codeSpan(line))
+		default:
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "<br>")
+}
+
+// writeTable writes rows as a markdown table, the first row is the header.
+func writeTable(rows [][]string, w io.Writer) {
+	// Gestion de la largeur des colonnes et affichage
+	maxcol := 0
+	for _, cols := range rows {
+		if len(cols) > maxcol {
+			maxcol = len(cols)
+		}
+	}
+	widths := make([]int, maxcol)
+	for _, row := range rows {
+		for i := 0; i < maxcol; i++ {
+			if i < len(row) {
+				width := runewidth.StringWidth(row[i])
+				if widths[i] < width {
+					widths[i] = width
+				}
+			}
+		}
+	}
+	for i, row := range rows {
+		if i == 0 {
+			// Afficher la première ligne
+			for j := 0; j < maxcol; j++ {
+				fmt.Fprint(w, "|")
+				if j < len(row) {
+					width := runewidth.StringWidth(row[j])
+					fmt.Fprint(w, tools.Escape(row[j], "|"))
+					fmt.Fprint(w, strings.Repeat(" ", widths[j]-width))
+				} else {
+					fmt.Fprint(w, strings.Repeat(" ", widths[j]))
+				}
+			}
+			fmt.Fprint(w, "|\n")
+
+			// Ligne de séparation après le header
+			for j := 0; j < maxcol; j++ {
+				fmt.Fprint(w, "|")
+				fmt.Fprint(w, strings.Repeat("-", widths[j]))
+			}
+			fmt.Fprint(w, "|\n")
+		} else {
+			// Lignes normales du tableau
+			for j := 0; j < maxcol; j++ {
+				fmt.Fprint(w, "|")
+				if j < len(row) {
+					width := runewidth.StringWidth(row[j])
+					fmt.Fprint(w, tools.Escape(row[j], "|"))
+					fmt.Fprint(w, strings.Repeat(" ", widths[j]-width))
+				} else {
+					fmt.Fprint(w, strings.Repeat(" ", widths[j]))
+				}
+			}
+			fmt.Fprint(w, "|\n")
+		}
+	}
+	fmt.Fprint(w, "\n")
+}
+
 // readFile
 func readFile(f *zip.File) (*Node, error) {
-	rc, err := f.Open()
-	defer rc.Close()
-
-	b, _ := io.ReadAll(rc)
+	b, err := readZipFile(f)
 	if err != nil {
 		return nil, err
 	}
@@ -465,17 +489,42 @@ func readFile(f *zip.File) (*Node, error) {
 	return &node, nil
 }
 
+// findFile returns the file of the package with the given name, or matching
+// the given pattern.
 func findFile(files []*zip.File, target string) *zip.File {
 	for _, f := range files {
-		if ok, _ := path.Match(target, f.Name); ok {
+		if ok, _ := path.Match(target, f.Name); ok || f.Name == target {
 			return f
 		}
 	}
 	return nil
 }
 
-// Docx2md return a markdown string from a docx file
-func Docx2md(arg string, embed bool) (string, tools.Metadata, error) {
+// unmarshalPart decodes a part of the package, a missing part is ignored.
+func unmarshalPart(files []*zip.File, name string, v any) error {
+	f := findFile(files, name)
+	if f == nil {
+		return nil
+	}
+	b, err := readZipFile(f)
+	if err != nil {
+		return err
+	}
+	return xml.Unmarshal(b, v)
+}
+
+// Docx2md return a markdown string from a docx file.
+// Images are saved in assetsDir, created next to the markdown file.
+func Docx2md(arg string, embed bool, assetsDir string) (string, tools.Metadata, error) {
+	return readDocx(arg, embed, assetsDir, "", 0)
+}
+
+// readDocx converts a docx file, assets are linked with the assetsLink path
+// and depth is the nesting level of embedded documents.
+func readDocx(arg string, embed bool, assetsDir string, assetsLink string, depth int) (string, tools.Metadata, error) {
+	if err := checkEmbeds(); err != nil {
+		return "", tools.Metadata{}, err
+	}
 
 	r, err := zip.OpenReader(arg)
 	if err != nil {
@@ -485,6 +534,7 @@ func Docx2md(arg string, embed bool) (string, tools.Metadata, error) {
 
 	var rels Relationships
 	var num Numbering
+	var styles Styles
 	var prop CoreProperties
 
 	for _, f := range r.File {
@@ -515,6 +565,19 @@ func Docx2md(arg string, embed bool) (string, tools.Metadata, error) {
 			if err != nil {
 				return "", tools.Metadata{}, err
 			}
+		case "word/styles.xml":
+			rc, err := f.Open()
+			defer rc.Close()
+
+			b, _ := io.ReadAll(rc)
+			if err != nil {
+				return "", tools.Metadata{}, err
+			}
+
+			err = xml.Unmarshal(b, &styles)
+			if err != nil {
+				return "", tools.Metadata{}, err
+			}
 		case "docProps/core.xml":
 			rc, err := f.Open()
 			defer rc.Close()
@@ -543,27 +606,63 @@ func Docx2md(arg string, embed bool) (string, tools.Metadata, error) {
 
 	var buf bytes.Buffer
 	zf := &file{
-		r:     r,
-		rels:  rels,
-		num:   num,
-		embed: embed,
-		list:  make(map[string]int),
+		r:                r,
+		rels:             rels,
+		num:              num,
+		styles:           styles,
+		embed:            embed,
+		part:             "word",
+		assetsDir:        assetsDir,
+		assetsLink:       assetsLink,
+		depth:            depth,
+		assets:           make(map[string]string),
+		assetNames:       make(map[string]bool),
+		slugs:            make(map[string]int),
+		anchors:          make(map[string]string),
+		headingBookmarks: make(map[string]bool),
+		referenced:       make(map[string]bool),
+	}
+	var images []tools.Image
+	if DescribeImages {
+		zf.images = &images
 	}
+	zf.resetNumbering()
+	zf.indexAnchors(node)
+	zf.resetNumbering()
 	err = zf.walk(node, &buf)
 	if err != nil {
 		return "", tools.Metadata{}, err
 	}
+	markdown := buf.String()
+	// document without TOC field, add the table of contents on top
+	if Toc && !zf.tocWritten && len(zf.headings) > 0 {
+		var toc bytes.Buffer
+		zf.writeToc(&toc)
+		markdown = toc.String() + "\n" + markdown
+	}
+	if len(images) > 0 {
+		markdown = tools.DocumentImagesAsMd(markdown, images)
+	}
 	//fmt.Print(buf.String())
 	log.Infof("Properties Title : %s\n", prop.Title)
 	var authors []string
 
 	meta := tools.Metadata{Title: prop.Title, Description: prop.Description, Authors: append(authors, prop.Creator)}
 
-	return buf.String(), meta, nil
+	return markdown, meta, nil
+}
+
+// Pptx2md convert a pptx file to markdown and add metadata header.
+// Images are saved in assetsDir, created next to the markdown file.
+func Pptx2md(pptxPath string, embed bool, assetsDir string) (string, tools.Metadata, error) {
+	return readPptx(pptxPath, embed, assetsDir, "", 0)
 }
 
-// Pptx2md convert a pptx file to markdown and add metadata header
-func Pptx2md(pptxPath string, embed bool) (string, tools.Metadata, error) {
+// readPptx converts a pptx file, see readDocx.
+func readPptx(pptxPath string, embed bool, assetsDir string, assetsLink string, depth int) (string, tools.Metadata, error) {
+	if err := checkEmbeds(); err != nil {
+		return "", tools.Metadata{}, err
+	}
 	// Ouvrir le fichier PPTX
 	r, err := zip.OpenReader(pptxPath)
 	if err != nil {
@@ -571,27 +670,13 @@ func Pptx2md(pptxPath string, embed bool) (string, tools.Metadata, error) {
 	}
 	defer r.Close()
 
-	// Initialiser les variables pour les relations et les propriétés
-	var rels Relationships
+	// Initialiser les variables pour les propriétés
 	var prop CoreProperties
 
 	// Lire les fichiers nécessaires dans le fichier PPTX
 	for _, f := range r.File {
 		log.Debugf("File: %s\n", f.Name)
 		switch f.Name {
-		//case "ppt/_rels/presentation.xml.rels", "ppt/slides/_rels/slide*.xml.rels":
-		//case "ppt/slides/_rels/slide*.xml.rels":
-		case "ppt/slides/_rels/slide1.xml.rels", "ppt/slides/_rels/slide2.xml.rels", "ppt/slides/_rels/slide3.xml.rels":
-			rc, err := f.Open()
-			defer rc.Close()
-			if err != nil {
-				return "", tools.Metadata{}, err
-			}
-			b, _ := io.ReadAll(rc)
-			err = xml.Unmarshal(b, &rels)
-			if err != nil {
-				return "", tools.Metadata{}, err
-			}
 		case "docProps/core.xml":
 			rc, err := f.Open()
 			defer rc.Close()
@@ -606,37 +691,65 @@ func Pptx2md(pptxPath string, embed bool) (string, tools.Metadata, error) {
 		}
 	}
 
-	// Parcourir tous les fichiers de slides
+	// Parcourir les slides dans l'ordre de la présentation
+	slides, err := slideParts(r.File)
+	if err != nil {
+		return "", tools.Metadata{}, err
+	}
 	var buf bytes.Buffer
-	for i := 1; ; i++ {
-		slideName := fmt.Sprintf("ppt/slides/slide%d.xml", i)
-		f := findFile(r.File, slideName)
+	assets := make(map[string]string)
+	assetNames := make(map[string]bool)
+	var images []tools.Image
+	for i, slide := range slides {
+		f := findFile(r.File, slide)
 		if f == nil {
-			break
+			log.Infof("Slide %s not found in the presentation", slide)
+			continue
 		}
 		node, err := readFile(f)
 		if err != nil {
 			return "", tools.Metadata{}, err
 		}
+		if SkipHidden && isHidden(node) {
+			continue
+		}
+		// chaque slide a ses propres relations
+		rels, err := readRels(r.File, slide)
+		if err != nil {
+			return "", tools.Metadata{}, err
+		}
 
 		// Convertir le contenu en Markdown
 		zf := &file{
-			r:     r,
-			rels:  rels,
-			embed: false,
-			list:  make(map[string]int),
-		}
-		err = zf.walk(node, &buf)
+			r:          r,
+			rels:       rels,
+			embed:      embed,
+			part:       path.Dir(slide),
+			slidePart:  slide,
+			assetsDir:  assetsDir,
+			assetsLink: assetsLink,
+			depth:      depth,
+			assets:     assets,
+			assetNames: assetNames,
+		}
+		if DescribeImages {
+			zf.images = &images
+		}
+		zf.resetNumbering()
+		err = zf.slide(node, i+1, &buf)
 		if err != nil {
 			return "", tools.Metadata{}, err
 		}
-		buf.WriteString("\n---\n") // Séparateur entre les slides
+		buf.WriteString("\n\n---\n") // Séparateur entre les slides
 	}
 
 	// Ajouter les métadonnées
 	var authors []string
 	meta := tools.Metadata{Title: prop.Title, Description: prop.Description, Authors: append(authors, prop.Creator)}
 	markdown := buf.String()
+	if len(images) > 0 {
+		markdown = tools.DocumentImagesAsMd(markdown, images)
+	}
 
 	return markdown, meta, nil
 }
@@ -644,7 +757,7 @@ func Pptx2md(pptxPath string, embed bool) (string, tools.Metadata, error) {
 // GetDocx convert a docx file to markdown and add metadata header
 func GetDocx(docxPath string, url string, customerId string, exportDir string, complements tools.Metadata) (tools.Page, error) {
 
-	markdown, meta, err := Docx2md(docxPath, false)
+	markdown, meta, err := Docx2md(docxPath, EmbedImages, tools.BuildAssetsDir(docxPath, exportDir, customerId))
 	tools.CheckError(err)
 
 	// Add metadata header to markdown with title , doc_id,description , tags, site_url, authors, creation_date, last_update
@@ -664,7 +777,7 @@ func GetDocx(docxPath string, url string, customerId string, exportDir string, c
 // GetPptx convert a pptx file to markdown and add metadata header
 func GetPptx(pptxPath string, url string, customerId string, exportDir string, complements tools.Metadata) (tools.Page, error) {
 
-	markdown, meta, err := Pptx2md(pptxPath, false)
+	markdown, meta, err := Pptx2md(pptxPath, EmbedImages, tools.BuildAssetsDir(pptxPath, exportDir, customerId))
 	tools.CheckError(err)
 
 	// Add metadata header to markdown with title , doc_id,description , tags, site_url, authors, creation_date, last_update
@@ -1,8 +1,14 @@
 package docx2md
 
 import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
+
+	"github.com/sacquatella/tomd/tools"
 )
 
 // TestEscape test escape function
@@ -19,7 +25,7 @@ func TestEscape(t *testing.T) {
 		{input: `\200`, escape: `\`, want: `\\200`},
 	}
 	for _, test := range tests {
-		got := escape(test.input, test.escape)
+		got := tools.Escape(test.input, test.escape)
 		if got != test.want {
 			t.Fatalf("want %v, but %v:", test.want, got)
 		}
@@ -33,7 +39,7 @@ func TestDocxToMd_ValidDocx(t *testing.T) {
 	expectedMarkdown := "Titre One\nExemple de texte en HTML \n## Titre Two\nAutre exemple de texte en HTML"
 	embed := false
 
-	result, _, err := Docx2md(docxfile, embed)
+	result, _, err := Docx2md(docxfile, embed, "")
 	if err != nil {
 		t.Errorf("expected no error, got %v", err)
 	}
@@ -41,3 +47,465 @@ func TestDocxToMd_ValidDocx(t *testing.T) {
 		t.Errorf("expected %s to contain %s", result, expectedMarkdown)
 	}
 }
+
+const docxNS = `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" ` +
+	`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" ` +
+	`xmlns:m="http://schemas.openxmlformats.org/officeDocument/2006/math" ` +
+	`xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" ` +
+	`xmlns:wp="http://schemas.openxmlformats.org/drawingml/2006/wordprocessingDrawing" ` +
+	`xmlns:pic="http://schemas.openxmlformats.org/drawingml/2006/picture" ` +
+	`xmlns:mc="http://schemas.openxmlformats.org/markup-compatibility/2006" ` +
+	`xmlns:wps="http://schemas.microsoft.com/office/word/2010/wordprocessingShape" ` +
+	`xmlns:v="urn:schemas-microsoft-com:vml" ` +
+	`xmlns:o="urn:schemas-microsoft-com:office:office" ` +
+	`xmlns:dgm="http://schemas.openxmlformats.org/drawingml/2006/diagram" ` +
+	`xmlns:c="http://schemas.openxmlformats.org/drawingml/2006/chart"`
+
+// buildDocx write a minimal docx file with the given document body and extra parts
+func buildDocx(t *testing.T, body string, parts map[string]string) string {
+	t.Helper()
+	docxfile := filepath.Join(t.TempDir(), "test.docx")
+	out, err := os.Create(docxfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	zw := zip.NewWriter(out)
+	files := map[string]string{
+		"word/document.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<w:document ` + docxNS + `><w:body>` + body + `</w:body></w:document>`,
+	}
+	for name, content := range parts {
+		files[name] = content
+	}
+	for name, content := range files {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return docxfile
+}
+
+// convertDocx convert a generated docx body to markdown
+func convertDocx(t *testing.T, body string, parts map[string]string) string {
+	t.Helper()
+	result, _, err := Docx2md(buildDocx(t, body, parts), false, "")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	return result
+}
+
+// TestDocxToMd_RunFormatting test run formatting toggles, merging and html tags
+func TestDocxToMd_RunFormatting(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "bold off",
+			body: `<w:p><w:r><w:rPr><w:b w:val="0"/></w:rPr><w:t>plain</w:t></w:r></w:p>`,
+			want: "plain\n",
+		},
+		{
+			name: "merged runs",
+			body: `<w:p><w:r><w:rPr><w:b/></w:rPr><w:t xml:space="preserve">bold </w:t></w:r><w:r><w:rPr><w:b w:val="true"/></w:rPr><w:t>text</w:t></w:r></w:p>`,
+			want: "**bold text**\n",
+		},
+		{
+			name: "spaces outside markers",
+			body: `<w:p><w:r><w:t>a</w:t></w:r><w:r><w:rPr><w:i/></w:rPr><w:t xml:space="preserve"> b </w:t></w:r><w:r><w:t>c</w:t></w:r></w:p>`,
+			want: "a *b* c\n",
+		},
+		{
+			name: "superscript and subscript",
+			body: `<w:p><w:r><w:t>x</w:t></w:r><w:r><w:rPr><w:vertAlign w:val="superscript"/></w:rPr><w:t>2</w:t></w:r><w:r><w:t>H</w:t></w:r><w:r><w:rPr><w:vertAlign w:val="subscript"/></w:rPr><w:t>2</w:t></w:r></w:p>`,
+			want: "x<sup>2</sup>H<sub>2</sub>\n",
+		},
+		{
+			name: "underline and highlight",
+			body: `<w:p><w:r><w:rPr><w:u w:val="single"/></w:rPr><w:t>u</w:t></w:r><w:r><w:rPr><w:u w:val="none"/><w:highlight w:val="yellow"/></w:rPr><w:t>h</w:t></w:r></w:p>`,
+			want: "<u>u</u><mark>h</mark>\n",
+		},
+		{
+			name: "monospace font",
+			body: `<w:p><w:r><w:t xml:space="preserve">call </w:t></w:r><w:r><w:rPr><w:rFonts w:ascii="Courier New" w:hAnsi="Courier New"/></w:rPr><w:t>a*b</w:t></w:r></w:p>`,
+			want: "call `a*b`\n",
+		},
+		{
+			name: "escaped text",
+			body: `<w:p><w:r><w:t>a_b &amp; [c]</w:t></w:r></w:p>`,
+			want: "a\\_b & \\[c\\]\n",
+		},
+	}
+	for _, test := range tests {
+		got := convertDocx(t, test.body, nil)
+		if got != test.want {
+			t.Errorf("%s: expected %q, got %q", test.name, test.want, got)
+		}
+	}
+}
+
+// TestDocxToMd_CodeBlock test grouping of code paragraphs into a fenced code block
+func TestDocxToMd_CodeBlock(t *testing.T) {
+	styles := `<w:styles ` + docxNS + `>` +
+		`<w:style w:type="paragraph" w:styleId="Mystyle"><w:name w:val="My Code"/>` +
+		`<w:rPr><w:rFonts w:ascii="Consolas" w:hAnsi="Consolas"/></w:rPr></w:style></w:styles>`
+	body := `<w:p><w:r><w:t>Sample:</w:t></w:r></w:p>` +
+		`<w:p><w:pPr><w:pStyle w:val="SourceCode"/></w:pPr><w:r><w:t xml:space="preserve">  def main():</w:t></w:r></w:p>` +
+		`<w:p><w:pPr><w:pStyle w:val="Mystyle"/></w:pPr><w:r><w:t xml:space="preserve">      print("*")</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>End</w:t></w:r></w:p>`
+	want := "Sample:\n```python\ndef main():\n    print(\"*\")\n```\nEnd\n"
+
+	got := convertDocx(t, body, map[string]string{"word/styles.xml": styles})
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+// TestDocxToMd_TableCells test the paragraphs and the code blocks of table cells
+func TestDocxToMd_TableCells(t *testing.T) {
+	cell := func(paragraphs ...string) string {
+		return `<w:tc>` + strings.Join(paragraphs, "") + `</w:tc>`
+	}
+	p := func(style, text string) string {
+		if style != "" {
+			style = `<w:pPr><w:pStyle w:val="` + style + `"/></w:pPr>`
+		}
+		return `<w:p>` + style + `<w:r><w:t xml:space="preserve">` + text + `</w:t></w:r></w:p>`
+	}
+	body := `<w:tbl><w:tr>` + cell(p("", "Step")) + cell(p("", "Command")) + `</w:tr>` +
+		`<w:tr>` + cell(p("", "Build"), p("", "then run")) +
+		cell(p("", "Type:"), p("SourceCode", "go build"), p("SourceCode", "  echo `ok`")) + `</w:tr></w:tbl>`
+	want := "|Step             |Command                               |\n" +
+		"|-----------------|--------------------------------------|\n" +
+		"|Build<br>then run|Type:<br>`go build`<br>`` echo `ok` ``|\n\n"
+
+	got := convertDocx(t, body, nil)
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+// TestDetectLanguage test the language guess of code blocks
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{code: "package main\n\nfunc main() {\n}", want: "go"},
+		{code: "SELECT id FROM users", want: "sql"},
+		{code: "#include <stdio.h>", want: "c"},
+		{code: "hello world", want: ""},
+	}
+	for _, test := range tests {
+		got := detectLanguage(test.code)
+		if got != test.want {
+			t.Errorf("expected %s, got %s for %s", test.want, got, test.code)
+		}
+	}
+}
+
+// TestDocxToMd_Anchors test bookmarks, internal links and fields
+func TestDocxToMd_Anchors(t *testing.T) {
+	tocEntry := func(anchor, text string) string {
+		return `<w:p><w:pPr><w:pStyle w:val="TOC1"/></w:pPr><w:hyperlink w:anchor="` + anchor + `">` +
+			`<w:r><w:t>` + text + `</w:t></w:r><w:r><w:tab/></w:r>` +
+			`<w:r><w:fldChar w:fldCharType="begin"/></w:r><w:r><w:instrText xml:space="preserve"> PAGEREF ` + anchor + ` \h </w:instrText></w:r>` +
+			`<w:r><w:fldChar w:fldCharType="separate"/></w:r><w:r><w:t>2</w:t></w:r><w:r><w:fldChar w:fldCharType="end"/></w:r></w:hyperlink></w:p>`
+	}
+	styles := `<w:styles ` + docxNS + `><w:style w:type="paragraph" w:styleId="TOC1"><w:name w:val="toc 1"/></w:style></w:styles>`
+	body := `<w:p><w:r><w:fldChar w:fldCharType="begin"/></w:r><w:r><w:instrText xml:space="preserve"> TOC \o "1-3" \h </w:instrText></w:r><w:r><w:fldChar w:fldCharType="separate"/></w:r></w:p>` +
+		tocEntry("_Toc1", "Intro") + tocEntry("_Toc2", "Usage") +
+		`<w:p><w:r><w:fldChar w:fldCharType="end"/></w:r></w:p>` +
+		`<w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr><w:bookmarkStart w:id="0" w:name="_Toc1"/><w:r><w:t>Intro</w:t></w:r><w:bookmarkEnd w:id="0"/></w:p>` +
+		`<w:p><w:bookmarkStart w:id="1" w:name="Note"/><w:r><w:t>A note.</w:t></w:r><w:bookmarkEnd w:id="1"/></w:p>` +
+		`<w:p><w:pPr><w:pStyle w:val="Heading2"/></w:pPr><w:bookmarkStart w:id="2" w:name="_Toc2"/><w:r><w:t>Usage</w:t></w:r><w:bookmarkEnd w:id="2"/></w:p>` +
+		`<w:p><w:r><w:t xml:space="preserve">See </w:t></w:r><w:r><w:fldChar w:fldCharType="begin"/></w:r><w:r><w:instrText xml:space="preserve"> REF Note \h </w:instrText></w:r>` +
+		`<w:r><w:fldChar w:fldCharType="separate"/></w:r><w:r><w:t>the note</w:t></w:r><w:r><w:fldChar w:fldCharType="end"/></w:r>` +
+		`<w:r><w:t xml:space="preserve"> and </w:t></w:r><w:hyperlink w:anchor="_Toc1"><w:r><w:t>intro</w:t></w:r></w:hyperlink></w:p>`
+	parts := map[string]string{"word/styles.xml": styles}
+
+	want := "\n- [Intro](#intro)\n- [Usage](#usage)\n\n# Intro\n<a id=\"note\"></a>A note.\n## Usage\n" +
+		"See [the note](#note) and [intro](#intro)\n"
+	got := convertDocx(t, body, parts)
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	Toc = true
+	defer func() { Toc = false }()
+	want = "- [Intro](#intro)\n  - [Usage](#usage)\n\n\n# Intro\n"
+	got = convertDocx(t, body, parts)
+	if !strings.HasPrefix(got, want) {
+		t.Errorf("expected %q to start with %q", got, want)
+	}
+}
+
+// TestDocxToMd_Numbering test list numbering formats, nesting, restarts and lists starting at 0
+func TestDocxToMd_Numbering(t *testing.T) {
+	numbering := `<w:numbering ` + docxNS + `>` +
+		`<w:abstractNum w:abstractNumId="0">` +
+		`<w:lvl w:ilvl="0"><w:start w:val="1"/><w:numFmt w:val="decimal"/><w:lvlText w:val="%1."/></w:lvl>` +
+		`<w:lvl w:ilvl="1"><w:start w:val="1"/><w:numFmt w:val="lowerLetter"/><w:lvlText w:val="%2)"/></w:lvl>` +
+		`<w:lvl w:ilvl="2"><w:start w:val="1"/><w:numFmt w:val="decimal"/><w:lvlText w:val="%1.%2.%3"/></w:lvl>` +
+		`</w:abstractNum>` +
+		`<w:abstractNum w:abstractNumId="1">` +
+		`<w:lvl w:ilvl="0"><w:start w:val="1"/><w:numFmt w:val="upperRoman"/><w:lvlText w:val="%1."/></w:lvl>` +
+		`</w:abstractNum>` +
+		`<w:num w:numId="1"><w:abstractNumId w:val="0"/></w:num>` +
+		`<w:num w:numId="2"><w:abstractNumId w:val="0"/><w:lvlOverride w:ilvl="0"><w:startOverride w:val="1"/></w:lvlOverride></w:num>` +
+		`<w:abstractNum w:abstractNumId="2">` +
+		`<w:lvl w:ilvl="0"><w:start w:val="0"/><w:numFmt w:val="decimal"/><w:lvlText w:val="%1."/></w:lvl>` +
+		`</w:abstractNum>` +
+		`<w:num w:numId="3"><w:abstractNumId w:val="1"/></w:num>` +
+		`<w:num w:numId="4"><w:abstractNumId w:val="2"/></w:num>` +
+		`</w:numbering>`
+	item := func(numID, ilvl, text string) string {
+		return `<w:p><w:pPr><w:numPr><w:ilvl w:val="` + ilvl + `"/><w:numId w:val="` + numID + `"/></w:numPr>` +
+			`<w:ind w:left="720"/></w:pPr><w:r><w:t>` + text + `</w:t></w:r></w:p>`
+	}
+	body := item("1", "0", "one") + item("1", "1", "sub a") + item("1", "1", "sub b") +
+		item("1", "2", "deep") + item("1", "0", "two") + item("1", "1", "sub a again") +
+		`<w:p><w:r><w:t>Break</w:t></w:r></w:p>` +
+		item("1", "0", "three") + item("2", "0", "restart") + item("3", "0", "roman") + item("3", "0", "roman") +
+		item("4", "0", "zero") + item("4", "0", "one")
+	want := "1. one\n" +
+		"   * a) sub a\n" +
+		"   * b) sub b\n" +
+		"     * 1.b.1 deep\n" +
+		"2. two\n" +
+		"   * a) sub a again\n" +
+		"Break\n" +
+		"3. three\n" +
+		"1. restart\n" +
+		"* I. roman\n" +
+		"* II. roman\n" +
+		"0. zero\n" +
+		"1. one\n"
+
+	got := convertDocx(t, body, map[string]string{"word/numbering.xml": numbering})
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+// TestFormatNumber test Word number formats
+func TestFormatNumber(t *testing.T) {
+	tests := []struct {
+		n      int
+		numFmt string
+		want   string
+	}{
+		{n: 3, numFmt: "decimal", want: "3"},
+		{n: 28, numFmt: "lowerLetter", want: "bb"},
+		{n: 14, numFmt: "upperRoman", want: "XIV"},
+		{n: 9, numFmt: "lowerRoman", want: "ix"},
+		{n: 2, numFmt: "decimalZero", want: "02"},
+	}
+	for _, test := range tests {
+		got := formatNumber(test.n, test.numFmt)
+		if got != test.want {
+			t.Errorf("expected %s, got %s", test.want, got)
+		}
+	}
+}
+
+// picture build a drawingml picture referencing the relationship id
+func picture(id, descr string) string {
+	return `<w:p><w:r><w:drawing><pic:pic><pic:nvPicPr><pic:cNvPr id="1" name="img" descr="` + descr + `"/></pic:nvPicPr>` +
+		`<pic:blipFill><a:blip r:embed="` + id + `"/></pic:blipFill></pic:pic></w:drawing></w:r></w:p>`
+}
+
+// TestDocxToMd_Images test images are saved in the assets folder with safe names
+func TestDocxToMd_Images(t *testing.T) {
+	png := "\x89PNG\r\n\x1a\n0000"
+	parts := map[string]string{
+		"word/_rels/document.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="image" Target="media/my image.png"/>` +
+			`<Relationship Id="rId2" Type="image" Target="other/my image.png"/>` +
+			`<Relationship Id="rId3" Type="image" Target="../../etc/passwd.png"/>` +
+			`<Relationship Id="rId4" Type="image" Target="media/photo.jpeg"/></Relationships>`,
+		"word/media/my image.png": png,
+		"word/other/my image.png": png,
+		"word/media/photo.jpeg":   "\xff\xd8\xff\xe0data",
+		"etc/passwd.png":          png,
+	}
+	body := picture("rId1", "first") + picture("rId2", "second") + picture("rId1", "again") + picture("rId3", "evil")
+	docxfile := buildDocx(t, body, parts)
+
+	assetsDir := filepath.Join(t.TempDir(), "docx-test-assets")
+	result, _, err := Docx2md(docxfile, false, assetsDir)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := "![first](docx-test-assets/my-image.png)\n![second](docx-test-assets/my-image-1.png)\n" +
+		"![again](docx-test-assets/my-image.png)\n\n"
+	if result != want {
+		t.Errorf("expected %q, got %q", want, result)
+	}
+	entries, _ := os.ReadDir(assetsDir)
+	if len(entries) != 2 {
+		t.Errorf("expected 2 images in %s, got %d", assetsDir, len(entries))
+	}
+
+	// another document with the same assets folder keeps the images of the first one
+	other := map[string]string{
+		"word/_rels/document.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="image" Target="media/my image.png"/></Relationships>`,
+		"word/media/my image.png": png + "other",
+	}
+	result, _, err = Docx2md(buildDocx(t, picture("rId1", "other"), other), false, assetsDir)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if want := "![other](docx-test-assets/my-image-2.png)\n"; result != want {
+		t.Errorf("expected %q, got %q", want, result)
+	}
+	if b, _ := os.ReadFile(filepath.Join(assetsDir, "my-image.png")); string(b) != png {
+		t.Errorf("expected the image of the first document, got %q", b)
+	}
+
+	result, _, err = Docx2md(buildDocx(t, picture("rId4", "photo"), parts), true, "")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.HasPrefix(result, "![photo](data:image/jpeg;base64,") {
+		t.Errorf("expected jpeg data uri, got %s", result)
+	}
+
+	// alternative texts are used as descriptions, without calling the vision model
+	defer func() { DescribeImages = false }()
+	DescribeImages = true
+	result, _, err = Docx2md(docxfile, false, assetsDir)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	// the images of the previous conversion are reused
+	want += "\n\n[docx-test-assets/my-image.png]: first\n\n[docx-test-assets/my-image-1.png]: second\n" +
+		"\n[docx-test-assets/my-image.png]: again\n"
+	if result != want {
+		t.Errorf("expected %q, got %q", want, result)
+	}
+}
+
+// textBox build a paragraph with a text box, in a wps shape and in its vml fallback
+func textBox(requires string, paragraphs ...string) string {
+	content := func(suffix string) string {
+		c := `<w:txbxContent>`
+		for _, p := range paragraphs {
+			c += `<w:p><w:r><w:t xml:space="preserve">` + p + suffix + `</w:t></w:r></w:p>`
+		}
+		return c + `</w:txbxContent>`
+	}
+	return `<w:p><w:r><mc:AlternateContent><mc:Choice Requires="` + requires + `"><w:drawing><wp:anchor><a:graphic><a:graphicData>` +
+		`<wps:wsp><wps:txbx>` + content("") + `</wps:txbx></wps:wsp></a:graphicData></a:graphic></wp:anchor></w:drawing></mc:Choice>` +
+		`<mc:Fallback><w:pict><v:shape><v:textbox>` + content(" (vml)") +
+		`</v:textbox></v:shape></w:pict></mc:Fallback></mc:AlternateContent></w:r></w:p>`
+}
+
+// TestDocxToMd_TextBoxes test text boxes are rendered once, as blockquotes or callouts
+func TestDocxToMd_TextBoxes(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "blockquote",
+			body: textBox("wps", "first line", "second line"),
+			want: "\n> first line\n> second line\n\n\n",
+		},
+		{
+			name: "callout",
+			body: textBox("wps", "Note: read this", "carefully"),
+			want: "\n> [!NOTE]\n> read this\n> carefully\n\n\n",
+		},
+		{
+			name: "title alone",
+			body: textBox("wps", "Attention", "hot"),
+			want: "\n> [!WARNING]\n> hot\n\n\n",
+		},
+		{
+			name: "unsupported choice",
+			body: textBox("cx1", "from fallback"),
+			want: "\n> from fallback (vml)\n\n\n",
+		},
+		{
+			name: "empty",
+			body: textBox("wps", " "),
+			want: "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := convertDocx(t, tt.body, nil); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestDocxToMd_SmartArt test SmartArt data models are rendered as nested lists
+func TestDocxToMd_SmartArt(t *testing.T) {
+	point := func(id, text string) string {
+		return `<dgm:pt modelId="` + id + `"><dgm:t><a:bodyPr/><a:p><a:r><a:t>` + text + `</a:t></a:r></a:p></dgm:t></dgm:pt>`
+	}
+	cxn := func(src, dest, ord string) string {
+		return `<dgm:cxn modelId="c` + dest + `" srcId="` + src + `" destId="` + dest + `" srcOrd="` + ord + `"/>`
+	}
+	parts := map[string]string{
+		"word/_rels/document.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId5" Type="diagramData" Target="diagrams/data1.xml"/></Relationships>`,
+		"word/diagrams/data1.xml": `<dgm:dataModel ` + docxNS + `><dgm:ptLst>` +
+			`<dgm:pt modelId="0" type="doc"/>` + point("1", "Plan") + point("2", "Build") + point("3", "Code") +
+			point("4", "Test") + `<dgm:pt modelId="5" type="pres"/><dgm:pt modelId="6" type="parTrans"/>` +
+			`</dgm:ptLst><dgm:cxnLst>` + cxn("0", "2", "1") + cxn("0", "1", "0") + cxn("2", "4", "1") + cxn("2", "3", "0") +
+			`<dgm:cxn modelId="c5" type="presOf" srcId="1" destId="5"/></dgm:cxnLst></dgm:dataModel>`,
+	}
+	body := `<w:p><w:r><w:drawing><wp:inline><a:graphic><a:graphicData>` +
+		`<dgm:relIds r:dm="rId5" r:lo="rId6" r:qs="rId7" r:cs="rId8"/></a:graphicData></a:graphic></wp:inline></w:drawing></w:r></w:p>`
+	want := "\n- Plan\n- Build\n  - Code\n  - Test\n\n\n"
+	if got := convertDocx(t, body, parts); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+// TestDocxToMd_Chart test charts are rendered as tables of their cached values, with escaped names
+func TestDocxToMd_Chart(t *testing.T) {
+	series := func(name string, values ...string) string {
+		ser := `<c:ser><c:idx val="0"/><c:tx><c:strRef><c:f>Sheet1!$B$1</c:f><c:strCache><c:ptCount val="1"/>` +
+			`<c:pt idx="0"><c:v>` + name + `</c:v></c:pt></c:strCache></c:strRef></c:tx>` +
+			`<c:cat><c:strRef><c:f>Sheet1!$A$2:$A$3</c:f><c:strCache><c:ptCount val="2"/>` +
+			`<c:pt idx="0"><c:v>Q1</c:v></c:pt><c:pt idx="1"><c:v>Q2</c:v></c:pt></c:strCache></c:strRef></c:cat>` +
+			`<c:val><c:numRef><c:f>Sheet1!$B$2:$B$3</c:f><c:numCache><c:formatCode>General</c:formatCode>`
+		for i, v := range values {
+			ser += `<c:pt idx="` + strconv.Itoa(i) + `"><c:v>` + v + `</c:v></c:pt>`
+		}
+		return ser + `</c:numCache></c:numRef></c:val></c:ser>`
+	}
+	parts := map[string]string{
+		"word/_rels/document.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId4" Type="chart" Target="charts/chart1.xml"/></Relationships>`,
+		"word/charts/chart1.xml": `<c:chartSpace ` + docxNS + `><c:chart>` +
+			`<c:title><c:tx><c:rich><a:bodyPr/><a:p><a:r><a:t>Sales</a:t></a:r></a:p></c:rich></c:tx></c:title>` +
+			`<c:plotArea><c:barChart><c:barDir val="col"/>` + series("North_1*", "10", "12.5") + series("South", "7") +
+			`</c:barChart><c:catAx><c:axId val="1"/></c:catAx></c:plotArea></c:chart></c:chartSpace>`,
+	}
+	body := `<w:p><w:r><w:drawing><wp:inline><a:graphic><a:graphicData>` +
+		`<c:chart r:id="rId4"/></a:graphicData></a:graphic></wp:inline></w:drawing></w:r></w:p>`
+	want := "\n*Sales (column chart)*\n\n" +
+		"|Category|North\\_1\\*|South|\n|--------|----------|-----|\n|Q1      |10        |7    |\n|Q2      |12.5      |     |\n\n\n"
+	if got := convertDocx(t, body, parts); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,269 @@
+package docx2md
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sacquatella/tomd/tools"
+)
+
+// Embeds is the rendering of embedded objects: link (saved as an attachment),
+// inline (converted under a sub-heading) or files (converted to a linked markdown file)
+var Embeds = "link"
+
+// maxEmbedDepth limits the conversion of documents embedded in embedded documents
+const maxEmbedDepth = 3
+
+// checkEmbeds checks the rendering of embedded objects.
+func checkEmbeds() error {
+	switch Embeds {
+	case "link", "inline", "files":
+		return nil
+	}
+	return fmt.Errorf("unknown embeds rendering %q, expected link, inline or files", Embeds)
+}
+
+// objectID returns the relationship id of an embedded object (w:object, p:oleObj).
+func objectID(node *Node) (string, bool) {
+	if id, ok := attr(node.Attrs, "id"); ok {
+		return id, true
+	}
+	for i := range node.Nodes {
+		switch node.Nodes[i].XMLName.Local {
+		case "OLEObject", "objectEmbed":
+			if id, ok := attr(node.Nodes[i].Attrs, "id"); ok {
+				return id, true
+			}
+		}
+	}
+	return "", false
+}
+
+// packageExt returns the extension of an office file from the parts of the zip.
+func packageExt(b []byte) string {
+	r, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return ".bin"
+	}
+	for _, f := range r.File {
+		switch {
+		case f.Name == "mimetype":
+			// OpenDocument files start with their mime type
+			if rc, err := f.Open(); err == nil {
+				mime, _ := io.ReadAll(io.LimitReader(rc, 128))
+				rc.Close()
+				if ext, ok := odfMimes[strings.TrimSpace(string(mime))]; ok {
+					return ext
+				}
+			}
+		case strings.HasPrefix(f.Name, "word/"):
+			return ".docx"
+		case strings.HasPrefix(f.Name, "ppt/"):
+			return ".pptx"
+		case strings.HasPrefix(f.Name, "xl/"):
+			return ".xlsx"
+		}
+	}
+	return ".zip"
+}
+
+// readCString reads a null terminated string.
+func readCString(b []byte, pos int) (string, int, bool) {
+	end := bytes.IndexByte(b[pos:], 0)
+	if end < 0 {
+		return "", pos, false
+	}
+	return string(b[pos : pos+end]), pos + end + 1, true
+}
+
+// parseOle10Native returns the file wrapped by the Packager in an Ole10Native stream.
+func parseOle10Native(b []byte) (string, []byte, bool) {
+	// size, flags, label, source path, 2 unknown words, temp path, size and data
+	if len(b) < 6 {
+		return "", nil, false
+	}
+	pos := 6
+	label, pos, ok := readCString(b, pos)
+	if !ok {
+		return "", nil, false
+	}
+	if _, pos, ok = readCString(b, pos); !ok || pos+8 > len(b) {
+		return "", nil, false
+	}
+	if _, pos, ok = readCString(b, pos+8); !ok || pos+4 > len(b) {
+		return "", nil, false
+	}
+	size := int(binary.LittleEndian.Uint32(b[pos:]))
+	pos += 4
+	if size < 0 || pos+size > len(b) {
+		return "", nil, false
+	}
+	return path.Base(strings.ReplaceAll(label, `\`, "/")), b[pos : pos+size], true
+}
+
+// unpackObject returns the file stored in an embedded object. Office files are
+// stored as is, other files are wrapped in an OLE compound file (oleObject*.bin).
+func unpackObject(name string, b []byte) (string, []byte) {
+	stem := strings.TrimSuffix(path.Base(name), path.Ext(name))
+	if bytes.HasPrefix(b, []byte("PK\x03\x04")) && strings.EqualFold(path.Ext(name), ".bin") {
+		return stem + packageExt(b), b
+	}
+	cf, err := openCompoundFile(b)
+	if err != nil {
+		return path.Base(name), b
+	}
+	if data, ok := cf.stream("\x01Ole10Native"); ok {
+		if filename, content, ok := parseOle10Native(data); ok && filename != "" {
+			return filename, content
+		}
+	}
+	if data, ok := cf.stream("CONTENTS"); ok && bytes.HasPrefix(data, []byte("%PDF")) {
+		return stem + ".pdf", data
+	}
+	if data, ok := cf.stream("Package"); ok {
+		return stem + packageExt(data), data
+	}
+	// legacy office files are compound files
+	switch {
+	case cf.hasStream("WordDocument"):
+		return stem + ".doc", b
+	case cf.hasStream("Workbook"), cf.hasStream("Book"):
+		return stem + ".xls", b
+	case cf.hasStream("PowerPoint Document"):
+		return stem + ".ppt", b
+	}
+	return path.Base(name), b
+}
+
+// convertObject converts an embedded document with the converter of its type.
+// It reports false for documents without converter.
+func (zf *file) convertObject(filename string, b []byte, assetsDir, assetsLink string) (string, bool, error) {
+	ext := strings.ToLower(path.Ext(filename))
+	switch ext {
+	case ".docx", ".docm", ".dotx", ".pptx", ".pptm", ".ppsx", ".xlsx", ".xlsm", ".odt", ".odp", ".ods", ".pdf":
+	default:
+		return "", false, nil
+	}
+	// converters read files
+	tmp, err := os.CreateTemp("", "tomd-*"+ext)
+	if err != nil {
+		return "", true, err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(b)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", true, err
+	}
+
+	var markdown string
+	switch ext {
+	case ".docx", ".docm", ".dotx":
+		markdown, _, err = readDocx(tmp.Name(), zf.embed, assetsDir, assetsLink, zf.depth+1)
+	case ".pptx", ".pptm", ".ppsx":
+		markdown, _, err = readPptx(tmp.Name(), zf.embed, assetsDir, assetsLink, zf.depth+1)
+	case ".xlsx", ".xlsm":
+		markdown, _, err = Xlsx2md(tmp.Name())
+	case ".odt":
+		markdown, _, err = readOdt(tmp.Name(), zf.embed, assetsDir, assetsLink, zf.depth+1)
+	case ".odp":
+		markdown, _, err = readOdp(tmp.Name(), zf.embed, assetsDir, assetsLink, zf.depth+1)
+	case ".ods":
+		markdown, _, err = Ods2md(tmp.Name())
+	case ".pdf":
+		markdown, err = tools.ExtractTextFromPDF(tmp.Name())
+	}
+	return markdown, true, err
+}
+
+// embeddedObject renders an embedded object: as an attachment, or converted to
+// markdown depending on Embeds. It reports false if the object is not found,
+// so that its preview image is rendered instead.
+func (zf *file) embeddedObject(node *Node, w io.Writer) (bool, error) {
+	id, ok := objectID(node)
+	if !ok {
+		return false, nil
+	}
+	var rel *Relationship
+	for i := range zf.rels.Relationship {
+		if zf.rels.Relationship[i].ID == id {
+			rel = &zf.rels.Relationship[i]
+			break
+		}
+	}
+	if rel == nil {
+		return false, nil
+	}
+	// linked object, not stored in the document
+	if rel.TargetMode == "External" {
+		fmt.Fprintf(w, "[Linked object: %s](%s)", tools.Escape(path.Base(rel.Target), "[]"), tools.Escape(rel.Target, "()"))
+		return true, nil
+	}
+	name, ok := zf.partPath(rel.Target)
+	if !ok {
+		return false, nil
+	}
+	f := findFile(zf.r.File, name)
+	if f == nil {
+		log.Infof("Embedded object %s not found in the document", name)
+		return false, nil
+	}
+	b, err := readZipFile(f)
+	if err != nil {
+		return false, err
+	}
+	filename, data := unpackObject(name, b)
+	label := tools.Escape(filename, "\\*_~[]`")
+
+	if (Embeds == "inline" || Embeds == "files") && zf.depth < maxEmbedDepth {
+		// assets of the embedded document are saved in a sub folder named after the part
+		stem := strings.TrimSuffix(sanitizeName(name), path.Ext(sanitizeName(name)))
+		assetsDir := ""
+		if zf.assetsDir != "" {
+			assetsDir = filepath.Join(zf.assetsDir, stem+"-assets")
+		}
+		assetsLink := ""
+		if Embeds == "inline" {
+			assetsLink = zf.assetsPath() + "/" + stem + "-assets"
+		}
+		markdown, converted, err := zf.convertObject(filename, data, assetsDir, assetsLink)
+		switch {
+		case err != nil:
+			log.Infof("Embedded object %s can not be converted: %v", name, err)
+		case converted && (Embeds == "inline" || zf.assetsDir == ""):
+			// sub-heading of the current heading, also without assets folder for the file
+			heading := strings.Repeat("#", min(zf.level+1, 6))
+			fmt.Fprintf(w, "\n%s Embedded document: %s\n\n%s\n", heading, label, strings.TrimSpace(markdown))
+			return true, nil
+		case converted:
+			link, err := zf.saveAsset(name+".md", stem+".md", []byte(markdown))
+			if err != nil {
+				return false, err
+			}
+			fmt.Fprintf(w, "[Embedded document: %s](%s)", label, tools.Escape(link, "()"))
+			return true, nil
+		}
+	}
+
+	link, err := zf.saveAsset(name, filename, data)
+	if err != nil {
+		return false, err
+	}
+	if link == "" {
+		fmt.Fprintf(w, "[Attachment: %s]", label)
+		return true, nil
+	}
+	fmt.Fprintf(w, "[Attachment: %s](%s)", label, tools.Escape(link, "()"))
+	return true, nil
+}
@@ -0,0 +1,191 @@
+package docx2md
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// buildCFB write a compound file with a single stream, stored in regular sectors
+func buildCFB(name string, data []byte) []byte {
+	le := binary.LittleEndian
+	const sector = 512
+	dataSectors := (len(data) + sector - 1) / sector
+	b := make([]byte, sector*(3+dataSectors))
+	copy(b, cfbMagic)
+	le.PutUint16(b[0x18:], 0x3e)
+	le.PutUint16(b[0x1a:], 3)
+	le.PutUint16(b[0x1c:], 0xfffe)
+	le.PutUint16(b[0x1e:], 9)
+	le.PutUint16(b[0x20:], 6)
+	le.PutUint32(b[0x2c:], 1)
+	le.PutUint32(b[0x30:], 1)
+	le.PutUint32(b[0x38:], 4096)
+	le.PutUint32(b[0x3c:], cfbEndOfChain)
+	le.PutUint32(b[0x44:], cfbEndOfChain)
+	for i := 0; i < 109; i++ {
+		le.PutUint32(b[0x4c+i*4:], cfbFreeSect)
+	}
+	le.PutUint32(b[0x4c:], 0)
+
+	// FAT: sector 0 is the FAT, sector 1 the directory, then the stream
+	fat := b[sector : 2*sector]
+	for i := 0; i < sector/4; i++ {
+		le.PutUint32(fat[i*4:], cfbFreeSect)
+	}
+	le.PutUint32(fat, 0xfffffffd)
+	le.PutUint32(fat[4:], cfbEndOfChain)
+	for i := 0; i < dataSectors; i++ {
+		next := uint32(3 + i)
+		if i == dataSectors-1 {
+			next = cfbEndOfChain
+		}
+		le.PutUint32(fat[(2+i)*4:], next)
+	}
+
+	dir := b[2*sector : 3*sector]
+	entry := func(e []byte, name string, typ byte, start uint32, size int) {
+		u := utf16.Encode([]rune(name))
+		for i, c := range u {
+			le.PutUint16(e[i*2:], c)
+		}
+		le.PutUint16(e[64:], uint16((len(u)+1)*2))
+		e[66] = typ
+		le.PutUint32(e[116:], start)
+		le.PutUint32(e[120:], uint32(size))
+	}
+	entry(dir, "Root Entry", 5, cfbEndOfChain, 0)
+	entry(dir[128:], name, 2, 2, len(data))
+	copy(b[3*sector:], data)
+	return b
+}
+
+// ole10Native wrap a file as the Packager does
+func ole10Native(label string, data []byte) []byte {
+	le := binary.LittleEndian
+	var b []byte
+	b = append(b, 0, 0, 0, 0, 2, 0)
+	b = append(b, label+"\x00"+`C:\`+label+"\x00"...)
+	b = append(b, 0, 0, 3, 0)
+	tmp := `C:\tmp\` + label + "\x00"
+	b = le.AppendUint32(b, uint32(len(tmp)))
+	b = append(b, tmp...)
+	b = le.AppendUint32(b, uint32(len(data)))
+	b = append(b, data...)
+	le.PutUint32(b, uint32(len(b)-4))
+	return b
+}
+
+// TestDocxToMd_Embeddings test embedded documents are attached, inlined or converted to files
+func TestDocxToMd_Embeddings(t *testing.T) {
+	inner, err := os.ReadFile(buildDocx(t, `<w:p><w:r><w:t>inner text</w:t></w:r></w:p>`, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	csv := strings.Repeat("a;b\n", 1100)
+	parts := map[string]string{
+		"word/_rels/document.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId7" Type="package" Target="embeddings/Microsoft_Word_Document.docx"/>` +
+			`<Relationship Id="rId8" Type="oleObject" Target="embeddings/oleObject1.bin"/></Relationships>`,
+		"word/embeddings/Microsoft_Word_Document.docx": string(inner),
+		"word/embeddings/oleObject1.bin":               string(buildCFB("\x01Ole10Native", ole10Native("report.csv", []byte(csv)))),
+	}
+	object := func(id, progID string) string {
+		return `<w:p><w:r><w:object><v:shape><v:imagedata r:id="rId99"/></v:shape>` +
+			`<o:OLEObject Type="Embed" ProgID="` + progID + `" r:id="` + id + `"/></w:object></w:r></w:p>`
+	}
+	// the objects are under a second level heading
+	body := `<w:p><w:pPr><w:pStyle w:val="Heading2"/></w:pPr><w:r><w:t>Annexes</w:t></w:r></w:p>` +
+		object("rId7", "Word.Document.12") + object("rId8", "Package")
+
+	tests := []struct {
+		mode  string
+		want  string
+		files []string
+	}{
+		{
+			mode: "link",
+			want: "## Annexes\n[Attachment: Microsoft\\_Word\\_Document.docx](doc-assets/Microsoft_Word_Document.docx)\n" +
+				"[Attachment: report.csv](doc-assets/report.csv)\n",
+			files: []string{"Microsoft_Word_Document.docx", "report.csv"},
+		},
+		{
+			mode: "inline",
+			want: "## Annexes\n\n### Embedded document: Microsoft\\_Word\\_Document.docx\n\ninner text\n\n" +
+				"[Attachment: report.csv](doc-assets/report.csv)\n",
+			files: []string{"report.csv"},
+		},
+		{
+			mode: "files",
+			want: "## Annexes\n[Embedded document: Microsoft\\_Word\\_Document.docx](doc-assets/Microsoft_Word_Document.md)\n" +
+				"[Attachment: report.csv](doc-assets/report.csv)\n",
+			files: []string{"Microsoft_Word_Document.md", "report.csv"},
+		},
+	}
+	defer func() { Embeds = "link" }()
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			Embeds = tt.mode
+			assetsDir := filepath.Join(t.TempDir(), "doc-assets")
+			result, _, err := Docx2md(buildDocx(t, body, parts), false, assetsDir)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if result != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, result)
+			}
+			for _, name := range tt.files {
+				if _, err := os.Stat(filepath.Join(assetsDir, name)); err != nil {
+					t.Errorf("expected %s in assets, got %v", name, err)
+				}
+			}
+			if b, _ := os.ReadFile(filepath.Join(assetsDir, "report.csv")); string(b) != csv {
+				t.Errorf("expected the packaged file content, got %d bytes", len(b))
+			}
+		})
+	}
+}
+
+// TestDocxToMd_EmbeddingsWithoutAssets test embedded documents are not linked without assets folder
+func TestDocxToMd_EmbeddingsWithoutAssets(t *testing.T) {
+	inner, err := os.ReadFile(buildDocx(t, `<w:p><w:r><w:t>inner text</w:t></w:r></w:p>`, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := map[string]string{
+		"word/_rels/document.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId7" Type="package" Target="embeddings/Microsoft_Word_Document.docx"/></Relationships>`,
+		"word/embeddings/Microsoft_Word_Document.docx": string(inner),
+	}
+	body := `<w:p><w:r><w:object><o:OLEObject Type="Embed" ProgID="Word.Document.12" r:id="rId7"/></w:object></w:r></w:p>`
+
+	tests := []struct {
+		mode string
+		want string
+	}{
+		{"link", "[Attachment: Microsoft\\_Word\\_Document.docx]\n"},
+		{"files", "\n# Embedded document: Microsoft\\_Word\\_Document.docx\n\ninner text\n\n"},
+	}
+	defer func() { Embeds = "link" }()
+	for _, tt := range tests {
+		Embeds = tt.mode
+		if got := convertDocx(t, body, parts); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.mode, tt.want, got)
+		}
+	}
+}
+
+// TestDocxToMd_UnknownEmbeds test an unknown rendering of embedded objects is an error
+func TestDocxToMd_UnknownEmbeds(t *testing.T) {
+	defer func() { Embeds = "link" }()
+	Embeds = "inlined"
+	if _, _, err := Docx2md(buildDocx(t, `<w:p><w:r><w:t>text</w:t></w:r></w:p>`, nil), false, ""); err == nil {
+		t.Errorf("expected an error for the embeds rendering %q", Embeds)
+	}
+	if _, _, err := Pptx2md(buildPptx(t, map[string]string{"ppt/slides/slide1.xml": slideXML("text", false, "")}), false, ""); err == nil {
+		t.Errorf("expected an error for the embeds rendering %q", Embeds)
+	}
+}
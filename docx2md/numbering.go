@@ -0,0 +1,294 @@
+package docx2md
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sacquatella/tomd/tools"
+)
+
+// maxLevels is the number of list levels supported by Word.
+const maxLevels = 9
+
+var lvlTextPlaceholder = regexp.MustCompile(`%([1-9])`)
+
+// listLevel is a resolved numbering level: the abstract definition merged with
+// the overrides of the numbering instance.
+type listLevel struct {
+	lvl     *NumberingLvl
+	key     string
+	start   int
+	restart bool
+}
+
+// findLvl returns the definition of a level in an abstract numbering.
+func (zf *file) findLvl(abstractNumID string, ilvl int) *NumberingLvl {
+	for i := range zf.num.AbstractNum {
+		abnum := &zf.num.AbstractNum[i]
+		if abnum.AbstractNumID != abstractNumID {
+			continue
+		}
+		for j := range abnum.Lvl {
+			if abnum.Lvl[j].Ilvl == strconv.Itoa(ilvl) {
+				return &abnum.Lvl[j]
+			}
+		}
+	}
+	return nil
+}
+
+// resolveLevel returns the level definition used by a numbering instance.
+// Instances of the same abstract numbering share their counters, unless
+// they override the start value, which restarts the list.
+func (zf *file) resolveLevel(numID string, ilvl int) *listLevel {
+	for i := range zf.num.Num {
+		num := &zf.num.Num[i]
+		if num.NumID != numID {
+			continue
+		}
+		level := &listLevel{key: "abstract:" + num.AbstractNumID.Val, start: 1}
+		level.lvl = zf.findLvl(num.AbstractNumID.Val, ilvl)
+		for _, override := range num.LvlOverride {
+			if override.Ilvl != strconv.Itoa(ilvl) {
+				continue
+			}
+			if override.Lvl != nil {
+				level.lvl = override.Lvl
+			}
+			if override.StartOverride != nil {
+				level.key = "num:" + numID
+				level.restart = true
+				if i, err := strconv.Atoi(override.StartOverride.Val); err == nil {
+					level.start = i
+				}
+				return level
+			}
+		}
+		if level.lvl == nil {
+			return nil
+		}
+		if i, err := strconv.Atoi(level.lvl.Start.Val); err == nil {
+			level.start = i
+		}
+		return level
+	}
+	return nil
+}
+
+// styleNumPr returns the numbering set by a paragraph style or one of its parents.
+func (zf *file) styleNumPr(id string) (string, string) {
+	for depth := 0; id != "" && depth < 10; depth++ {
+		basedOn := ""
+		for _, style := range zf.styles.Style {
+			if style.StyleID != id {
+				continue
+			}
+			if style.PPr.NumPr.NumID.Val != "" {
+				return style.PPr.NumPr.NumID.Val, style.PPr.NumPr.Ilvl.Val
+			}
+			basedOn = style.BasedOn.Val
+			break
+		}
+		id = basedOn
+	}
+	return "", ""
+}
+
+// formatNumber formats a list counter with a Word number format.
+func formatNumber(n int, numFmt string) string {
+	switch numFmt {
+	case "lowerLetter":
+		return strings.ToLower(toLetters(n))
+	case "upperLetter":
+		return toLetters(n)
+	case "lowerRoman":
+		return strings.ToLower(toRoman(n))
+	case "upperRoman":
+		return toRoman(n)
+	case "decimalZero":
+		return fmt.Sprintf("%02d", n)
+	case "ordinal":
+		suffix := "th"
+		if n%100 < 11 || n%100 > 13 {
+			switch n % 10 {
+			case 1:
+				suffix = "st"
+			case 2:
+				suffix = "nd"
+			case 3:
+				suffix = "rd"
+			}
+		}
+		return strconv.Itoa(n) + suffix
+	case "none", "bullet":
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// toLetters converts a counter to letters as Word does: A..Z, AA..ZZ, AAA...
+func toLetters(n int) string {
+	if n <= 0 {
+		return strconv.Itoa(n)
+	}
+	letter := string(rune('A' + (n-1)%26))
+	return strings.Repeat(letter, (n-1)/26+1)
+}
+
+// toRoman converts a counter to upper roman numerals.
+func toRoman(n int) string {
+	if n <= 0 || n >= 4000 {
+		return strconv.Itoa(n)
+	}
+	values := []int{1000, 900, 500, 400, 100, 90, 50, 40, 10, 9, 5, 4, 1}
+	symbols := []string{"M", "CM", "D", "CD", "C", "XC", "L", "XL", "X", "IX", "V", "IV", "I"}
+	var sb strings.Builder
+	for i, v := range values {
+		for n >= v {
+			sb.WriteString(symbols[i])
+			n -= v
+		}
+	}
+	return sb.String()
+}
+
+// numbering writes the list marker of a numbered paragraph and updates the
+// list counters. Headings get their number as plain text.
+func (zf *file) numbering(numID string, ilvl int, heading bool, w io.Writer) {
+	if ilvl < 0 || ilvl >= maxLevels {
+		ilvl = 0
+	}
+	level := zf.resolveLevel(numID, ilvl)
+	if level == nil || level.lvl == nil {
+		return
+	}
+
+	// update counters of the list, deeper levels restart after this item
+	counters, ok := zf.list[level.key]
+	if !ok || (level.restart && !zf.restarted[level.key]) {
+		counters = make([]int, maxLevels)
+		zf.list[level.key] = counters
+		zf.started[level.key] = make([]bool, maxLevels)
+		if level.restart {
+			zf.restarted[level.key] = true
+		}
+	}
+	// a level may start at 0, started tells whether it has been used
+	started := zf.started[level.key]
+	if !started[ilvl] {
+		counters[ilvl] = level.start
+		started[ilvl] = true
+	} else {
+		counters[ilvl]++
+	}
+	for d := ilvl + 1; d < maxLevels; d++ {
+		restart := -1
+		if lvl := zf.levelDef(numID, d); lvl != nil && lvl.LvlRestart != nil {
+			restart, _ = strconv.Atoi(lvl.LvlRestart.Val)
+		}
+		if restart == 0 || (restart > 0 && ilvl >= restart) {
+			continue
+		}
+		counters[d] = 0
+		started[d] = false
+	}
+
+	// build the label from the level text, %1 is the counter of the first level
+	numFmt := level.lvl.NumFmt.Val
+	label := lvlTextPlaceholder.ReplaceAllStringFunc(level.lvl.LvlText.Val, func(s string) string {
+		d := int(s[1] - '1')
+		fmtd := numFmt
+		if d != ilvl {
+			fmtd = "decimal"
+			if lvl := zf.levelDef(numID, d); lvl != nil {
+				fmtd = lvl.NumFmt.Val
+			}
+		}
+		if level.lvl.IsLgl != nil {
+			fmtd = "decimal"
+		}
+		n := counters[d]
+		if !started[d] {
+			// a parent level not used yet counts from its start value
+			n = 1
+			if lvl := zf.resolveLevel(numID, d); lvl != nil {
+				n = lvl.start
+			}
+		}
+		return formatNumber(n, fmtd)
+	})
+
+	if heading {
+		if numFmt != "bullet" && label != "" {
+			fmt.Fprint(w, label+" ")
+		}
+		return
+	}
+
+	// nested items are indented to the content of their parent item
+	indent := 0
+	for d := 0; d < ilvl; d++ {
+		if zf.listWidths[d] == 0 {
+			indent += 2
+		} else {
+			indent += zf.listWidths[d]
+		}
+	}
+	var marker, text string
+	switch {
+	case numFmt == "bullet" || label == "":
+		marker = "* "
+	case (numFmt == "decimal" || numFmt == "") && (level.lvl.LvlText.Val == "%"+strconv.Itoa(ilvl+1)+"." ||
+		level.lvl.LvlText.Val == "%"+strconv.Itoa(ilvl+1)+")"):
+		marker = label + " "
+	default:
+		// formats without markdown equivalent are kept as text
+		marker = "* "
+		text = tools.Escape(label, "\\*_~[]`") + " "
+	}
+	zf.listWidths[ilvl] = len(marker)
+	fmt.Fprint(w, strings.Repeat(" ", indent)+marker+text)
+}
+
+// levelDef returns the definition of another level of the same list.
+func (zf *file) levelDef(numID string, ilvl int) *NumberingLvl {
+	if level := zf.resolveLevel(numID, ilvl); level != nil {
+		return level.lvl
+	}
+	return nil
+}
+
+// paragraphNumbering writes the number of a paragraph from its properties (pPr),
+// using the numbering of the paragraph or the one inherited from its style.
+func (zf *file) paragraphNumbering(pPr *Node, w io.Writer) {
+	style := ""
+	if pStyle := child(pPr, "pStyle"); pStyle != nil {
+		style, _ = attr(pStyle.Attrs, "val")
+	}
+	numID, ilvl := zf.styleNumPr(style)
+	if numPr := child(pPr, "numPr"); numPr != nil {
+		if n := child(numPr, "numId"); n != nil {
+			numID, _ = attr(n.Attrs, "val")
+		}
+		if n := child(numPr, "ilvl"); n != nil {
+			ilvl, _ = attr(n.Attrs, "val")
+		}
+	}
+	// numId 0 removes the numbering inherited from the style
+	if numID == "" || numID == "0" {
+		return
+	}
+	level, _ := strconv.Atoi(ilvl)
+	zf.numbering(numID, level, zf.headingLevel(style) > 0, w)
+}
+
+// resetNumbering clears the list counters.
+func (zf *file) resetNumbering() {
+	zf.list = make(map[string][]int)
+	zf.started = make(map[string][]bool)
+	zf.restarted = make(map[string]bool)
+	zf.listWidths = make([]int, maxLevels)
+}
@@ -0,0 +1,1256 @@
+package docx2md
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sacquatella/tomd/tools"
+)
+
+// odfMimes are the extensions of the OpenDocument files by mime type
+var odfMimes = map[string]string{
+	"application/vnd.oasis.opendocument.text":         ".odt",
+	"application/vnd.oasis.opendocument.presentation": ".odp",
+	"application/vnd.oasis.opendocument.spreadsheet":  ".ods",
+}
+
+// odfUnits are the units of the lengths of OpenDocument files, in EMU
+var odfUnits = map[string]float64{
+	"cm": 360000, "mm": 36000, "in": 914400, "pt": 12700, "pc": 152400, "px": 9525,
+}
+
+// contentsStyle matches the paragraph styles of the table of contents entries
+var contentsStyle = regexp.MustCompile(`^Contents_20_(\d+)$`)
+
+// odfStyle is the markdown relevant part of a style of an OpenDocument file
+type odfStyle struct {
+	parent    string
+	text      map[string]string // text properties
+	outline   int               // default outline level of paragraph styles
+	hidden    bool              // hidden slides and sheets
+	automatic bool              // direct formatting of the content
+}
+
+// odfDocument converts the content of an OpenDocument file, images and
+// anchors are handled by its file
+type odfDocument struct {
+	*file
+	styles   map[string]*odfStyle
+	lists    map[string][]bool // numbered levels by list style
+	counters map[string]int    // last number of the lists by list style, for continued lists
+	notes    []string          // footnotes, written at the end of the document
+}
+
+// odfRun is a text of a paragraph with its formatting, or markdown when raw
+type odfRun struct {
+	style runStyle
+	text  string
+	raw   bool
+}
+
+// openOdf reads the metadata and the common styles of an OpenDocument file.
+func openOdf(r *zip.ReadCloser, embed bool, assetsDir string, assetsLink string, depth int) (*odfDocument, tools.Metadata, error) {
+	var m OdfMeta
+	if err := unmarshalPart(r.File, "meta.xml", &m); err != nil {
+		return nil, tools.Metadata{}, err
+	}
+	od := &odfDocument{
+		file: &file{
+			r:                r,
+			embed:            embed,
+			assetsDir:        assetsDir,
+			assetsLink:       assetsLink,
+			depth:            depth,
+			assets:           make(map[string]string),
+			assetNames:       make(map[string]bool),
+			slugs:            make(map[string]int),
+			anchors:          make(map[string]string),
+			headingBookmarks: make(map[string]bool),
+			referenced:       make(map[string]bool),
+		},
+		styles:   make(map[string]*odfStyle),
+		lists:    make(map[string][]bool),
+		counters: make(map[string]int),
+	}
+	if f := findFile(r.File, "styles.xml"); f != nil {
+		node, err := readFile(f)
+		if err != nil {
+			return nil, tools.Metadata{}, err
+		}
+		od.readStyles(node, false)
+	}
+
+	// dc:creator is the last author, meta:initial-creator the first one
+	var authors []string
+	if author := m.Meta.InitialCreator; author != "" {
+		authors = append(authors, author)
+	} else if m.Meta.Creator != "" {
+		authors = append(authors, m.Meta.Creator)
+	}
+	description := m.Meta.Description
+	if description == "" {
+		description = m.Meta.Subject
+	}
+	return od, tools.Metadata{Title: m.Meta.Title, Description: description, Authors: authors}, nil
+}
+
+// readContent reads content.xml with its automatic styles, and returns the
+// body of the given type (text, presentation).
+func (od *odfDocument) readContent(body string) (*Node, error) {
+	f := findFile(od.r.File, "content.xml")
+	if f == nil {
+		return nil, errors.New("incorrect document")
+	}
+	content, err := readFile(f)
+	if err != nil {
+		return nil, err
+	}
+	od.readStyles(content, false)
+	if b := child(content, "body"); b != nil {
+		if node := child(b, body); node != nil {
+			return node, nil
+		}
+	}
+	return nil, errors.New("incorrect document")
+}
+
+// readStyles reads the styles and the list styles of the children of a node,
+// the automatic styles are the direct formatting of the content.
+func (od *odfDocument) readStyles(node *Node, automatic bool) {
+	for i := range node.Nodes {
+		n := &node.Nodes[i]
+		switch n.XMLName.Local {
+		case "automatic-styles":
+			od.readStyles(n, true)
+		case "styles":
+			od.readStyles(n, automatic)
+		case "style":
+			name, _ := attr(n.Attrs, "name")
+			s := &odfStyle{text: make(map[string]string), automatic: automatic}
+			s.parent, _ = attr(n.Attrs, "parent-style-name")
+			if level, ok := attr(n.Attrs, "default-outline-level"); ok {
+				s.outline, _ = strconv.Atoi(level)
+			}
+			for j := range n.Nodes {
+				props := &n.Nodes[j]
+				switch props.XMLName.Local {
+				case "text-properties":
+					for _, a := range props.Attrs {
+						s.text[a.Name.Local] = a.Value
+					}
+				case "drawing-page-properties":
+					v, _ := attr(props.Attrs, "visibility")
+					s.hidden = v == "hidden"
+				case "table-properties":
+					v, _ := attr(props.Attrs, "display")
+					s.hidden = v == "false"
+				}
+			}
+			od.styles[name] = s
+		case "list-style":
+			name, _ := attr(n.Attrs, "name")
+			var levels []bool
+			for j := range n.Nodes {
+				v, _ := attr(n.Nodes[j].Attrs, "level")
+				level, err := strconv.Atoi(v)
+				if err != nil || level < 1 || level > 10 {
+					continue
+				}
+				for len(levels) < level {
+					levels = append(levels, false)
+				}
+				levels[level-1] = n.Nodes[j].XMLName.Local == "list-level-style-number"
+			}
+			od.lists[name] = levels
+		}
+	}
+}
+
+// textStyle returns the formatting of a style, with the properties of the
+// styles it is based on.
+func (od *odfDocument) textStyle(name string) runStyle {
+	var chain []*odfStyle
+	code := false
+	for depth := 0; name != "" && depth < 10; depth++ {
+		code = code || codeStyles[strings.ReplaceAll(name, "_20_", "")]
+		s, ok := od.styles[name]
+		if !ok {
+			break
+		}
+		chain = append(chain, s)
+		name = s.parent
+	}
+	props := make(map[string]string)
+	for i := len(chain) - 1; i >= 0; i-- {
+		maps.Copy(props, chain[i].text)
+	}
+	style := odfRunStyle(props)
+	style.code = style.code || code
+	return style
+}
+
+// paragraphFormat returns the direct formatting of a paragraph. The formatting
+// of common styles, like the bold of headings, is not repeated on the text.
+func (od *odfDocument) paragraphFormat(node *Node) runStyle {
+	name, _ := attr(node.Attrs, "style-name")
+	if s, ok := od.styles[name]; ok && s.automatic {
+		return odfRunStyle(s.text)
+	}
+	return runStyle{}
+}
+
+// odfRunStyle returns the formatting of text properties.
+func odfRunStyle(props map[string]string) runStyle {
+	var style runStyle
+	if weight, err := strconv.Atoi(props["font-weight"]); err == nil {
+		style.bold = weight >= 600
+	} else {
+		style.bold = props["font-weight"] == "bold"
+	}
+	style.italic = props["font-style"] == "italic" || props["font-style"] == "oblique"
+	line := func(key string) bool {
+		return props[key] != "" && props[key] != "none"
+	}
+	style.strike = line("text-line-through-style")
+	style.underline = line("text-underline-style")
+	if position := strings.Fields(props["text-position"]); len(position) > 0 {
+		switch position[0] {
+		case "super":
+			style.sup = true
+		case "sub":
+			style.sub = true
+		default:
+			if f, err := strconv.ParseFloat(strings.TrimSuffix(position[0], "%"), 64); err == nil {
+				style.sup, style.sub = f > 0, f < 0
+			}
+		}
+	}
+	style.caps = props["text-transform"] == "uppercase"
+	style.smallCaps = props["font-variant"] == "small-caps"
+	style.highlight = props["background-color"] != "" && props["background-color"] != "transparent"
+	style.code = isMonospace(props["font-name"]) || isMonospace(props["font-family"])
+	return style
+}
+
+// mergeStyles returns the formatting of a span inside a formatted text.
+func mergeStyles(outer, inner runStyle) runStyle {
+	inner.bold = inner.bold || outer.bold
+	inner.italic = inner.italic || outer.italic
+	inner.strike = inner.strike || outer.strike
+	inner.underline = inner.underline || outer.underline
+	inner.sup = inner.sup || outer.sup
+	inner.sub = inner.sub || outer.sub
+	inner.highlight = inner.highlight || outer.highlight
+	inner.smallCaps = inner.smallCaps || outer.smallCaps
+	inner.caps = inner.caps || outer.caps
+	inner.code = inner.code || outer.code
+	if inner.link == "" {
+		inner.link = outer.link
+	}
+	return inner
+}
+
+// isXMLSpace reports whether a rune is a white space of XML.
+func isXMLSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r'
+}
+
+// collapseSpaces replaces the white space sequences of a text by a space, like
+// OpenDocument applications do. Non breaking spaces are kept.
+func collapseSpaces(s string) string {
+	fields := strings.FieldsFunc(s, isXMLSpace)
+	if len(fields) == 0 {
+		if s == "" {
+			return ""
+		}
+		return " "
+	}
+	text := strings.Join(fields, " ")
+	if isXMLSpace(rune(s[0])) {
+		text = " " + text
+	}
+	if isXMLSpace(rune(s[len(s)-1])) {
+		text += " "
+	}
+	return text
+}
+
+// spaceCount returns the number of spaces of a text:s element.
+func spaceCount(attrs []xml.Attr) int {
+	n := 1
+	if c, ok := attr(attrs, "c"); ok {
+		n, _ = strconv.Atoi(c)
+	}
+	return min(max(n, 1), 100)
+}
+
+// odfMaxRepeat caps the repeated cells and rows of a sheet holding a value,
+// larger repeats fill the sheet up to its last row or column
+const odfMaxRepeat = 1024
+
+// odfRepeat returns the value of a repeat or span attribute, 1 by default.
+func odfRepeat(attrs []xml.Attr, name string) int {
+	v, _ := attr(attrs, name)
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return 1
+	}
+	return min(n, 1<<20)
+}
+
+// odfLength returns a length like 2.5cm in EMU.
+func odfLength(s string) (int64, bool) {
+	for unit, emu := range odfUnits {
+		if v, ok := strings.CutSuffix(s, unit); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			return int64(f * emu), err == nil
+		}
+	}
+	return 0, false
+}
+
+// odfText returns the text of mixed content without formatting. Spaces are
+// collapsed, notes and annotations are skipped.
+func odfText(content []byte) string {
+	d := xml.NewDecoder(bytes.NewReader(content))
+	var sb strings.Builder
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			break
+		}
+		switch t := tok.(type) {
+		case xml.CharData:
+			sb.WriteString(collapseSpaces(string(t)))
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "s":
+				sb.WriteString(strings.Repeat(" ", spaceCount(t.Attr)))
+			case "tab":
+				sb.WriteString("\t")
+			case "line-break":
+				sb.WriteString("\n")
+			case "note", "annotation":
+				d.Skip()
+			}
+		}
+	}
+	return sb.String()
+}
+
+// paragraphsText returns the text of the paragraphs of a node on a line.
+func paragraphsText(node *Node) string {
+	var texts []string
+	var visit func(n *Node)
+	visit = func(n *Node) {
+		if n.XMLName.Local == "p" || n.XMLName.Local == "h" {
+			texts = append(texts, odfText(n.Content))
+			return
+		}
+		for i := range n.Nodes {
+			visit(&n.Nodes[i])
+		}
+	}
+	visit(node)
+	return strings.Join(strings.Fields(strings.Join(texts, " ")), " ")
+}
+
+// link returns the markdown target of a link, internal links point to the
+// anchor of their bookmark or heading.
+func (od *odfDocument) link(href string) string {
+	name, ok := strings.CutPrefix(href, "#")
+	if !ok {
+		return href
+	}
+	if unescaped, err := url.PathUnescape(name); err == nil {
+		name = unescaped
+	}
+	return od.anchor(name)
+}
+
+// indexHeadings collects the headings and the bookmarks before rendering, for
+// the internal links and the table of contents.
+func (od *odfDocument) indexHeadings(node *Node) {
+	for i := range node.Nodes {
+		n := &node.Nodes[i]
+		switch n.XMLName.Local {
+		case "h":
+			text := strings.TrimSpace(strings.ReplaceAll(odfText(n.Content), "\n", " "))
+			if text == "" {
+				break
+			}
+			slug := tools.UniqueSlug(od.slugs, text)
+			od.headings = append(od.headings, heading{level: od.outlineLevel(n), text: text, slug: slug})
+			// links to headings from the navigator
+			od.anchors[text+"|outline"] = slug
+			var visit func(b *Node)
+			visit = func(b *Node) {
+				if name, ok := attr(b.Attrs, "name"); ok && (b.XMLName.Local == "bookmark" || b.XMLName.Local == "bookmark-start") {
+					od.anchors[name] = slug
+					od.headingBookmarks[name] = true
+				}
+				for j := range b.Nodes {
+					visit(&b.Nodes[j])
+				}
+			}
+			visit(n)
+		case "bookmark", "bookmark-start":
+			if name, ok := attr(n.Attrs, "name"); ok {
+				if _, found := od.anchors[name]; !found {
+					od.anchors[name] = tools.Slugify(name)
+				}
+			}
+		case "a":
+			if href, _ := attr(n.Attrs, "href"); strings.HasPrefix(href, "#") {
+				name := href[1:]
+				if unescaped, err := url.PathUnescape(name); err == nil {
+					name = unescaped
+				}
+				od.referenced[name] = true
+			}
+		}
+		od.indexHeadings(n)
+	}
+}
+
+// outlineLevel returns the level of a heading, from its outline level or its style.
+func (od *odfDocument) outlineLevel(node *Node) int {
+	level := 0
+	if v, ok := attr(node.Attrs, "outline-level"); ok {
+		level, _ = strconv.Atoi(v)
+	}
+	name, _ := attr(node.Attrs, "style-name")
+	for depth := 0; level == 0 && name != "" && depth < 10; depth++ {
+		s, ok := od.styles[name]
+		if !ok {
+			break
+		}
+		level, name = s.outline, s.parent
+	}
+	return min(max(level, 1), 6)
+}
+
+// isCode reports whether a paragraph is source code, from the font or the
+// name of its style.
+func (od *odfDocument) isCode(node *Node) bool {
+	if node.XMLName.Local != "p" {
+		return false
+	}
+	name, _ := attr(node.Attrs, "style-name")
+	return name != "" && od.textStyle(name).code
+}
+
+// runs returns the texts of the mixed content of a paragraph with their
+// formatting. Frames, shapes and notes are read as nodes and rendered as markdown.
+func (od *odfDocument) runs(content []byte, base runStyle) ([]odfRun, error) {
+	d := xml.NewDecoder(bytes.NewReader(content))
+	stack := []runStyle{base}
+	var runs []odfRun
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return runs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		style := stack[len(stack)-1]
+		switch t := tok.(type) {
+		case xml.CharData:
+			runs = append(runs, odfRun{style: style, text: collapseSpaces(string(t))})
+		case xml.EndElement:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "span":
+				name, _ := attr(t.Attr, "style-name")
+				style = mergeStyles(style, od.textStyle(name))
+			case "a":
+				href, _ := attr(t.Attr, "href")
+				style.link = od.link(href)
+			case "s":
+				runs = append(runs, odfRun{style: style, text: strings.Repeat(" ", spaceCount(t.Attr))})
+			case "tab":
+				runs = append(runs, odfRun{style: style, text: "\t"})
+			case "line-break":
+				runs = append(runs, odfRun{text: "\n", raw: true})
+			case "bookmark", "bookmark-start":
+				var anchor bytes.Buffer
+				od.bookmark(&Node{Attrs: t.Attr}, &anchor)
+				runs = append(runs, odfRun{text: anchor.String(), raw: true})
+			case "frame", "custom-shape", "g", "note", "annotation":
+				var node Node
+				if err := d.DecodeElement(&node, &t); err != nil {
+					return nil, err
+				}
+				markdown, err := od.inlineNode(&node)
+				if err != nil {
+					return nil, err
+				}
+				runs = append(runs, odfRun{text: markdown, raw: true})
+				continue
+			}
+			stack = append(stack, style)
+		}
+	}
+}
+
+// inline returns the markdown of the mixed content of a paragraph or a
+// heading. Adjacent texts sharing the same formatting are merged so that
+// markers are written once.
+func (od *odfDocument) inline(node *Node, base runStyle) (string, error) {
+	runs, err := od.runs(node.Content, base)
+	if err != nil {
+		return "", err
+	}
+	var sb, text strings.Builder
+	var current runStyle
+	flush := func() {
+		if text.Len() > 0 {
+			sb.WriteString(current.wrap(text.String()))
+			text.Reset()
+		}
+	}
+	for _, run := range runs {
+		if run.raw {
+			flush()
+			sb.WriteString(run.text)
+			continue
+		}
+		// a blank text takes the formatting of the text around it
+		if run.style != current && (strings.TrimSpace(run.text) != "" || text.Len() == 0) {
+			flush()
+			current = run.style
+		}
+		text.WriteString(run.text)
+	}
+	flush()
+	return strings.TrimSpace(sb.String()), nil
+}
+
+// inlineNode returns the markdown of a frame, a shape or a note of a paragraph.
+func (od *odfDocument) inlineNode(node *Node) (string, error) {
+	switch node.XMLName.Local {
+	case "annotation":
+		return "", nil
+	case "note":
+		label := ""
+		if citation := child(node, "note-citation"); citation != nil {
+			label = strings.TrimSpace(odfText(citation.Content))
+		}
+		if label == "" {
+			label = strconv.Itoa(len(od.notes) + 1)
+		}
+		var buf bytes.Buffer
+		if body := child(node, "note-body"); body != nil {
+			if err := od.blocks(body.Nodes, &buf); err != nil {
+				return "", err
+			}
+		}
+		od.notes = append(od.notes, fmt.Sprintf("[^%s]: %s", label, strings.Join(strings.Fields(buf.String()), " ")))
+		return "[^" + label + "]", nil
+	}
+	var buf bytes.Buffer
+	err := od.blocks([]Node{*node}, &buf)
+	return strings.TrimSpace(buf.String()), err
+}
+
+// blocks writes the block elements of a container: body, section, list item,
+// cell, text box... Consecutive code paragraphs are a fenced code block.
+func (od *odfDocument) blocks(nodes []Node, w io.Writer) error {
+	for i := 0; i < len(nodes); i++ {
+		node := &nodes[i]
+		if od.isCode(node) {
+			var lines []string
+			for ; i < len(nodes) && od.isCode(&nodes[i]); i++ {
+				lines = append(lines, strings.Split(odfText(nodes[i].Content), "\n")...)
+			}
+			i--
+			writeCodeBlock(lines, "", w)
+			fmt.Fprintln(w)
+			continue
+		}
+		switch node.XMLName.Local {
+		case "h":
+			text, err := od.inline(node, runStyle{})
+			if err != nil {
+				return err
+			}
+			if text != "" {
+				fmt.Fprintf(w, "%s %s\n\n", strings.Repeat("#", od.outlineLevel(node)), strings.ReplaceAll(text, "\n", " "))
+			}
+		case "p":
+			text, err := od.inline(node, od.paragraphFormat(node))
+			if err != nil {
+				return err
+			}
+			if text != "" {
+				fmt.Fprintf(w, "%s\n\n", text)
+			}
+		case "list":
+			if err := od.list(node, "", "", 0, w); err != nil {
+				return err
+			}
+			fmt.Fprintln(w)
+		case "table":
+			if err := od.table(node, w); err != nil {
+				return err
+			}
+		case "frame":
+			var buf bytes.Buffer
+			if err := od.frame(node, &buf); err != nil {
+				return err
+			}
+			if markdown := strings.TrimSpace(buf.String()); markdown != "" {
+				fmt.Fprintf(w, "%s\n\n", markdown)
+			}
+		case "table-of-content":
+			if err := od.tableOfContents(node, w); err != nil {
+				return err
+			}
+		case "tracked-changes", "sequence-decls", "variable-decls", "user-field-decls", "forms",
+			"annotation", "notes", "page-thumbnail", "desc", "title", "object", "object-ole":
+			// no content, or content rendered elsewhere
+		default:
+			if err := od.blocks(node.Nodes, w); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// list writes the items of a list, indented under the marker of their parent
+// item. Nested lists without style use the style of their parent.
+func (od *odfDocument) list(node *Node, style string, indent string, level int, w io.Writer) error {
+	if name, ok := attr(node.Attrs, "style-name"); ok {
+		style = name
+	}
+	levels := od.lists[style]
+	numbered := level < len(levels) && levels[level]
+	number := 0
+	if v, _ := attr(node.Attrs, "continue-numbering"); v == "true" {
+		number = od.counters[style]
+	} else if _, ok := attr(node.Attrs, "continue-list"); ok {
+		number = od.counters[style]
+	}
+	for i := range node.Nodes {
+		item := &node.Nodes[i]
+		if item.XMLName.Local != "list-item" && item.XMLName.Local != "list-header" {
+			continue
+		}
+		// an item holding only a nested list has no marker
+		marker := ""
+		for j := range item.Nodes {
+			if item.XMLName.Local == "list-item" && item.Nodes[j].XMLName.Local != "list" {
+				if v, ok := attr(item.Attrs, "start-value"); ok {
+					if n, err := strconv.Atoi(v); err == nil {
+						number = n - 1
+					}
+				}
+				number++
+				marker = "- "
+				if numbered {
+					marker = strconv.Itoa(number) + ". "
+				}
+				break
+			}
+		}
+		pad := indent + strings.Repeat(" ", len(marker))
+		prefix := indent + marker
+		for j := range item.Nodes {
+			n := &item.Nodes[j]
+			if n.XMLName.Local == "list" {
+				if err := od.list(n, style, pad, level+1, w); err != nil {
+					return err
+				}
+				prefix = pad
+				continue
+			}
+			var buf bytes.Buffer
+			if err := od.blocks([]Node{*n}, &buf); err != nil {
+				return err
+			}
+			text := strings.TrimSpace(buf.String())
+			if text == "" {
+				continue
+			}
+			for k, line := range strings.Split(text, "\n") {
+				switch {
+				case k == 0:
+					fmt.Fprintln(w, prefix+line)
+				case line == "":
+					fmt.Fprintln(w)
+				default:
+					fmt.Fprintln(w, pad+line)
+				}
+			}
+			prefix = pad
+		}
+	}
+	if level == 0 {
+		od.counters[style] = number
+	}
+	return nil
+}
+
+// table writes a table of a text or a slide, covered cells of merged cells are empty.
+func (od *odfDocument) table(node *Node, w io.Writer) error {
+	var rows [][]string
+	var visit func(n *Node) error
+	visit = func(n *Node) error {
+		for i := range n.Nodes {
+			tr := &n.Nodes[i]
+			switch tr.XMLName.Local {
+			case "table-header-rows", "table-rows", "table-row-group":
+				if err := visit(tr); err != nil {
+					return err
+				}
+			case "table-row":
+				var cols []string
+				for j := range tr.Nodes {
+					tc := &tr.Nodes[j]
+					if tc.XMLName.Local != "table-cell" && tc.XMLName.Local != "covered-table-cell" {
+						continue
+					}
+					var buf bytes.Buffer
+					if tc.XMLName.Local == "table-cell" {
+						if err := od.blocks(tc.Nodes, &buf); err != nil {
+							return err
+						}
+					}
+					content := cellContent(buf.String())
+					for k := 0; k < min(odfRepeat(tc.Attrs, "number-columns-repeated"), 100); k++ {
+						cols = append(cols, content)
+					}
+				}
+				for k := 0; k < min(odfRepeat(tr.Attrs, "number-rows-repeated"), 100); k++ {
+					rows = append(rows, cols)
+				}
+			}
+		}
+		return nil
+	}
+	if err := visit(node); err != nil {
+		return err
+	}
+	if len(rows) > 0 {
+		writeTable(rows, w)
+	}
+	return nil
+}
+
+// frame writes the content of a frame: its image, the replacement image of
+// an object, or its text box and table.
+func (od *odfDocument) frame(node *Node, w io.Writer) error {
+	description := ""
+	for _, name := range []string{"desc", "title"} {
+		if n := child(node, name); n != nil && description == "" {
+			description = strings.TrimSpace(odfText(n.Content))
+		}
+	}
+	// the first image is the preferred one, the next ones are fallbacks
+	if img := child(node, "image"); img != nil {
+		href, _ := attr(img.Attrs, "href")
+		rel := Relationship{Target: href, TargetMode: "External"}
+		if !strings.Contains(href, "://") {
+			if unescaped, err := url.PathUnescape(href); err == nil {
+				href = unescaped
+			}
+			rel = Relationship{Target: strings.TrimPrefix(href, "./")}
+		}
+		if href == "" {
+			log.Infof("Image without link in frame %s, ignored", description)
+			return nil
+		}
+		return od.extract(&rel, w, description)
+	}
+	return od.blocks(node.Nodes, w)
+}
+
+// tableOfContents writes the entries of a table of contents as a list of
+// links, or the table of contents of the document headings with Toc.
+func (od *odfDocument) tableOfContents(node *Node, w io.Writer) error {
+	if Toc {
+		od.writeToc(w)
+		fmt.Fprintln(w)
+		return nil
+	}
+	body := child(node, "index-body")
+	if body == nil {
+		return nil
+	}
+	for i := range body.Nodes {
+		n := &body.Nodes[i]
+		if n.XMLName.Local != "p" {
+			if err := od.blocks([]Node{*n}, w); err != nil {
+				return err
+			}
+			continue
+		}
+		// entries end with a tab and the page number
+		text, _, _ := strings.Cut(odfText(n.Content), "\t")
+		text = tools.Escape(strings.TrimSpace(text), "\\*_~[]`")
+		if text == "" {
+			continue
+		}
+		if a := child(n, "a"); a != nil {
+			href, _ := attr(a.Attrs, "href")
+			text = "[" + text + "](" + tools.Escape(od.link(href), "()") + ")"
+		}
+		level := 1
+		name, _ := attr(n.Attrs, "style-name")
+		for depth := 0; name != "" && depth < 10; depth++ {
+			if m := contentsStyle.FindStringSubmatch(name); m != nil {
+				level, _ = strconv.Atoi(m[1])
+				break
+			}
+			s, ok := od.styles[name]
+			if !ok {
+				break
+			}
+			name = s.parent
+		}
+		fmt.Fprintf(w, "%s- %s\n", strings.Repeat("  ", max(level-1, 0)), text)
+	}
+	fmt.Fprintln(w)
+	return nil
+}
+
+// writeNotes writes the footnotes at the end of the document.
+func (od *odfDocument) writeNotes(w io.Writer) {
+	for _, note := range od.notes {
+		fmt.Fprintln(w, note)
+	}
+	if len(od.notes) > 0 {
+		fmt.Fprintln(w)
+	}
+}
+
+// Odt2md convert an odt file to markdown.
+// Images are saved in assetsDir, created next to the markdown file.
+func Odt2md(odtPath string, embed bool, assetsDir string) (string, tools.Metadata, error) {
+	return readOdt(odtPath, embed, assetsDir, "", 0)
+}
+
+// readOdt converts an odt file, see readDocx.
+func readOdt(odtPath string, embed bool, assetsDir string, assetsLink string, depth int) (string, tools.Metadata, error) {
+	r, err := zip.OpenReader(odtPath)
+	if err != nil {
+		return "", tools.Metadata{}, err
+	}
+	defer r.Close()
+
+	od, meta, err := openOdf(r, embed, assetsDir, assetsLink, depth)
+	if err != nil {
+		return "", tools.Metadata{}, err
+	}
+	body, err := od.readContent("text")
+	if err != nil {
+		return "", tools.Metadata{}, err
+	}
+	var images []tools.Image
+	if DescribeImages {
+		od.images = &images
+	}
+	od.indexHeadings(body)
+	var buf bytes.Buffer
+	if err := od.blocks(body.Nodes, &buf); err != nil {
+		return "", tools.Metadata{}, err
+	}
+	od.writeNotes(&buf)
+	markdown := buf.String()
+	// document without table of contents, add it on top
+	if Toc && !od.tocWritten && len(od.headings) > 0 {
+		var toc bytes.Buffer
+		od.writeToc(&toc)
+		markdown = toc.String() + "\n" + markdown
+	}
+	if len(images) > 0 {
+		markdown = tools.DocumentImagesAsMd(markdown, images)
+	}
+	return markdown, meta, nil
+}
+
+// odpShapes returns the shapes of a slide with their position, with the
+// shapes of the groups.
+func odpShapes(node *Node) []positionedShape {
+	var shapes []positionedShape
+	for i := range node.Nodes {
+		n := &node.Nodes[i]
+		switch n.XMLName.Local {
+		case "g":
+			shapes = append(shapes, odpShapes(n)...)
+		case "frame", "custom-shape", "rect", "ellipse", "polygon", "path", "caption":
+			s := positionedShape{node: n}
+			xs, _ := attr(n.Attrs, "x")
+			ys, _ := attr(n.Attrs, "y")
+			x, okX := odfLength(xs)
+			y, okY := odfLength(ys)
+			s.x, s.y, s.found = x, y, okX && okY
+			shapes = append(shapes, s)
+		}
+	}
+	return shapes
+}
+
+// slide writes a slide: a heading from its title and number, its shapes in
+// reading order and its notes.
+func (od *odfDocument) slide(page *Node, number int, w io.Writer) error {
+	title := ""
+	var shapes []positionedShape
+	for _, s := range odpShapes(page) {
+		switch class, _ := attr(s.node.Attrs, "class"); class {
+		case "title":
+			if title == "" {
+				title = paragraphsText(s.node)
+			}
+		case "page-number", "footer", "date-time", "header":
+		default:
+			shapes = append(shapes, s)
+		}
+	}
+	heading := tools.Escape(title, "\\*_~[]`")
+	if SlideNumbers {
+		if heading == "" {
+			heading = fmt.Sprintf("Slide %d", number)
+		} else {
+			heading = fmt.Sprintf("Slide %d: %s", number, heading)
+		}
+	}
+	if heading != "" {
+		fmt.Fprintf(w, "# %s\n\n", heading)
+	}
+	readingOrder(shapes)
+	for i := range shapes {
+		if err := od.blocks([]Node{*shapes[i].node}, w); err != nil {
+			return err
+		}
+	}
+
+	notesPage := child(page, "notes")
+	if (Notes != "section" && Notes != "quote") || notesPage == nil {
+		return nil
+	}
+	var buf bytes.Buffer
+	for _, s := range odpShapes(notesPage) {
+		if class, _ := attr(s.node.Attrs, "class"); class == "notes" {
+			if err := od.blocks([]Node{*s.node}, &buf); err != nil {
+				return err
+			}
+		}
+	}
+	notes := strings.TrimSpace(buf.String())
+	if notes == "" {
+		return nil
+	}
+	if Notes == "quote" {
+		writeBlockquote("**Notes**\n\n"+notes, w)
+	} else {
+		fmt.Fprintf(w, "## Notes\n\n%s\n\n", notes)
+	}
+	return nil
+}
+
+// Odp2md convert an odp file to markdown, a section per slide.
+// Images are saved in assetsDir, created next to the markdown file.
+func Odp2md(odpPath string, embed bool, assetsDir string) (string, tools.Metadata, error) {
+	return readOdp(odpPath, embed, assetsDir, "", 0)
+}
+
+// readOdp converts an odp file, see readDocx.
+func readOdp(odpPath string, embed bool, assetsDir string, assetsLink string, depth int) (string, tools.Metadata, error) {
+	r, err := zip.OpenReader(odpPath)
+	if err != nil {
+		return "", tools.Metadata{}, err
+	}
+	defer r.Close()
+
+	od, meta, err := openOdf(r, embed, assetsDir, assetsLink, depth)
+	if err != nil {
+		return "", tools.Metadata{}, err
+	}
+	body, err := od.readContent("presentation")
+	if err != nil {
+		return "", tools.Metadata{}, err
+	}
+	var images []tools.Image
+	if DescribeImages {
+		od.images = &images
+	}
+	var buf bytes.Buffer
+	number := 0
+	for i := range body.Nodes {
+		page := &body.Nodes[i]
+		if page.XMLName.Local != "page" {
+			continue
+		}
+		number++
+		name, _ := attr(page.Attrs, "style-name")
+		if s, ok := od.styles[name]; ok && s.hidden && SkipHidden {
+			continue
+		}
+		if err := od.slide(page, number, &buf); err != nil {
+			return "", tools.Metadata{}, err
+		}
+		buf.WriteString("---\n\n") // Séparateur entre les slides
+	}
+	markdown := buf.String()
+	if len(images) > 0 {
+		markdown = tools.DocumentImagesAsMd(markdown, images)
+	}
+	return markdown, meta, nil
+}
+
+// odsValue returns the text of a cell of a spreadsheet: its paragraphs, as
+// displayed, or else its value.
+func odsValue(cell *Node) string {
+	var lines []string
+	for i := range cell.Nodes {
+		if cell.Nodes[i].XMLName.Local == "p" {
+			lines = append(lines, odfText(cell.Nodes[i].Content))
+		}
+	}
+	if text := strings.Join(lines, "\n"); strings.TrimSpace(text) != "" {
+		return text
+	}
+	typ, _ := attr(cell.Attrs, "value-type")
+	switch typ {
+	case "float", "percentage", "currency":
+		v, _ := attr(cell.Attrs, "value")
+		return v
+	case "date":
+		v, _ := attr(cell.Attrs, "date-value")
+		return strings.TrimSuffix(v, "T00:00:00")
+	case "time":
+		v, _ := attr(cell.Attrs, "time-value")
+		return v
+	case "boolean":
+		v, _ := attr(cell.Attrs, "boolean-value")
+		return strings.ToUpper(v)
+	case "string":
+		v, _ := attr(cell.Attrs, "string-value")
+		return v
+	}
+	return ""
+}
+
+// readOdsSheet reads the cells of a table of a spreadsheet until its end.
+// Rows are decoded one by one, and only counted beyond MaxRows. Repeated
+// empty rows and cells, filling the sheets up to their last row and column,
+// are only counted.
+func readOdsSheet(d *xml.Decoder) (*sheet, error) {
+	sh := &sheet{cells: make(map[[2]int]string), lastRow: -1}
+	rowIndex, read := 0, 0
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.EndElement:
+			if t.Name.Local == "table" {
+				return sh, nil
+			}
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "shapes":
+				if err := d.Skip(); err != nil {
+					return nil, err
+				}
+			case "table-row":
+				var row Node
+				if err := d.DecodeElement(&row, &t); err != nil {
+					return nil, err
+				}
+				values := make(map[int]string)
+				col := 0
+				for i := range row.Nodes {
+					c := &row.Nodes[i]
+					if c.XMLName.Local != "table-cell" && c.XMLName.Local != "covered-table-cell" {
+						continue
+					}
+					repeat := odfRepeat(c.Attrs, "number-columns-repeated")
+					if v := cellContent(tools.Escape(odsValue(c), "\\*_~[]`")); v != "" {
+						for k := 0; k < min(repeat, odfMaxRepeat); k++ {
+							values[col+k] = v
+						}
+						cols, rows := odfRepeat(c.Attrs, "number-columns-spanned"), odfRepeat(c.Attrs, "number-rows-spanned")
+						if cols > 1 || rows > 1 {
+							sh.merges = append(sh.merges, [4]int{rowIndex, col, rowIndex + rows - 1, col + cols - 1})
+						}
+					}
+					col += repeat
+				}
+				repeat := odfRepeat(row.Attrs, "number-rows-repeated")
+				if len(values) == 0 {
+					rowIndex += repeat
+					continue
+				}
+				// rows beyond the cap are not shown, like the rows beyond MaxRows
+				written := min(repeat, odfMaxRepeat)
+				for k := 0; k < written; k++ {
+					if MaxRows > 0 && read >= MaxRows {
+						sh.skipped += written - k
+						break
+					}
+					read++
+					for col, v := range values {
+						sh.cells[[2]int{rowIndex + k, col}] = v
+					}
+					sh.lastRow = rowIndex + k
+				}
+				sh.skipped += repeat - written
+				rowIndex += repeat
+			}
+		}
+	}
+}
+
+// Ods2md convert an ods file to markdown, each sheet is a table under a heading.
+func Ods2md(odsPath string) (string, tools.Metadata, error) {
+	r, err := zip.OpenReader(odsPath)
+	if err != nil {
+		return "", tools.Metadata{}, err
+	}
+	defer r.Close()
+
+	od, meta, err := openOdf(r, false, "", "", 0)
+	if err != nil {
+		return "", tools.Metadata{}, err
+	}
+	f := findFile(r.File, "content.xml")
+	if f == nil {
+		return "", tools.Metadata{}, errors.New("incorrect document")
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return "", tools.Metadata{}, err
+	}
+	defer rc.Close()
+
+	// the content of huge sheets is read row by row
+	var buf bytes.Buffer
+	d := xml.NewDecoder(rc)
+	number := 0
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", tools.Metadata{}, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "automatic-styles":
+			var node Node
+			if err := d.DecodeElement(&node, &start); err != nil {
+				return "", tools.Metadata{}, err
+			}
+			od.readStyles(&Node{Nodes: []Node{node}}, false)
+		case "table":
+			number++
+			name, _ := attr(start.Attr, "name")
+			style, _ := attr(start.Attr, "style-name")
+			state := ""
+			if s, ok := od.styles[style]; ok && s.hidden {
+				state = "hidden"
+			}
+			if !selectedSheet(name, number, state) {
+				if err := d.Skip(); err != nil {
+					return "", tools.Metadata{}, err
+				}
+				continue
+			}
+			sh, err := readOdsSheet(d)
+			if err != nil {
+				return "", tools.Metadata{}, err
+			}
+			fmt.Fprintf(&buf, "# %s\n\n", tools.Escape(name, "\\*_~[]`"))
+			if rows := sh.rows(); len(rows) > 0 {
+				writeTable(rows, &buf)
+			}
+			if sh.skipped > 0 {
+				fmt.Fprintf(&buf, "*%d more rows not shown*\n\n", sh.skipped)
+			}
+		}
+	}
+	return buf.String(), meta, nil
+}
+
+// GetOdt convert an odt file to markdown and add metadata header
+func GetOdt(odtPath string, url string, customerId string, exportDir string, complements tools.Metadata) (tools.Page, error) {
+
+	markdown, meta, err := Odt2md(odtPath, EmbedImages, tools.BuildAssetsDir(odtPath, exportDir, customerId))
+	tools.CheckError(err)
+
+	// Add metadata header to markdown with title , doc_id,description , tags, site_url, authors, creation_date, last_update
+	metadata, metaDatas := tools.BuildFileMetadata(odtPath, url, customerId, meta, complements)
+
+	// Add metadata header to markdown
+	markdown = metadata + markdown
+
+	exportedFile := tools.BuildFilename(metaDatas.Title, exportDir, customerId)
+	// Écrire le Markdown dans un fichier
+	err = tools.WriteMarkdownToFile(markdown, exportedFile)
+	tools.CheckError(err)
+
+	return tools.Page{PageId: metaDatas.Doc_id, Url: metaDatas.Site_url, MdFile: exportedFile}, nil
+}
+
+// GetOdp convert an odp file to markdown and add metadata header
+func GetOdp(odpPath string, url string, customerId string, exportDir string, complements tools.Metadata) (tools.Page, error) {
+
+	markdown, meta, err := Odp2md(odpPath, EmbedImages, tools.BuildAssetsDir(odpPath, exportDir, customerId))
+	tools.CheckError(err)
+
+	// Add metadata header to markdown with title , doc_id,description , tags, site_url, authors, creation_date, last_update
+	metadata, metaDatas := tools.BuildFileMetadata(odpPath, url, customerId, meta, complements)
+
+	// Add metadata header to markdown
+	markdown = metadata + markdown
+
+	exportedFile := tools.BuildFilename(metaDatas.Title, exportDir, customerId)
+	// Écrire le Markdown dans un fichier
+	err = tools.WriteMarkdownToFile(markdown, exportedFile)
+	tools.CheckError(err)
+
+	return tools.Page{PageId: metaDatas.Doc_id, Url: metaDatas.Site_url, MdFile: exportedFile}, nil
+}
+
+// GetOds convert an ods file to markdown and add metadata header
+func GetOds(odsPath string, url string, customerId string, exportDir string, complements tools.Metadata) (tools.Page, error) {
+
+	markdown, meta, err := Ods2md(odsPath)
+	tools.CheckError(err)
+
+	// Add metadata header to markdown with title , doc_id,description , tags, site_url, authors, creation_date, last_update
+	metadata, metaDatas := tools.BuildFileMetadata(odsPath, url, customerId, meta, complements)
+
+	// Add metadata header to markdown
+	markdown = metadata + markdown
+
+	exportedFile := tools.BuildFilename(metaDatas.Title, exportDir, customerId)
+	// Écrire le Markdown dans un fichier
+	err = tools.WriteMarkdownToFile(markdown, exportedFile)
+	tools.CheckError(err)
+
+	return tools.Page{PageId: metaDatas.Doc_id, Url: metaDatas.Site_url, MdFile: exportedFile}, nil
+}
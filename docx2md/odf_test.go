@@ -0,0 +1,182 @@
+package docx2md
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+const odfNS = `xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" ` +
+	`xmlns:style="urn:oasis:names:tc:opendocument:xmlns:style:1.0" ` +
+	`xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0" ` +
+	`xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0" ` +
+	`xmlns:draw="urn:oasis:names:tc:opendocument:xmlns:drawing:1.0" ` +
+	`xmlns:fo="urn:oasis:names:tc:opendocument:xmlns:xsl-fo-compatible:1.0" ` +
+	`xmlns:xlink="http://www.w3.org/1999/xlink" ` +
+	`xmlns:svg="urn:oasis:names:tc:opendocument:xmlns:svg-compatible:1.0" ` +
+	`xmlns:presentation="urn:oasis:names:tc:opendocument:xmlns:presentation:1.0" ` +
+	`xmlns:dc="http://purl.org/dc/elements/1.1/" ` +
+	`xmlns:meta="urn:oasis:names:tc:opendocument:xmlns:meta:1.0"`
+
+// buildOdf write a minimal OpenDocument file with its content and metadata
+func buildOdf(t *testing.T, name string, mime string, styles string, body string) string {
+	return buildZip(t, name, map[string]string{
+		"mimetype": mime,
+		"meta.xml": `<office:document-meta ` + odfNS + `><office:meta>` +
+			`<dc:title>Partner guide</dc:title><dc:creator>Bob</dc:creator>` +
+			`<meta:initial-creator>Alice</meta:initial-creator><dc:subject>Onboarding</dc:subject>` +
+			`</office:meta></office:document-meta>`,
+		"content.xml": `<office:document-content ` + odfNS + `>` +
+			`<office:automatic-styles>` + styles + `</office:automatic-styles>` +
+			`<office:body>` + body + `</office:body></office:document-content>`,
+		"Pictures/logo.png": "png",
+	})
+}
+
+// TestOdtToMd test the headings, formatting, lists, tables, images, links and notes of an odt file
+func TestOdtToMd(t *testing.T) {
+	styles := `<style:style style:name="T1" style:family="text"><style:text-properties fo:font-weight="bold"/></style:style>` +
+		`<style:style style:name="T2" style:family="text"><style:text-properties fo:font-style="italic"/></style:style>` +
+		`<text:list-style style:name="L1"><text:list-level-style-number text:level="1"/>` +
+		`<text:list-level-style-bullet text:level="2"/></text:list-style>`
+	body := `<office:text>` +
+		`<text:h text:outline-level="1">Getting <text:span text:style-name="T2">started</text:span></text:h>` +
+		`<text:p>Read   the <text:span text:style-name="T1">setup</text:span> and see ` +
+		`<text:a xlink:href="#Usage">usage</text:a>.<text:note text:note-class="footnote">` +
+		`<text:note-citation>1</text:note-citation><text:note-body><text:p>A note.</text:p></text:note-body></text:note></text:p>` +
+		`<text:list text:style-name="L1"><text:list-item><text:p>Install</text:p>` +
+		`<text:list><text:list-item><text:p>Linux</text:p></text:list-item></text:list></text:list-item>` +
+		`<text:list-item><text:p>Run</text:p></text:list-item></text:list>` +
+		`<table:table><table:table-row><table:table-cell><text:p>Name</text:p></table:table-cell>` +
+		`<table:table-cell><text:p>Value</text:p></table:table-cell></table:table-row>` +
+		`<table:table-row table:number-rows-repeated="2"><table:table-cell><text:p>a</text:p></table:table-cell>` +
+		`<table:table-cell><text:p>1</text:p></table:table-cell></table:table-row></table:table>` +
+		`<text:p><draw:frame><draw:image xlink:href="Pictures/logo.png"/><svg:desc>Logo</svg:desc></draw:frame></text:p>` +
+		`<text:h text:outline-level="2">Usage</text:h>` +
+		`</office:text>`
+	odt := buildOdf(t, "test.odt", "application/vnd.oasis.opendocument.text", styles, body)
+
+	markdown, meta, err := Odt2md(odt, true, "")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, want := range []string{
+		"# Getting *started*\n\n",
+		"Read the **setup** and see [usage](#usage).[^1]\n\n",
+		"1. Install\n   - Linux\n2. Run\n\n",
+		"|Name|Value|",
+		"|a   |1    |\n|a   |1    |\n\n",
+		"![Logo](data:image/png;base64,",
+		"## Usage\n\n",
+		"[^1]: A note.\n",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("expected %q in %q", want, markdown)
+		}
+	}
+	if meta.Title != "Partner guide" || meta.Description != "Onboarding" || len(meta.Authors) != 1 || meta.Authors[0] != "Alice" {
+		t.Errorf("expected the metadata of meta.xml, got %+v", meta)
+	}
+}
+
+// TestOdpToMd test the slides of an odp file, with their title, content, notes and the hidden slides
+func TestOdpToMd(t *testing.T) {
+	defer func() { SkipHidden, Notes, SlideNumbers = false, "section", true }()
+	styles := `<style:style style:name="dp2" style:family="drawing-page">` +
+		`<style:drawing-page-properties presentation:visibility="hidden"/></style:style>`
+	frame := func(class, x, y, text string) string {
+		return `<draw:frame presentation:class="` + class + `" svg:x="` + x + `" svg:y="` + y + `">` +
+			`<draw:text-box><text:p>` + text + `</text:p></draw:text-box></draw:frame>`
+	}
+	body := `<office:presentation>` +
+		`<draw:page draw:name="p1">` + frame("outline", "2cm", "5cm", "Second") + frame("title", "2cm", "1cm", "Welcome") +
+		frame("subtitle", "2cm", "3cm", "First") +
+		`<presentation:notes>` + frame("notes", "1cm", "1cm", "Say hello") + `</presentation:notes></draw:page>` +
+		`<draw:page draw:name="p2" draw:style-name="dp2">` + frame("title", "2cm", "1cm", "Hidden") + `</draw:page>` +
+		`<draw:page draw:name="p3">` + frame("title", "2cm", "1cm", "End") + `</draw:page>` +
+		`</office:presentation>`
+	odp := buildOdf(t, "test.odp", "application/vnd.oasis.opendocument.presentation", styles, body)
+
+	tests := []struct {
+		skipHidden bool
+		notes      string
+		want       string
+	}{
+		{false, "section", "# Slide 1: Welcome\n\nFirst\n\nSecond\n\n## Notes\n\nSay hello\n\n---\n\n" +
+			"# Slide 2: Hidden\n\n---\n\n# Slide 3: End\n\n---\n\n"},
+		{true, "none", "# Slide 1: Welcome\n\nFirst\n\nSecond\n\n---\n\n# Slide 3: End\n\n---\n\n"},
+	}
+	for _, test := range tests {
+		SkipHidden, Notes = test.skipHidden, test.notes
+		markdown, _, err := Odp2md(odp, true, "")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if markdown != test.want {
+			t.Errorf("expected %q, got %q", test.want, markdown)
+		}
+	}
+}
+
+// TestOdsToMd test the sheets of an ods file, with repeated rows and cells and a hidden sheet
+func TestOdsToMd(t *testing.T) {
+	defer func() { Sheets, MaxRows = nil, 0 }()
+	styles := `<style:style style:name="ta2" style:family="table"><style:table-properties table:display="false"/></style:style>`
+	cell := func(attrs, text string) string {
+		return `<table:table-cell ` + attrs + `><text:p>` + text + `</text:p></table:table-cell>`
+	}
+	body := `<office:spreadsheet>` +
+		`<table:table table:name="Prices"><table:table-row>` + cell("", "Item") + cell("", "Price") + `</table:table-row>` +
+		`<table:table-row table:number-rows-repeated="2">` + cell(`table:number-columns-repeated="2"`, "x") + `</table:table-row>` +
+		`<table:table-row table:number-rows-repeated="1048570"><table:table-cell table:number-columns-repeated="1024"/></table:table-row>` +
+		`</table:table>` +
+		`<table:table table:name="Filled"><table:table-row>` + cell("", "Total") + `</table:table-row>` +
+		`<table:table-row table:number-rows-repeated="1048575">` + cell("", "y") + `</table:table-row></table:table>` +
+		`<table:table table:name="Secret" table:style-name="ta2"><table:table-row>` + cell("", "hidden") + `</table:table-row></table:table>` +
+		`<table:table table:name="S_1*"><table:table-row>` + cell("", "a*b*c [x](y) __init__") + cell("", "```") + `</table:table-row></table:table>` +
+		`</office:spreadsheet>`
+	ods := buildOdf(t, "test.ods", "application/vnd.oasis.opendocument.spreadsheet", styles, body)
+
+	tests := []struct {
+		sheets  []string
+		maxRows int
+		want    []string
+		absent  string
+	}{
+		{nil, 0, []string{"# Prices\n\n", "|Item|Price|", "|x   |x    |", "# Filled\n\n", "*1047551 more rows not shown*"}, "Secret"},
+		{[]string{"Secret"}, 0, []string{"# Secret\n\n", "hidden"}, "Prices"},
+		{[]string{"S_1*"}, 0, []string{"# S\\_1\\*\n\n", "|a\\*b\\*c \\[x\\](y) \\_\\_init\\_\\_|\\`\\`\\`|\n"}, "Prices"},
+		{nil, 2, []string{"*1 more rows not shown*"}, "Secret"},
+	}
+	for _, test := range tests {
+		Sheets, MaxRows = test.sheets, test.maxRows
+		markdown, meta, err := Ods2md(ods)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		for _, want := range test.want {
+			if !strings.Contains(markdown, want) {
+				t.Errorf("expected %q in %q", want, markdown)
+			}
+		}
+		if strings.Contains(markdown, test.absent) {
+			t.Errorf("expected no %q in %q", test.absent, markdown)
+		}
+		if meta.Title != "Partner guide" {
+			t.Errorf("expected %q, got %q", "Partner guide", meta.Title)
+		}
+	}
+}
+
+// TestPackageExt_Odf test the type of embedded OpenDocument files
+func TestPackageExt_Odf(t *testing.T) {
+	for mime, want := range odfMimes {
+		b, err := os.ReadFile(buildZip(t, "object.bin", map[string]string{"mimetype": mime}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := packageExt(b); got != want {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+	}
+}
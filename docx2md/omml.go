@@ -0,0 +1,342 @@
+package docx2md
+
+import (
+	"strings"
+)
+
+// latexSymbols maps unicode characters used by Office Math to LaTeX commands.
+var latexSymbols = map[rune]string{
+	'α': `\alpha`, 'β': `\beta`, 'γ': `\gamma`, 'δ': `\delta`, 'ε': `\epsilon`,
+	'ζ': `\zeta`, 'η': `\eta`, 'θ': `\theta`, 'ι': `\iota`, 'κ': `\kappa`,
+	'λ': `\lambda`, 'μ': `\mu`, 'ν': `\nu`, 'ξ': `\xi`, 'π': `\pi`,
+	'ρ': `\rho`, 'σ': `\sigma`, 'τ': `\tau`, 'υ': `\upsilon`, 'φ': `\phi`,
+	'χ': `\chi`, 'ψ': `\psi`, 'ω': `\omega`, 'ϕ': `\varphi`, 'ϵ': `\varepsilon`,
+	'Γ': `\Gamma`, 'Δ': `\Delta`, 'Θ': `\Theta`, 'Λ': `\Lambda`, 'Ξ': `\Xi`,
+	'Π': `\Pi`, 'Σ': `\Sigma`, 'Υ': `\Upsilon`, 'Φ': `\Phi`, 'Ψ': `\Psi`,
+	'Ω': `\Omega`,
+	'∞': `\infty`, '±': `\pm`, '∓': `\mp`, '×': `\times`, '÷': `\div`,
+	'·': `\cdot`, '⋅': `\cdot`, '∘': `\circ`, '≤': `\leq`, '≥': `\geq`,
+	'≠': `\neq`, '≈': `\approx`, '≡': `\equiv`, '∼': `\sim`, '≅': `\cong`,
+	'∝': `\propto`, '→': `\to`, '←': `\leftarrow`, '↔': `\leftrightarrow`,
+	'⇒': `\Rightarrow`, '⇐': `\Leftarrow`, '⇔': `\Leftrightarrow`,
+	'∈': `\in`, '∉': `\notin`, '⊂': `\subset`, '⊃': `\supset`, '⊆': `\subseteq`,
+	'⊇': `\supseteq`, '∪': `\cup`, '∩': `\cap`, '∅': `\emptyset`,
+	'∀': `\forall`, '∃': `\exists`, '¬': `\neg`, '∧': `\wedge`, '∨': `\vee`,
+	'∂': `\partial`, '∇': `\nabla`, '…': `\ldots`, '⋯': `\cdots`, '⋮': `\vdots`,
+	'⋱': `\ddots`, 'ℝ': `\mathbb{R}`, 'ℕ': `\mathbb{N}`, 'ℤ': `\mathbb{Z}`,
+	'ℚ': `\mathbb{Q}`, 'ℂ': `\mathbb{C}`, '°': `^{\circ}`, '′': `'`,
+	'−': `-`, '∗': `*`, '⊥': `\perp`, '∥': `\parallel`, 'ℏ': `\hbar`,
+	'ℓ': `\ell`, '∠': `\angle`,
+}
+
+// naryOperators maps n-ary operator characters to LaTeX commands.
+var naryOperators = map[string]string{
+	"∑": `\sum`, "∏": `\prod`, "∐": `\coprod`, "∫": `\int`, "∬": `\iint`,
+	"∭": `\iiint`, "∮": `\oint`, "⋃": `\bigcup`, "⋂": `\bigcap`,
+	"⋁": `\bigvee`, "⋀": `\bigwedge`, "⨁": `\bigoplus`, "⨂": `\bigotimes`,
+}
+
+// accents maps accent characters to LaTeX commands.
+var accents = map[string]string{
+	"̂": `\hat`, "̃": `\tilde`, "̄": `\bar`, "̅": `\overline`,
+	"̇": `\dot`, "̈": `\ddot`, "̌": `\check`, "̆": `\breve`,
+	"́": `\acute`, "̀": `\grave`, "⃗": `\vec`, "⃖": `\overleftarrow`,
+}
+
+// delimiters maps OMML delimiter characters to their LaTeX form.
+var delimiters = map[string]string{
+	"(": "(", ")": ")", "[": "[", "]": "]", "{": `\{`, "}": `\}`,
+	"|": "|", "‖": `\|`, "⟨": `\langle`, "⟩": `\rangle`, "〈": `\langle`,
+	"〉": `\rangle`, "⌊": `\lfloor`, "⌋": `\rfloor`, "⌈": `\lceil`, "⌉": `\rceil`,
+	"": ".",
+}
+
+// mathFunctions lists function names that have a dedicated LaTeX command.
+var mathFunctions = map[string]bool{
+	"sin": true, "cos": true, "tan": true, "cot": true, "sec": true, "csc": true,
+	"arcsin": true, "arccos": true, "arctan": true, "sinh": true, "cosh": true,
+	"tanh": true, "coth": true, "log": true, "ln": true, "lg": true, "exp": true,
+	"lim": true, "max": true, "min": true, "sup": true, "inf": true, "det": true,
+	"gcd": true, "deg": true, "dim": true, "ker": true, "arg": true,
+}
+
+// child returns the first child node with the given local name.
+func child(node *Node, name string) *Node {
+	for i := range node.Nodes {
+		if node.Nodes[i].XMLName.Local == name {
+			return &node.Nodes[i]
+		}
+	}
+	return nil
+}
+
+// propVal returns the val attribute of the named child of an OMML property node.
+func propVal(props *Node, name string) (string, bool) {
+	if props == nil {
+		return "", false
+	}
+	n := child(props, name)
+	if n == nil {
+		return "", false
+	}
+	val, ok := attr(n.Attrs, "val")
+	if !ok {
+		// flags such as <m:degHide/> are on when present without value
+		return "", true
+	}
+	return val, true
+}
+
+// isOn reports whether an OMML on/off property is set.
+func isOn(props *Node, name string) bool {
+	val, ok := propVal(props, name)
+	if !ok {
+		return false
+	}
+	return val == "" || val == "1" || val == "on" || val == "true"
+}
+
+// omml2latex converts an Office Math node (m:oMath) to a LaTeX string.
+func omml2latex(node *Node) string {
+	var sb strings.Builder
+	for i := range node.Nodes {
+		sb.WriteString(ommlNode(&node.Nodes[i]))
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// ommlArg converts the named argument of an OMML object (e, num, sub...).
+func ommlArg(node *Node, name string) string {
+	n := child(node, name)
+	if n == nil {
+		return ""
+	}
+	return omml2latex(n)
+}
+
+// ommlNode converts a single OMML element to LaTeX.
+func ommlNode(node *Node) string {
+	switch node.XMLName.Local {
+	case "r":
+		return ommlRun(node)
+	case "f":
+		num, den := ommlArg(node, "num"), ommlArg(node, "den")
+		typ, _ := propVal(child(node, "fPr"), "type")
+		switch typ {
+		case "lin", "skw":
+			return "{" + num + "}/{" + den + "}"
+		case "noBar":
+			return `\genfrac{}{}{0pt}{}{` + num + "}{" + den + "}"
+		}
+		return `\frac{` + num + "}{" + den + "}"
+	case "rad":
+		e := ommlArg(node, "e")
+		deg := ommlArg(node, "deg")
+		if isOn(child(node, "radPr"), "degHide") || deg == "" {
+			return `\sqrt{` + e + "}"
+		}
+		return `\sqrt[` + deg + "]{" + e + "}"
+	case "sSup":
+		return "{" + ommlArg(node, "e") + "}^{" + ommlArg(node, "sup") + "}"
+	case "sSub":
+		return "{" + ommlArg(node, "e") + "}_{" + ommlArg(node, "sub") + "}"
+	case "sSubSup":
+		return "{" + ommlArg(node, "e") + "}_{" + ommlArg(node, "sub") + "}^{" + ommlArg(node, "sup") + "}"
+	case "sPre":
+		return "{}_{" + ommlArg(node, "sub") + "}^{" + ommlArg(node, "sup") + "}{" + ommlArg(node, "e") + "}"
+	case "nary":
+		return ommlNary(node)
+	case "d":
+		return ommlDelimiter(node)
+	case "m":
+		return ommlMatrix(node, "matrix")
+	case "eqArr":
+		var rows []string
+		for i := range node.Nodes {
+			if node.Nodes[i].XMLName.Local == "e" {
+				rows = append(rows, omml2latex(&node.Nodes[i]))
+			}
+		}
+		return `\begin{aligned}` + strings.Join(rows, ` \\ `) + `\end{aligned}`
+	case "func":
+		name := ommlArg(node, "fName")
+		return name + "{" + ommlArg(node, "e") + "}"
+	case "acc":
+		chr, ok := propVal(child(node, "accPr"), "chr")
+		if !ok {
+			chr = "̂"
+		}
+		cmd, found := accents[chr]
+		if !found {
+			cmd = `\hat`
+		}
+		return cmd + "{" + ommlArg(node, "e") + "}"
+	case "bar":
+		if pos, _ := propVal(child(node, "barPr"), "pos"); pos == "top" {
+			return `\overline{` + ommlArg(node, "e") + "}"
+		}
+		return `\underline{` + ommlArg(node, "e") + "}"
+	case "groupChr":
+		pos, _ := propVal(child(node, "groupChrPr"), "pos")
+		if pos == "top" {
+			return `\overbrace{` + ommlArg(node, "e") + "}"
+		}
+		return `\underbrace{` + ommlArg(node, "e") + "}"
+	case "limLow":
+		e, lim := ommlArg(node, "e"), ommlArg(node, "lim")
+		if strings.HasPrefix(e, `\`) && mathFunctions[strings.TrimPrefix(e, `\`)] {
+			return e + "_{" + lim + "}"
+		}
+		return `\underset{` + lim + "}{" + e + "}"
+	case "limUpp":
+		e, lim := ommlArg(node, "e"), ommlArg(node, "lim")
+		if strings.HasPrefix(e, `\`) && mathFunctions[strings.TrimPrefix(e, `\`)] {
+			return e + "^{" + lim + "}"
+		}
+		return `\overset{` + lim + "}{" + e + "}"
+	case "borderBox":
+		return `\boxed{` + ommlArg(node, "e") + "}"
+	case "box", "phant", "e", "num", "den", "sub", "sup", "deg", "lim", "fName", "oMath":
+		return omml2latex(node)
+	case "rPr", "ctrlPr", "fPr", "radPr", "sSupPr", "sSubPr", "sSubSupPr", "sPrePr",
+		"naryPr", "dPr", "mPr", "eqArrPr", "funcPr", "accPr", "barPr", "groupChrPr",
+		"limLowPr", "limUppPr", "borderBoxPr", "boxPr", "phantPr", "argPr", "oMathParaPr":
+		return ""
+	}
+	var sb strings.Builder
+	for i := range node.Nodes {
+		sb.WriteString(ommlNode(&node.Nodes[i]))
+	}
+	return sb.String()
+}
+
+// ommlRun converts an OMML run (m:r) to LaTeX, mapping symbols and function names.
+func ommlRun(node *Node) string {
+	var text strings.Builder
+	for i := range node.Nodes {
+		if node.Nodes[i].XMLName.Local == "t" {
+			text.WriteString(innerText(&node.Nodes[i]))
+		}
+	}
+	s := text.String()
+	if rPr := child(node, "rPr"); rPr != nil && isOn(rPr, "nor") {
+		return `\text{` + latexEscape(s) + "}"
+	}
+	if mathFunctions[s] {
+		return `\` + s + " "
+	}
+	var sb strings.Builder
+	for _, r := range s {
+		if cmd, ok := latexSymbols[r]; ok {
+			sb.WriteString(cmd)
+			sb.WriteString(" ")
+			continue
+		}
+		if cmd, ok := naryOperators[string(r)]; ok {
+			sb.WriteString(cmd)
+			sb.WriteString(" ")
+			continue
+		}
+		sb.WriteString(latexEscape(string(r)))
+	}
+	return sb.String()
+}
+
+// latexEscape escapes the LaTeX special characters of plain text.
+func latexEscape(s string) string {
+	return strings.NewReplacer(
+		`\`, `\backslash `,
+		"{", `\{`, "}", `\}`,
+		"#", `\#`, "%", `\%`, "&", `\&`, "_", `\_`, "$", `\$`,
+	).Replace(s)
+}
+
+// ommlNary converts an n-ary operator (sum, integral...) to LaTeX.
+func ommlNary(node *Node) string {
+	props := child(node, "naryPr")
+	chr, ok := propVal(props, "chr")
+	if !ok || chr == "" {
+		chr = "∫"
+	}
+	op, found := naryOperators[chr]
+	if !found {
+		op = latexEscape(chr)
+	}
+	var sb strings.Builder
+	sb.WriteString(op)
+	if sub := ommlArg(node, "sub"); sub != "" && !isOn(props, "subHide") {
+		sb.WriteString("_{" + sub + "}")
+	}
+	if sup := ommlArg(node, "sup"); sup != "" && !isOn(props, "supHide") {
+		sb.WriteString("^{" + sup + "}")
+	}
+	sb.WriteString("{" + ommlArg(node, "e") + "}")
+	return sb.String()
+}
+
+// ommlDelimiter converts a delimiter object (parentheses, brackets...) to LaTeX.
+func ommlDelimiter(node *Node) string {
+	props := child(node, "dPr")
+	beg, ok := propVal(props, "begChr")
+	if !ok {
+		beg = "("
+	}
+	end, ok := propVal(props, "endChr")
+	if !ok {
+		end = ")"
+	}
+	sep, ok := propVal(props, "sepChr")
+	if !ok {
+		sep = "|"
+	}
+	var args []string
+	for i := range node.Nodes {
+		if node.Nodes[i].XMLName.Local != "e" {
+			continue
+		}
+		e := &node.Nodes[i]
+		// a lone matrix between parentheses or brackets becomes pmatrix / bmatrix
+		if len(args) == 0 && len(e.Nodes) == 1 && e.Nodes[0].XMLName.Local == "m" {
+			switch beg + end {
+			case "()":
+				return ommlMatrix(&e.Nodes[0], "pmatrix")
+			case "[]":
+				return ommlMatrix(&e.Nodes[0], "bmatrix")
+			case "||":
+				return ommlMatrix(&e.Nodes[0], "vmatrix")
+			}
+		}
+		args = append(args, omml2latex(e))
+	}
+	left, ok := delimiters[beg]
+	if !ok {
+		left = latexEscape(beg)
+	}
+	right, ok := delimiters[end]
+	if !ok {
+		right = latexEscape(end)
+	}
+	middle, ok := delimiters[sep]
+	if !ok {
+		middle = latexEscape(sep)
+	}
+	return `\left` + left + " " + strings.Join(args, ` \middle`+middle+" ") + ` \right` + right
+}
+
+// ommlMatrix converts a matrix object to a LaTeX matrix environment.
+func ommlMatrix(node *Node, env string) string {
+	var rows []string
+	for i := range node.Nodes {
+		if node.Nodes[i].XMLName.Local != "mr" {
+			continue
+		}
+		var cells []string
+		for j := range node.Nodes[i].Nodes {
+			if node.Nodes[i].Nodes[j].XMLName.Local == "e" {
+				cells = append(cells, omml2latex(&node.Nodes[i].Nodes[j]))
+			}
+		}
+		rows = append(rows, strings.Join(cells, " & "))
+	}
+	return `\begin{` + env + "}" + strings.Join(rows, ` \\ `) + `\end{` + env + "}"
+}
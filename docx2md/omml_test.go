@@ -0,0 +1,110 @@
+package docx2md
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+const mathNS = `xmlns:m="http://schemas.openxmlformats.org/officeDocument/2006/math"`
+
+// parseMath unmarshal an OMML snippet into a Node
+func parseMath(t *testing.T, s string) *Node {
+	var node Node
+	if err := xml.Unmarshal([]byte(`<m:oMath `+mathNS+`>`+s+`</m:oMath>`), &node); err != nil {
+		t.Fatalf("can't parse %s: %v", s, err)
+	}
+	return &node
+}
+
+// TestOmml2latex test conversion of the main OMML constructs
+func TestOmml2latex(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "fraction",
+			input: `<m:f><m:num><m:r><m:t>a</m:t></m:r></m:num><m:den><m:r><m:t>b</m:t></m:r></m:den></m:f>`,
+			want:  `\frac{a}{b}`,
+		},
+		{
+			name:  "linear fraction",
+			input: `<m:f><m:fPr><m:type m:val="lin"/></m:fPr><m:num><m:r><m:t>a</m:t></m:r></m:num><m:den><m:r><m:t>b</m:t></m:r></m:den></m:f>`,
+			want:  `{a}/{b}`,
+		},
+		{
+			name:  "square root",
+			input: `<m:rad><m:radPr><m:degHide m:val="1"/></m:radPr><m:deg/><m:e><m:r><m:t>x</m:t></m:r></m:e></m:rad>`,
+			want:  `\sqrt{x}`,
+		},
+		{
+			name:  "cubic root",
+			input: `<m:rad><m:deg><m:r><m:t>3</m:t></m:r></m:deg><m:e><m:r><m:t>x</m:t></m:r></m:e></m:rad>`,
+			want:  `\sqrt[3]{x}`,
+		},
+		{
+			name:  "superscript",
+			input: `<m:sSup><m:e><m:r><m:t>x</m:t></m:r></m:e><m:sup><m:r><m:t>2</m:t></m:r></m:sup></m:sSup>`,
+			want:  `{x}^{2}`,
+		},
+		{
+			name:  "sub and superscript",
+			input: `<m:sSubSup><m:e><m:r><m:t>x</m:t></m:r></m:e><m:sub><m:r><m:t>i</m:t></m:r></m:sub><m:sup><m:r><m:t>2</m:t></m:r></m:sup></m:sSubSup>`,
+			want:  `{x}_{i}^{2}`,
+		},
+		{
+			name:  "sum",
+			input: `<m:nary><m:naryPr><m:chr m:val="∑"/></m:naryPr><m:sub><m:r><m:t>i=1</m:t></m:r></m:sub><m:sup><m:r><m:t>n</m:t></m:r></m:sup><m:e><m:r><m:t>i</m:t></m:r></m:e></m:nary>`,
+			want:  `\sum_{i=1}^{n}{i}`,
+		},
+		{
+			name:  "integral by default",
+			input: `<m:nary><m:naryPr><m:subHide m:val="1"/><m:supHide m:val="1"/></m:naryPr><m:sub/><m:sup/><m:e><m:r><m:t>f</m:t></m:r></m:e></m:nary>`,
+			want:  `\int{f}`,
+		},
+		{
+			name:  "matrix in parentheses",
+			input: `<m:d><m:e><m:m><m:mr><m:e><m:r><m:t>1</m:t></m:r></m:e><m:e><m:r><m:t>0</m:t></m:r></m:e></m:mr><m:mr><m:e><m:r><m:t>0</m:t></m:r></m:e><m:e><m:r><m:t>1</m:t></m:r></m:e></m:mr></m:m></m:e></m:d>`,
+			want:  `\begin{pmatrix}1 & 0 \\ 0 & 1\end{pmatrix}`,
+		},
+		{
+			name:  "brackets",
+			input: `<m:d><m:dPr><m:begChr m:val="["/><m:endChr m:val="]"/></m:dPr><m:e><m:r><m:t>a+b</m:t></m:r></m:e></m:d>`,
+			want:  `\left[ a+b \right]`,
+		},
+		{
+			name:  "function and greek letters",
+			input: `<m:func><m:fName><m:r><m:t>sin</m:t></m:r></m:fName><m:e><m:r><m:t>α</m:t></m:r></m:e></m:func>`,
+			want:  `\sin{\alpha}`,
+		},
+		{
+			name:  "function name run",
+			input: `<m:r><m:t>sin</m:t></m:r><m:r><m:t>x</m:t></m:r>`,
+			want:  `\sin x`,
+		},
+		{
+			name:  "escaped text",
+			input: `<m:r><m:t>a&lt;b</m:t></m:r>`,
+			want:  `a<b`,
+		},
+	}
+	for _, test := range tests {
+		got := omml2latex(parseMath(t, test.input))
+		if got != test.want {
+			t.Errorf("%s: expected %s, got %s", test.name, test.want, got)
+		}
+	}
+}
+
+// TestDocxToMd_Math test equations are written inline and in display mode
+func TestDocxToMd_Math(t *testing.T) {
+	body := `<w:p><w:r><w:t xml:space="preserve">Value of </w:t></w:r>` +
+		`<m:oMath><m:r><m:t>sin</m:t></m:r><m:r><m:t>x</m:t></m:r></m:oMath></w:p>` +
+		`<w:p><m:oMathPara><m:oMath><m:f><m:num><m:r><m:t>a</m:t></m:r></m:num>` +
+		`<m:den><m:r><m:t>b</m:t></m:r></m:den></m:f></m:oMath></m:oMathPara></w:p>`
+	want := "Value of $\\sin x$\n\n$$\n\\frac{a}{b}\n$$\n\n"
+	if got := convertDocx(t, body, nil); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,473 @@
+package docx2md
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sacquatella/tomd/tools"
+)
+
+// SkipHidden ignore the hidden slides of a presentation
+var SkipHidden bool
+
+var slideName = regexp.MustCompile(`^ppt/slides/slide(\d+)\.xml$`)
+
+// readRels reads the relationships of a part, a part without relationships
+// has an empty set.
+func readRels(files []*zip.File, part string) (Relationships, error) {
+	var rels Relationships
+	err := unmarshalPart(files, path.Join(path.Dir(part), "_rels", path.Base(part)+".rels"), &rels)
+	return rels, err
+}
+
+// slideParts returns the slides of a presentation in the order of the slide
+// list of ppt/presentation.xml. Without this list, slides are sorted by number.
+func slideParts(files []*zip.File) ([]string, error) {
+	var parts []string
+	var pres Presentation
+	if err := unmarshalPart(files, "ppt/presentation.xml", &pres); err != nil {
+		return nil, err
+	}
+	if len(pres.SldIDLst.SldID) > 0 {
+		rels, err := readRels(files, "ppt/presentation.xml")
+		if err != nil {
+			return nil, err
+		}
+		presentation := &file{part: "ppt"}
+		for _, sld := range pres.SldIDLst.SldID {
+			for _, rel := range rels.Relationship {
+				if rel.ID != sld.RID {
+					continue
+				}
+				if name, ok := presentation.partPath(rel.Target); ok {
+					parts = append(parts, name)
+				}
+				break
+			}
+		}
+		if len(parts) > 0 {
+			return parts, nil
+		}
+	}
+
+	numbers := make(map[string]int)
+	for _, f := range files {
+		if m := slideName.FindStringSubmatch(f.Name); m != nil {
+			numbers[f.Name], _ = strconv.Atoi(m[1])
+			parts = append(parts, f.Name)
+		}
+	}
+	sort.Slice(parts, func(i, j int) bool { return numbers[parts[i]] < numbers[parts[j]] })
+	return parts, nil
+}
+
+// isHidden reports whether a slide is hidden in the slide show.
+func isHidden(slide *Node) bool {
+	show, ok := attr(slide.Attrs, "show")
+	return ok && (show == "0" || show == "false")
+}
+
+// Notes is the rendering of speaker notes: none, section or quote
+var Notes = "section"
+
+// SlideNumbers add the slide number to the slide headings
+var SlideNumbers = true
+
+// placeholderType returns the placeholder type of a shape, content
+// placeholders have no type.
+func placeholderType(sp *Node) (string, bool) {
+	nvSpPr := child(sp, "nvSpPr")
+	if nvSpPr == nil {
+		return "", false
+	}
+	nvPr := child(nvSpPr, "nvPr")
+	if nvPr == nil {
+		return "", false
+	}
+	ph := child(nvPr, "ph")
+	if ph == nil {
+		return "", false
+	}
+	typ, _ := attr(ph.Attrs, "type")
+	return typ, true
+}
+
+// slideTitle returns the text of the title placeholder of a slide.
+func slideTitle(slide *Node) string {
+	var title string
+	var visit func(n *Node) bool
+	visit = func(n *Node) bool {
+		if n.XMLName.Local == "sp" {
+			if typ, _ := placeholderType(n); typ == "title" || typ == "ctrTitle" {
+				title = strings.Join(strings.Fields(plainText(n)), " ")
+				return true
+			}
+			return false
+		}
+		for i := range n.Nodes {
+			if visit(&n.Nodes[i]) {
+				return true
+			}
+		}
+		return false
+	}
+	visit(slide)
+	return title
+}
+
+// shape renders a shape of a slide. Titles are rendered by the slide heading,
+// dates, footers and slide numbers are ignored, body placeholders are lists.
+func (zf *file) shape(node *Node, w io.Writer) error {
+	typ, placeholder := placeholderType(node)
+	switch typ {
+	case "title", "ctrTitle", "dt", "ftr", "sldNum", "hdr", "sldImg":
+		return nil
+	}
+	bullets := zf.bullets
+	zf.bullets = placeholder && (typ == "" || typ == "body" || typ == "obj")
+	err := zf.walkBlocks(node.Nodes, w)
+	zf.bullets = bullets
+	return err
+}
+
+// bulletMarker returns the list marker of a slide paragraph, indented by its
+// level. Paragraphs of body placeholders are bullets unless buNone is set,
+// other paragraphs only when they have a bullet.
+func (zf *file) bulletMarker(p *Node) string {
+	pPr := child(p, "pPr")
+	bullet := zf.bullets
+	numbered := false
+	if pPr != nil {
+		switch {
+		case child(pPr, "buNone") != nil:
+			return ""
+		case child(pPr, "buAutoNum") != nil:
+			bullet, numbered = true, true
+		case child(pPr, "buChar") != nil, child(pPr, "buBlip") != nil:
+			bullet = true
+		}
+	}
+	if !bullet {
+		return ""
+	}
+	level := 0
+	if pPr != nil {
+		if lvl, ok := attr(pPr.Attrs, "lvl"); ok {
+			level, _ = strconv.Atoi(lvl)
+		}
+	}
+	if numbered {
+		return strings.Repeat("   ", level) + "1. "
+	}
+	return strings.Repeat("  ", level) + "- "
+}
+
+// notes returns the speaker notes of a slide, from the body placeholder of its notes slide.
+func (zf *file) notes() (string, error) {
+	for _, rel := range zf.rels.Relationship {
+		if !strings.HasSuffix(rel.Type, "/notesSlide") {
+			continue
+		}
+		name, ok := zf.partPath(rel.Target)
+		if !ok {
+			return "", nil
+		}
+		f := findFile(zf.r.File, name)
+		if f == nil {
+			return "", nil
+		}
+		node, err := readFile(f)
+		if err != nil {
+			return "", err
+		}
+		rels, err := readRels(zf.r.File, name)
+		if err != nil {
+			return "", err
+		}
+		// links of the notes are relative to the notes slide
+		notes := *zf
+		notes.rels, notes.part, notes.bullets = rels, path.Dir(name), false
+		var buf bytes.Buffer
+		var visit func(n *Node) error
+		visit = func(n *Node) error {
+			if n.XMLName.Local == "sp" {
+				if typ, _ := placeholderType(n); typ == "body" {
+					if txBody := child(n, "txBody"); txBody != nil {
+						return notes.walkBlocks(txBody.Nodes, &buf)
+					}
+				}
+				return nil
+			}
+			for i := range n.Nodes {
+				if err := visit(&n.Nodes[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if err := visit(node); err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(buf.String()), nil
+	}
+	return "", nil
+}
+
+// slide writes a slide: a heading from its title and number, its shapes and its notes.
+func (zf *file) slide(node *Node, number int, w io.Writer) error {
+	heading := tools.Escape(slideTitle(node), "\\*_~[]`")
+	if SlideNumbers {
+		if heading == "" {
+			heading = fmt.Sprintf("Slide %d", number)
+		} else {
+			heading = fmt.Sprintf("Slide %d: %s", number, heading)
+		}
+	}
+	if heading != "" {
+		zf.level = 1
+		fmt.Fprintf(w, "# %s\n\n", heading)
+	}
+	if err := zf.walk(node, w); err != nil {
+		return err
+	}
+	if Notes != "section" && Notes != "quote" {
+		return nil
+	}
+	notes, err := zf.notes()
+	if err != nil || notes == "" {
+		return err
+	}
+	if Notes == "quote" {
+		writeBlockquote("**Notes**\n\n"+notes, w)
+	} else {
+		fmt.Fprintf(w, "\n## Notes\n\n%s\n", notes)
+	}
+	return nil
+}
+
+// rowTolerance is the vertical distance (EMU) under which shapes are on the same row
+const rowTolerance = 228600
+
+// positionedShape is a shape of a slide with its position on the slide.
+type positionedShape struct {
+	node  *Node
+	x, y  int64
+	found bool
+	band  int
+}
+
+// transform converts coordinates of a group to coordinates of the slide.
+type transform func(x, y int64) (int64, int64)
+
+// xfrmValues returns the x and y (or cx and cy) attributes of a child of a xfrm.
+func xfrmValues(xfrm *Node, name, xName, yName string) (int64, int64, bool) {
+	n := child(xfrm, name)
+	if n == nil {
+		return 0, 0, false
+	}
+	xs, _ := attr(n.Attrs, xName)
+	ys, _ := attr(n.Attrs, yName)
+	x, errX := strconv.ParseInt(xs, 10, 64)
+	y, errY := strconv.ParseInt(ys, 10, 64)
+	return x, y, errX == nil && errY == nil
+}
+
+// shapeXfrm returns the transform (a:xfrm) of a shape, picture, graphic frame or group.
+func shapeXfrm(node *Node) *Node {
+	if xfrm := child(node, "xfrm"); xfrm != nil {
+		return xfrm
+	}
+	for _, pr := range []string{"spPr", "grpSpPr"} {
+		if spPr := child(node, pr); spPr != nil {
+			return child(spPr, "xfrm")
+		}
+	}
+	return nil
+}
+
+// placeholderKey returns the type and index of the placeholder of a shape.
+func placeholderKey(node *Node) (string, string, bool) {
+	for _, nvPr := range []string{"nvSpPr", "nvPicPr", "nvGraphicFramePr"} {
+		if nv := child(node, nvPr); nv != nil {
+			if pr := child(nv, "nvPr"); pr != nil {
+				if ph := child(pr, "ph"); ph != nil {
+					typ, _ := attr(ph.Attrs, "type")
+					idx, _ := attr(ph.Attrs, "idx")
+					return typ, idx, true
+				}
+			}
+		}
+	}
+	return "", "", false
+}
+
+// relatedPart returns the part and the relationships of the first relationship
+// of a part with the given type (slideLayout, slideMaster...).
+func relatedPart(files []*zip.File, part string, rels Relationships, typ string) (*Node, string, Relationships, bool) {
+	for _, rel := range rels.Relationship {
+		if !strings.HasSuffix(rel.Type, "/"+typ) {
+			continue
+		}
+		name, ok := (&file{part: path.Dir(part)}).partPath(rel.Target)
+		if !ok {
+			return nil, "", rels, false
+		}
+		f := findFile(files, name)
+		if f == nil {
+			return nil, "", rels, false
+		}
+		node, err := readFile(f)
+		if err != nil {
+			return nil, "", rels, false
+		}
+		partRels, _ := readRels(files, name)
+		return node, name, partRels, true
+	}
+	return nil, "", rels, false
+}
+
+// slideLayouts returns the slide layout and the slide master of the slide,
+// nil when missing.
+func (zf *file) slideLayouts() []*Node {
+	if zf.layouts == nil {
+		layout, layoutPart, layoutRels, ok := relatedPart(zf.r.File, zf.slidePart, zf.rels, "slideLayout")
+		var master *Node
+		if ok {
+			master, _, _, _ = relatedPart(zf.r.File, layoutPart, layoutRels, "slideMaster")
+		}
+		zf.layouts = []*Node{layout, master}
+	}
+	return zf.layouts
+}
+
+// inheritedOffset returns the position of a placeholder without transform,
+// inherited from the slide layout or the slide master.
+func (zf *file) inheritedOffset(node *Node) (int64, int64, bool) {
+	typ, idx, ok := placeholderKey(node)
+	if !ok || zf.slidePart == "" {
+		return 0, 0, false
+	}
+	// layouts match the placeholder index, masters only know the placeholder types
+	for i, part := range zf.slideLayouts() {
+		if part == nil {
+			continue
+		}
+		var found *Node
+		var visit func(n *Node)
+		visit = func(n *Node) {
+			if found != nil {
+				return
+			}
+			if t, x, ok := placeholderKey(n); ok && shapeXfrm(n) != nil {
+				switch {
+				case i == 0 && idx != "" && x == idx,
+					i == 0 && idx == "" && t == typ,
+					i == 1 && (t == typ || (t == "body" && (typ == "" || typ == "obj"))):
+					found = n
+					return
+				}
+			}
+			for j := range n.Nodes {
+				visit(&n.Nodes[j])
+			}
+		}
+		visit(part)
+		if found != nil {
+			return xfrmValues(shapeXfrm(found), "off", "x", "y")
+		}
+	}
+	return 0, 0, false
+}
+
+// flattenShapes returns the shapes of a shape tree, with the shapes of groups,
+// and their position on the slide.
+func (zf *file) flattenShapes(node *Node, t transform) []positionedShape {
+	var shapes []positionedShape
+	for i := range node.Nodes {
+		n := &node.Nodes[i]
+		switch n.XMLName.Local {
+		case "nvGrpSpPr", "grpSpPr", "extLst":
+		case "AlternateContent":
+			if branch := alternateBranch(n); branch != nil {
+				shapes = append(shapes, zf.flattenShapes(branch, t)...)
+			}
+		case "grpSp":
+			// children of a group are placed in the child coordinates of the group
+			group := t
+			if xfrm := shapeXfrm(n); xfrm != nil {
+				offX, offY, _ := xfrmValues(xfrm, "off", "x", "y")
+				extX, extY, _ := xfrmValues(xfrm, "ext", "cx", "cy")
+				chOffX, chOffY, _ := xfrmValues(xfrm, "chOff", "x", "y")
+				chExtX, chExtY, ok := xfrmValues(xfrm, "chExt", "cx", "cy")
+				if !ok || chExtX == 0 || chExtY == 0 {
+					chExtX, chExtY, extX, extY = 1, 1, 1, 1
+				}
+				group = func(x, y int64) (int64, int64) {
+					return t(offX+(x-chOffX)*extX/chExtX, offY+(y-chOffY)*extY/chExtY)
+				}
+			}
+			shapes = append(shapes, zf.flattenShapes(n, group)...)
+		default:
+			s := positionedShape{node: n}
+			if xfrm := shapeXfrm(n); xfrm != nil {
+				s.x, s.y, s.found = xfrmValues(xfrm, "off", "x", "y")
+				s.x, s.y = t(s.x, s.y)
+			} else {
+				s.x, s.y, s.found = zf.inheritedOffset(n)
+			}
+			shapes = append(shapes, s)
+		}
+	}
+	return shapes
+}
+
+// shapeTree renders the shapes of a slide in reading order.
+func (zf *file) shapeTree(node *Node, w io.Writer) error {
+	shapes := zf.flattenShapes(node, func(x, y int64) (int64, int64) { return x, y })
+	readingOrder(shapes)
+	for i := range shapes {
+		if err := zf.walk(shapes[i].node, w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readingOrder sorts the shapes of a slide top to bottom by rows, then left
+// to right.
+func readingOrder(shapes []positionedShape) {
+	// shapes without position follow the previous shape
+	for i := range shapes {
+		if !shapes[i].found && i > 0 {
+			shapes[i].x, shapes[i].y = shapes[i-1].x, shapes[i-1].y
+		}
+	}
+	sorted := make([]*positionedShape, len(shapes))
+	for i := range shapes {
+		sorted[i] = &shapes[i]
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].y < sorted[j].y })
+	band, top := 0, int64(0)
+	for i, s := range sorted {
+		if i > 0 && s.y-top > rowTolerance {
+			band++
+			top = s.y
+		} else if i == 0 {
+			top = s.y
+		}
+		s.band = band
+	}
+	sort.SliceStable(shapes, func(i, j int) bool {
+		if shapes[i].band != shapes[j].band {
+			return shapes[i].band < shapes[j].band
+		}
+		return shapes[i].x < shapes[j].x
+	})
+}
@@ -0,0 +1,311 @@
+package docx2md
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const pptxNS = `xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" ` +
+	`xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" ` +
+	`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`
+
+// buildPptx write a minimal pptx file with the given package parts
+func buildPptx(t *testing.T, parts map[string]string) string {
+	return buildZip(t, "test.pptx", parts)
+}
+
+// buildZip write an office package with the given parts
+func buildZip(t *testing.T, name string, parts map[string]string) string {
+	t.Helper()
+	zipfile := filepath.Join(t.TempDir(), name)
+	out, err := os.Create(zipfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	zw := zip.NewWriter(out)
+	for name, content := range parts {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return zipfile
+}
+
+// slideXML build a slide with a text shape, the text is a link when linked
+func slideXML(text string, linked bool, attrs string) string {
+	rPr := ""
+	if linked {
+		rPr = `<a:rPr><a:hlinkClick r:id="rId2"/></a:rPr>`
+	}
+	return `<p:sld ` + pptxNS + attrs + `><p:cSld><p:spTree><p:sp><p:txBody><a:p><a:r>` + rPr +
+		`<a:t>` + text + `</a:t></a:r></a:p></p:txBody></p:sp></p:spTree></p:cSld></p:sld>`
+}
+
+// TestPptxToMd_Slides test slides follow the presentation order with their own relationships
+func TestPptxToMd_Slides(t *testing.T) {
+	const count = 12
+	parts := map[string]string{}
+	var sldIds, presRels string
+	for i := 1; i <= count; i++ {
+		attrs := ""
+		if i == 5 {
+			attrs = ` show="0"`
+		}
+		parts[fmt.Sprintf("ppt/slides/slide%d.xml", i)] = slideXML(fmt.Sprintf("slide %d", i), true, attrs)
+		parts[fmt.Sprintf("ppt/slides/_rels/slide%d.xml.rels", i)] = `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="slideLayout" Target="../slideLayouts/slideLayout1.xml"/>` +
+			fmt.Sprintf(`<Relationship Id="rId2" Type="hyperlink" Target="https://example.com/%d" TargetMode="External"/>`, i) +
+			`</Relationships>`
+		presRels += fmt.Sprintf(`<Relationship Id="rId%d" Type="slide" Target="slides/slide%d.xml"/>`, 100+i, i)
+	}
+	// the presentation shows the slides in reverse order
+	for i := count; i >= 1; i-- {
+		sldIds += fmt.Sprintf(`<p:sldId id="%d" r:id="rId%d"/>`, 255+i, 100+i)
+	}
+	parts["ppt/presentation.xml"] = `<p:presentation ` + pptxNS + `><p:sldIdLst>` + sldIds + `</p:sldIdLst></p:presentation>`
+	parts["ppt/_rels/presentation.xml.rels"] = `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		presRels + `</Relationships>`
+	pptxfile := buildPptx(t, parts)
+
+	var want []string
+	for i := count; i >= 1; i-- {
+		want = append(want, fmt.Sprintf("[slide %d](https://example.com/%d)", i, i))
+	}
+	result, _, err := Pptx2md(pptxfile, false, "")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got := linkLines(result); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	SkipHidden = true
+	defer func() { SkipHidden = false }()
+	result, _, err = Pptx2md(pptxfile, false, "")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got := linkLines(result); len(got) != count-1 || strings.Contains(result, "slide 5") {
+		t.Errorf("expected hidden slide 5 to be skipped, got %v", got)
+	}
+}
+
+// TestPptxToMd_SlidesWithoutPresentation test slides are sorted by number without presentation.xml
+func TestPptxToMd_SlidesWithoutPresentation(t *testing.T) {
+	parts := map[string]string{}
+	for _, i := range []int{10, 2, 1} {
+		parts[fmt.Sprintf("ppt/slides/slide%d.xml", i)] = slideXML(fmt.Sprintf("slide %d", i), false, "")
+	}
+	result, _, err := Pptx2md(buildPptx(t, parts), false, "")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if i1, i2, i10 := strings.Index(result, "slide 1\n"), strings.Index(result, "slide 2"), strings.Index(result, "slide 10"); !(i1 < i2 && i2 < i10) {
+		t.Errorf("expected slides sorted by number, got %q", result)
+	}
+}
+
+// linkLines returns the lines of a markdown containing a link
+func linkLines(markdown string) []string {
+	var links []string
+	for _, line := range strings.Split(markdown, "\n") {
+		if strings.HasPrefix(line, "[") {
+			links = append(links, strings.TrimSpace(line))
+		}
+	}
+	return links
+}
+
+// TestPptxToMd_TitlesAndNotes test slide headings, body bullets by level and speaker notes
+func TestPptxToMd_TitlesAndNotes(t *testing.T) {
+	shape := func(ph, paragraphs string) string {
+		nvPr := `<p:nvPr/>`
+		if ph != "" {
+			nvPr = `<p:nvPr>` + ph + `</p:nvPr>`
+		}
+		return `<p:sp><p:nvSpPr><p:cNvPr id="1" name="s"/><p:cNvSpPr/>` + nvPr + `</p:nvSpPr><p:txBody>` + paragraphs + `</p:txBody></p:sp>`
+	}
+	para := func(pPr, text string) string {
+		return `<a:p>` + pPr + `<a:r><a:t>` + text + `</a:t></a:r></a:p>`
+	}
+	slide := `<p:sld ` + pptxNS + `><p:cSld><p:spTree>` +
+		shape(`<p:ph type="title"/>`, para("", "Roadmap")) +
+		shape(`<p:ph idx="1"/>`, para("", "Build")+para(`<a:pPr lvl="1"/>`, "Code")+para(`<a:pPr><a:buNone/></a:pPr>`, "Plain")) +
+		shape("", para("", "Free text")+para(`<a:pPr><a:buChar char="•"/></a:pPr>`, "Point")) +
+		shape(`<p:ph type="sldNum" idx="12"/>`, para("", "1")) +
+		`</p:spTree></p:cSld></p:sld>`
+	notes := `<p:notes ` + pptxNS + `><p:cSld><p:spTree>` +
+		shape(`<p:ph type="sldImg"/>`, "") + shape(`<p:ph type="body" idx="1"/>`, para("", "Say hello")) +
+		`</p:spTree></p:cSld></p:notes>`
+	parts := map[string]string{
+		"ppt/slides/slide1.xml": slide,
+		"ppt/slides/_rels/slide1.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/notesSlide" ` +
+			`Target="../notesSlides/notesSlide1.xml"/></Relationships>`,
+		"ppt/notesSlides/notesSlide1.xml": notes,
+	}
+	pptxfile := buildPptx(t, parts)
+	body := "- Build\n  - Code\nPlain\nFree text\n- Point\n"
+
+	tests := []struct {
+		notes   string
+		numbers bool
+		want    string
+	}{
+		{notes: "section", numbers: true, want: "# Slide 1: Roadmap\n\n" + body + "\n## Notes\n\nSay hello\n\n\n---\n"},
+		{notes: "quote", numbers: false, want: "# Roadmap\n\n" + body + "\n> **Notes**\n>\n> Say hello\n\n\n\n---\n"},
+		{notes: "none", numbers: true, want: "# Slide 1: Roadmap\n\n" + body + "\n\n---\n"},
+	}
+	defer func() { Notes, SlideNumbers = "section", true }()
+	for _, tt := range tests {
+		t.Run(tt.notes, func(t *testing.T) {
+			Notes, SlideNumbers = tt.notes, tt.numbers
+			result, _, err := Pptx2md(pptxfile, false, "")
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if result != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, result)
+			}
+		})
+	}
+}
+
+// textShape build a text shape at the given position, without position if x < 0
+func textShape(text string, x, y int) string {
+	spPr := `<p:spPr/>`
+	if x >= 0 {
+		spPr = fmt.Sprintf(`<p:spPr><a:xfrm><a:off x="%d" y="%d"/><a:ext cx="100" cy="100"/></a:xfrm></p:spPr>`, x, y)
+	}
+	return `<p:sp><p:nvSpPr><p:cNvPr id="1" name="s"/><p:cNvSpPr/><p:nvPr/></p:nvSpPr>` + spPr +
+		`<p:txBody><a:p><a:r><a:t>` + text + `</a:t></a:r></a:p></p:txBody></p:sp>`
+}
+
+// TestPptxToMd_ReadingOrder test shapes are sorted by rows then columns, groups included
+func TestPptxToMd_ReadingOrder(t *testing.T) {
+	// the group is scaled by 2 and moved to the bottom of the slide
+	group := `<p:grpSp><p:nvGrpSpPr><p:cNvPr id="9" name="g"/><p:cNvGrpSpPr/><p:nvPr/></p:nvGrpSpPr>` +
+		`<p:grpSpPr><a:xfrm><a:off x="0" y="5000000"/><a:ext cx="2000000" cy="2000000"/>` +
+		`<a:chOff x="0" y="0"/><a:chExt cx="1000000" cy="1000000"/></a:xfrm></p:grpSpPr>` +
+		textShape("group right", 600000, 0) + textShape("group left", 0, 0) + `</p:grpSp>`
+	slide := `<p:sld ` + pptxNS + `><p:cSld><p:spTree>` +
+		group +
+		textShape("bottom", 0, 9000000) +
+		textShape("right", 4000000, 1000100) +
+		textShape("left", 100000, 1000000) +
+		textShape("below left", -1, 0) +
+		textShape("top", 0, 0) +
+		`</p:spTree></p:cSld></p:sld>`
+	defer func() { SlideNumbers = true }()
+	SlideNumbers = false
+	result, _, err := Pptx2md(buildPptx(t, map[string]string{"ppt/slides/slide1.xml": slide}), false, "")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := "top\nleft\nbelow left\nright\ngroup left\ngroup right\nbottom\n\n\n---\n"
+	if result != want {
+		t.Errorf("expected %q, got %q", want, result)
+	}
+}
+
+// TestPptxToMd_InheritedPositions test placeholders without position are placed by their layout or master
+func TestPptxToMd_InheritedPositions(t *testing.T) {
+	placeholder := func(ph, text string, y int) string {
+		spPr := `<p:spPr/>`
+		if y >= 0 {
+			spPr = fmt.Sprintf(`<p:spPr><a:xfrm><a:off x="0" y="%d"/><a:ext cx="100" cy="100"/></a:xfrm></p:spPr>`, y)
+		}
+		return `<p:sp><p:nvSpPr><p:cNvPr id="1" name="s"/><p:cNvSpPr/><p:nvPr>` + ph + `</p:nvPr></p:nvSpPr>` + spPr +
+			`<p:txBody><a:p><a:r><a:t>` + text + `</a:t></a:r></a:p></p:txBody></p:sp>`
+	}
+	rels := func(typ, target string) string {
+		return `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/` + typ + `" Target="` + target + `"/></Relationships>`
+	}
+	tree := func(root string, shapes ...string) string {
+		return `<p:` + root + ` ` + pptxNS + `><p:cSld><p:spTree>` + strings.Join(shapes, "") + `</p:spTree></p:cSld></p:` + root + `>`
+	}
+	parts := map[string]string{
+		"ppt/slides/slide1.xml": tree("sld", placeholder(`<p:ph type="subTitle"/>`, "subtitle", -1),
+			textShape("middle", 0, 2000000), placeholder(`<p:ph idx="1"/>`, "body", -1)),
+		"ppt/slides/_rels/slide1.xml.rels":             rels("slideLayout", "../slideLayouts/slideLayout1.xml"),
+		"ppt/slideLayouts/slideLayout1.xml":            tree("sldLayout", placeholder(`<p:ph type="subTitle"/>`, "", 5000000)),
+		"ppt/slideLayouts/_rels/slideLayout1.xml.rels": rels("slideMaster", "../slideMasters/slideMaster1.xml"),
+		"ppt/slideMasters/slideMaster1.xml":            tree("sldMaster", placeholder(`<p:ph type="body" idx="1"/>`, "", 100)),
+	}
+	defer func() { SlideNumbers = true }()
+	SlideNumbers = false
+	result, _, err := Pptx2md(buildPptx(t, parts), false, "")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := "- body\nmiddle\nsubtitle\n\n\n---\n"
+	if result != want {
+		t.Errorf("expected %q, got %q", want, result)
+	}
+}
+
+// TestPptxToMd_Table test pptx tables with merged and multi paragraph cells
+func TestPptxToMd_Table(t *testing.T) {
+	cell := func(attrs string, texts ...string) string {
+		c := `<a:tc` + attrs + `><a:txBody><a:bodyPr/>`
+		for _, text := range texts {
+			c += `<a:p><a:r><a:t>` + text + `</a:t></a:r></a:p>`
+		}
+		return c + `</a:txBody><a:tcPr/></a:tc>`
+	}
+	slide := `<p:sld ` + pptxNS + `><p:cSld><p:spTree><p:graphicFrame><p:nvGraphicFramePr><p:cNvPr id="4" name="t"/>` +
+		`<p:cNvGraphicFramePr/><p:nvPr/></p:nvGraphicFramePr><p:xfrm><a:off x="0" y="0"/><a:ext cx="10" cy="10"/></p:xfrm>` +
+		`<a:graphic><a:graphicData><a:tbl><a:tblPr firstRow="1"/><a:tblGrid><a:gridCol w="10"/><a:gridCol w="10"/></a:tblGrid>` +
+		`<a:tr h="10">` + cell("", "Name") + cell("", "Value") + `</a:tr>` +
+		`<a:tr h="10">` + cell(` gridSpan="2"`, "merged") + cell(` hMerge="1"`, "hidden") + `</a:tr>` +
+		`<a:tr h="10">` + cell("", "a") + cell("", "line 1", "line 2") + `</a:tr>` +
+		`</a:tbl></a:graphicData></a:graphic></p:graphicFrame></p:spTree></p:cSld></p:sld>`
+	defer func() { SlideNumbers = true }()
+	SlideNumbers = false
+	result, _, err := Pptx2md(buildPptx(t, map[string]string{"ppt/slides/slide1.xml": slide}), false, "")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := "|Name  |Value           |\n|------|----------------|\n|merged|                |\n|a     |line 1<br>line 2|\n"
+	if !strings.HasPrefix(result, want) {
+		t.Errorf("expected %q, got %q", want, result)
+	}
+}
+
+// TestPptxToMd_Images test pptx images are embedded and described
+func TestPptxToMd_Images(t *testing.T) {
+	pic := `<p:pic><p:nvPicPr><p:cNvPr id="2" name="img" descr="a chart"/><p:cNvPicPr/><p:nvPr/></p:nvPicPr>` +
+		`<p:blipFill><a:blip r:embed="rId3"/></p:blipFill><p:spPr/></p:pic>`
+	pptxfile := buildPptx(t, map[string]string{
+		"ppt/slides/slide1.xml": `<p:sld ` + pptxNS + `><p:cSld><p:spTree>` + pic + `</p:spTree></p:cSld></p:sld>`,
+		"ppt/slides/_rels/slide1.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId3" Type="image" Target="../media/image1.png"/></Relationships>`,
+		"ppt/media/image1.png": "\x89PNG\r\n\x1a\n0000",
+	})
+	defer func() { SlideNumbers, DescribeImages = true, false }()
+	SlideNumbers, DescribeImages = false, true
+	result, _, err := Pptx2md(pptxfile, true, "")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.HasPrefix(result, "![a chart](data:image/png;base64,") {
+		t.Errorf("expected png data uri, got %q", result)
+	}
+	if want := "\n[image1.png]: a chart\n"; !strings.HasSuffix(result, want) {
+		t.Errorf("expected %q at the end, got %q", want, result)
+	}
+}
@@ -0,0 +1,281 @@
+package docx2md
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/sacquatella/tomd/tools"
+)
+
+// runStyle is the markdown relevant formatting of a text run.
+type runStyle struct {
+	bold      bool
+	italic    bool
+	strike    bool
+	underline bool
+	sup       bool
+	sub       bool
+	highlight bool
+	smallCaps bool
+	caps      bool
+	code      bool
+	link      string
+}
+
+// monospaceFonts lists font families rendered as inline code.
+var monospaceFonts = []string{
+	"courier", "consolas", "lucida console", "lucida sans typewriter", "menlo",
+	"monaco", "mono", "source code", "fira code", "cascadia", "inconsolata",
+}
+
+// codeStyles lists character and paragraph styles used for source code.
+var codeStyles = map[string]bool{
+	"HTMLCode":         true,
+	"HTMLKeyboard":     true,
+	"HTMLTypewriter":   true,
+	"HTMLPreformatted": true,
+	"SourceCode":       true,
+	"VerbatimChar":     true,
+	"Code":             true,
+	"CodeChar":         true,
+	"InlineCode":       true,
+	"PrformatHTML":     true,
+	"CodeHTML":         true,
+	"MachinecrireHTML": true,
+	"PreformattedText": true,
+	"SourceText":       true,
+}
+
+// isMonospace reports whether the font family is a fixed width font.
+func isMonospace(font string) bool {
+	font = strings.ToLower(font)
+	if font == "" {
+		return false
+	}
+	for _, m := range monospaceFonts {
+		if strings.Contains(font, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// isToggleOn reports whether an on/off property (w:b, w:i...) is enabled.
+// The property is on when present without value or with a true value.
+func isToggleOn(attrs []xml.Attr) bool {
+	val, ok := attr(attrs, "val")
+	if !ok {
+		return true
+	}
+	switch strings.ToLower(val) {
+	case "0", "false", "off", "none":
+		return false
+	}
+	return true
+}
+
+// runStyle returns the formatting of a docx (w:r) or pptx (a:r) run.
+func (zf *file) runStyle(node *Node) runStyle {
+	var style runStyle
+	for _, n := range node.Nodes {
+		if n.XMLName.Local != "rPr" {
+			continue
+		}
+		// pptx context, formatting is set as attributes of a:rPr
+		for _, a := range n.Attrs {
+			switch a.Name.Local {
+			case "b":
+				style.bold = a.Value == "1" || a.Value == "true"
+			case "i":
+				style.italic = a.Value == "1" || a.Value == "true"
+			case "strike":
+				style.strike = a.Value != "noStrike"
+			case "u":
+				style.underline = a.Value != "none"
+			case "baseline":
+				if i, err := strconv.Atoi(a.Value); err == nil {
+					style.sup = i > 0
+					style.sub = i < 0
+				}
+			case "cap":
+				style.caps = a.Value == "all"
+				style.smallCaps = a.Value == "small"
+			}
+		}
+
+		// docx context, formatting is set as sub nodes of w:rPr
+		for _, nn := range n.Nodes {
+			switch nn.XMLName.Local {
+			case "b":
+				style.bold = isToggleOn(nn.Attrs)
+			case "i":
+				style.italic = isToggleOn(nn.Attrs)
+			case "strike", "dstrike":
+				style.strike = isToggleOn(nn.Attrs)
+			case "u":
+				style.underline = isToggleOn(nn.Attrs)
+			case "vertAlign":
+				val, _ := attr(nn.Attrs, "val")
+				style.sup = val == "superscript"
+				style.sub = val == "subscript"
+			case "highlight":
+				style.highlight = isToggleOn(nn.Attrs)
+			case "smallCaps":
+				style.smallCaps = isToggleOn(nn.Attrs)
+			case "caps":
+				style.caps = isToggleOn(nn.Attrs)
+			case "rStyle":
+				val, _ := attr(nn.Attrs, "val")
+				switch {
+				case zf.isCodeStyle(val):
+					style.code = true
+				case val == "Strong" || val == "lev":
+					style.bold = true
+				case val == "Emphasis" || val == "Accentuation":
+					style.italic = true
+				}
+			case "rFonts":
+				for _, name := range []string{"ascii", "hAnsi"} {
+					if font, ok := attr(nn.Attrs, name); ok && isMonospace(font) {
+						style.code = true
+					}
+				}
+			case "latin":
+				if font, ok := attr(nn.Attrs, "typeface"); ok && isMonospace(font) {
+					style.code = true
+				}
+			case "hlinkClick":
+				id, _ := attr(nn.Attrs, "id")
+				style.link = zf.relTarget(id)
+			}
+		}
+	}
+	return style
+}
+
+// relTarget returns the target of the relationship with the given id.
+func (zf *file) relTarget(id string) string {
+	for _, rel := range zf.rels.Relationship {
+		if id == rel.ID {
+			return rel.Target
+		}
+	}
+	return ""
+}
+
+// wrap formats the text of a run according to its style.
+// Surrounding spaces stay outside of the markers so that emphasis stays valid.
+func (s runStyle) wrap(text string) string {
+	left := strings.TrimLeft(text, " \t")
+	core := strings.TrimRight(left, " \t")
+	if core == "" {
+		return text
+	}
+	lead := text[:len(text)-len(left)]
+	trail := left[len(core):]
+
+	if s.caps {
+		core = strings.ToUpper(core)
+	}
+	if s.code {
+		core = codeSpan(core)
+	} else {
+		core = tools.Escape(core, "\\*_~[]`")
+	}
+	if s.sup {
+		core = "<sup>" + core + "</sup>"
+	}
+	if s.sub {
+		core = "<sub>" + core + "</sub>"
+	}
+	if s.smallCaps {
+		core = `<span style="font-variant:small-caps">` + core + "</span>"
+	}
+	if s.underline && s.link == "" {
+		core = "<u>" + core + "</u>"
+	}
+	if s.highlight {
+		core = "<mark>" + core + "</mark>"
+	}
+	if s.italic {
+		core = "*" + core + "*"
+	}
+	if s.bold {
+		core = "**" + core + "**"
+	}
+	if s.strike {
+		core = "~~" + core + "~~"
+	}
+	if s.link != "" {
+		core = "[" + core + "](" + tools.Escape(s.link, "()") + ")"
+	}
+	return lead + core + trail
+}
+
+// codeSpan returns a text as inline code.
+func codeSpan(s string) string {
+	if strings.Contains(s, "`") {
+		return "`` " + s + " ``"
+	}
+	return "`" + s + "`"
+}
+
+// walkRuns walks the children of a paragraph, merging adjacent runs sharing
+// the same formatting so that markers are written once for the whole text.
+func (zf *file) walkRuns(nodes []Node, w io.Writer) error {
+	var current runStyle
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			fmt.Fprint(w, current.wrap(text.String()))
+			text.Reset()
+		}
+	}
+	for i := range nodes {
+		node := &nodes[i]
+		if node.XMLName.Local != "r" {
+			// nodes without output (proofErr, bookmarkEnd...) don't break the merge
+			var cbuf bytes.Buffer
+			if err := zf.walk(node, &cbuf); err != nil {
+				return err
+			}
+			if cbuf.Len() > 0 {
+				flush()
+				w.Write(cbuf.Bytes())
+			}
+			continue
+		}
+		style := zf.runStyle(node)
+		if link := zf.fieldLink(); link != "" && style.link == "" {
+			style.link = link
+		}
+		for j := range node.Nodes {
+			n := &node.Nodes[j]
+			switch n.XMLName.Local {
+			case "rPr":
+			case "t":
+				if zf.hidden() {
+					continue
+				}
+				t := innerText(n)
+				// a blank run takes the formatting of the text around it
+				if style != current && (strings.TrimSpace(t) != "" || text.Len() == 0) {
+					flush()
+					current = style
+				}
+				text.WriteString(t)
+			default:
+				flush()
+				if err := zf.walk(n, w); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	flush()
+	return nil
+}
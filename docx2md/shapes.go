@@ -0,0 +1,78 @@
+package docx2md
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// calloutTitle matches a title alone on its line or followed by a colon, in bold or not
+var calloutTitle = regexp.MustCompile(`^(?:\*\*)?(\pL+)\s*(?::\s*(?:\*\*)?|(?:\*\*)?\s*:\s*|(?:\*\*)?\s*(?:\n|$))`)
+
+// supportedChoice reports whether the namespaces required by a mc:Choice
+// are handled by the walker, otherwise the mc:Fallback is rendered.
+func supportedChoice(node *Node) bool {
+	requires, _ := attr(node.Attrs, "Requires")
+	for _, prefix := range strings.Fields(requires) {
+		if !supportedNamespaces[prefix] {
+			return false
+		}
+	}
+	return true
+}
+
+// alternateBranch returns the branch of a mc:AlternateContent to render: the
+// first supported choice, or the fallback, so that content is neither lost nor duplicated.
+func alternateBranch(node *Node) *Node {
+	for i := range node.Nodes {
+		n := &node.Nodes[i]
+		if n.XMLName.Local == "Choice" && supportedChoice(n) {
+			return n
+		}
+	}
+	return child(node, "Fallback")
+}
+
+// alternateContent renders a single branch of a mc:AlternateContent.
+func (zf *file) alternateContent(node *Node, w io.Writer) error {
+	if branch := alternateBranch(node); branch != nil {
+		return zf.walkBlocks(branch.Nodes, w)
+	}
+	return nil
+}
+
+// textBox renders the content of a text box as a blockquote. A text box
+// starting with a known title (Note, Warning...) becomes a callout.
+func (zf *file) textBox(node *Node, w io.Writer) error {
+	var cbuf bytes.Buffer
+	if err := zf.walkBlocks(node.Nodes, &cbuf); err != nil {
+		return err
+	}
+	content := strings.Trim(cbuf.String(), "\n")
+	if strings.TrimSpace(content) == "" {
+		return nil
+	}
+	if m := calloutTitle.FindStringSubmatch(content); m != nil {
+		if kind, ok := callouts[strings.ToLower(m[1])]; ok {
+			content = "[!" + kind + "]\n" + strings.TrimLeft(content[len(m[0]):], " \n")
+		}
+	}
+	writeBlockquote(content, w)
+	return nil
+}
+
+// writeBlockquote writes a markdown content as a blockquote.
+func writeBlockquote(content string, w io.Writer) {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	fmt.Fprintln(w)
+	for _, line := range lines {
+		if line == "" {
+			fmt.Fprintln(w, ">")
+		} else {
+			fmt.Fprintln(w, "> "+line)
+		}
+	}
+	fmt.Fprintln(w)
+}
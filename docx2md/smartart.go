@@ -0,0 +1,118 @@
+package docx2md
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sacquatella/tomd/tools"
+)
+
+// diagramChild is a child of a SmartArt point, ordered by its position in the parent.
+type diagramChild struct {
+	order int
+	id    string
+}
+
+// pointText returns the text of a SmartArt point, its paragraphs joined by spaces.
+func pointText(pt *Node) string {
+	t := child(pt, "t")
+	if t == nil {
+		return ""
+	}
+	var texts []string
+	for i := range t.Nodes {
+		if t.Nodes[i].XMLName.Local != "p" {
+			continue
+		}
+		if s := strings.TrimSpace(plainText(&t.Nodes[i])); s != "" {
+			texts = append(texts, s)
+		}
+	}
+	return strings.Join(texts, " ")
+}
+
+// smartArt renders the data model of a SmartArt diagram as a nested bullet list.
+// The model is a list of points linked by parent/child connections; layout and
+// presentation points are ignored.
+func (zf *file) smartArt(node *Node, w io.Writer) error {
+	id, ok := attr(node.Attrs, "dm")
+	if !ok {
+		return nil
+	}
+	name, ok := zf.partPath(zf.relTarget(id))
+	if !ok {
+		return nil
+	}
+	f := findFile(zf.r.File, name)
+	if f == nil {
+		log.Infof("SmartArt data %s not found in the document", name)
+		return nil
+	}
+	model, err := readFile(f)
+	if err != nil {
+		return err
+	}
+
+	texts := make(map[string]string)
+	root := ""
+	if ptLst := child(model, "ptLst"); ptLst != nil {
+		for i := range ptLst.Nodes {
+			pt := &ptLst.Nodes[i]
+			modelID, _ := attr(pt.Attrs, "modelId")
+			switch typ, _ := attr(pt.Attrs, "type"); typ {
+			case "doc":
+				root = modelID
+			case "", "node", "asst":
+				texts[modelID] = pointText(pt)
+			}
+		}
+	}
+	children := make(map[string][]diagramChild)
+	if cxnLst := child(model, "cxnLst"); cxnLst != nil {
+		for i := range cxnLst.Nodes {
+			cxn := &cxnLst.Nodes[i]
+			if typ, _ := attr(cxn.Attrs, "type"); typ != "" && typ != "parOf" {
+				continue
+			}
+			src, _ := attr(cxn.Attrs, "srcId")
+			dest, _ := attr(cxn.Attrs, "destId")
+			if _, found := texts[dest]; !found {
+				continue
+			}
+			ord, _ := attr(cxn.Attrs, "srcOrd")
+			order, _ := strconv.Atoi(ord)
+			children[src] = append(children[src], diagramChild{order: order, id: dest})
+		}
+	}
+
+	var sb strings.Builder
+	visited := make(map[string]bool)
+	var write func(id string, depth int)
+	write = func(id string, depth int) {
+		nodes := children[id]
+		sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].order < nodes[j].order })
+		for _, c := range nodes {
+			if visited[c.id] {
+				continue
+			}
+			visited[c.id] = true
+			// empty placeholders don't add a level
+			if text := texts[c.id]; text != "" {
+				fmt.Fprintf(&sb, "%s- %s\n", strings.Repeat("  ", depth), tools.Escape(text, "\\*_~[]`"))
+				write(c.id, depth+1)
+			} else {
+				write(c.id, depth)
+			}
+		}
+	}
+	write(root, 0)
+	if sb.Len() > 0 {
+		fmt.Fprint(w, "\n"+sb.String()+"\n")
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package docx2md
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sacquatella/tomd/tools"
+)
+
+// Toc regenerate a markdown table of contents in place of Word TOC fields
+var Toc bool
+
+// heading is a heading of the document used to build the table of contents.
+type heading struct {
+	level int
+	text  string
+	slug  string
+}
+
+// field is a Word field (TOC, PAGEREF, REF, HYPERLINK...) being rendered.
+type field struct {
+	instr    string
+	inResult bool
+	hidden   bool
+	link     string
+}
+
+var (
+	tocStyleName = regexp.MustCompile(`^toc (\d)$`)
+	headingName  = regexp.MustCompile(`^heading (\d)$`)
+	fieldRef     = regexp.MustCompile(`^\s*(REF|PAGEREF|NOTEREF)\s+"?([^\s"]+)"?`)
+	fieldLink    = regexp.MustCompile(`^\s*HYPERLINK\s+(\\l\s+)?"([^"]*)"(\s+\\l\s+"([^"]*)")?`)
+	fieldTarget  = regexp.MustCompile(`(?:REF|\\l)\s+"?([^\s"\\]+)"?`)
+)
+
+// styleName returns the name of a style from its id.
+func (zf *file) styleName(id string) string {
+	for _, style := range zf.styles.Style {
+		if style.StyleID == id {
+			return strings.ToLower(style.Name.Val)
+		}
+	}
+	return ""
+}
+
+// headingLevel returns the heading level of a paragraph style, or 0.
+func (zf *file) headingLevel(style string) int {
+	if strings.HasPrefix(style, "Heading") {
+		if i, err := strconv.Atoi(style[7:]); err == nil && i > 0 {
+			return i
+		}
+	}
+	if level, found := customHeadings[style]; found {
+		return level
+	}
+	if m := headingName.FindStringSubmatch(zf.styleName(style)); m != nil {
+		i, _ := strconv.Atoi(m[1])
+		return i
+	}
+	return 0
+}
+
+// tocLevel returns the level of a table of contents paragraph style, or 0.
+func (zf *file) tocLevel(style string) int {
+	if m := tocStyleName.FindStringSubmatch(zf.styleName(style)); m != nil {
+		i, _ := strconv.Atoi(m[1])
+		return i
+	}
+	return 0
+}
+
+// indexAnchors collect headings and bookmarks before rendering so that links
+// to a bookmark defined later in the document can be resolved.
+func (zf *file) indexAnchors(node *Node) {
+	if node.XMLName.Local == "AlternateContent" {
+		if branch := alternateBranch(node); branch != nil {
+			zf.indexAnchors(branch)
+		}
+		return
+	}
+	if node.XMLName.Local != "p" {
+		for i := range node.Nodes {
+			zf.indexAnchors(&node.Nodes[i])
+		}
+		return
+	}
+	// numbers of numbered headings are part of their anchor
+	var number bytes.Buffer
+	if pPr := child(node, "pPr"); pPr != nil {
+		zf.paragraphNumbering(pPr, &number)
+	}
+	slug := ""
+	if level := zf.headingLevel(paragraphStyle(node)); level > 0 {
+		text := strings.TrimSpace(number.String() + plainText(node))
+		slug = tools.UniqueSlug(zf.slugs, text)
+		zf.headings = append(zf.headings, heading{level: level, text: text, slug: slug})
+	}
+	var visit func(n *Node)
+	visit = func(n *Node) {
+		switch n.XMLName.Local {
+		case "bookmarkStart":
+			if name, ok := attr(n.Attrs, "name"); ok {
+				if slug != "" {
+					zf.anchors[name] = slug
+					zf.headingBookmarks[name] = true
+				} else if _, found := zf.anchors[name]; !found {
+					zf.anchors[name] = tools.Slugify(name)
+				}
+			}
+		case "hyperlink":
+			if anchor, ok := attr(n.Attrs, "anchor"); ok {
+				zf.referenced[anchor] = true
+			}
+		case "instrText":
+			for _, m := range fieldTarget.FindAllStringSubmatch(innerText(n), -1) {
+				zf.referenced[m[1]] = true
+			}
+		case "fldSimple":
+			instr, _ := attr(n.Attrs, "instr")
+			for _, m := range fieldTarget.FindAllStringSubmatch(instr, -1) {
+				zf.referenced[m[1]] = true
+			}
+		}
+		for i := range n.Nodes {
+			visit(&n.Nodes[i])
+		}
+	}
+	visit(node)
+}
+
+// anchor returns the markdown link target of a bookmark.
+func (zf *file) anchor(name string) string {
+	if slug, ok := zf.anchors[name]; ok {
+		return "#" + slug
+	}
+	return "#" + tools.Slugify(name)
+}
+
+// writeToc writes the table of contents built from the document headings.
+func (zf *file) writeToc(w io.Writer) {
+	zf.tocWritten = true
+	minLevel := 0
+	for _, h := range zf.headings {
+		if minLevel == 0 || h.level < minLevel {
+			minLevel = h.level
+		}
+	}
+	for _, h := range zf.headings {
+		fmt.Fprintf(w, "%s- [%s](#%s)\n", strings.Repeat("  ", h.level-minLevel), tools.Escape(h.text, "\\*_~[]`"), h.slug)
+	}
+}
+
+// hidden reports whether the current field result must not be written.
+func (zf *file) hidden() bool {
+	for _, f := range zf.fields {
+		if f.hidden {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldLink returns the link target of the innermost link field being rendered.
+func (zf *file) fieldLink() string {
+	for i := len(zf.fields) - 1; i >= 0; i-- {
+		if zf.fields[i].inResult && zf.fields[i].link != "" {
+			return zf.fields[i].link
+		}
+	}
+	return ""
+}
+
+// beginFieldResult interprets the instruction of a field when its result starts.
+func (zf *file) beginFieldResult(f *field, w io.Writer) {
+	f.inResult = true
+	instr := strings.TrimSpace(f.instr)
+	switch {
+	case strings.HasPrefix(instr, "TOC"):
+		if Toc {
+			zf.writeToc(w)
+			f.hidden = true
+		}
+	case strings.HasPrefix(instr, "PAGE"), strings.HasPrefix(instr, "NUMPAGES"):
+		// page numbers have no meaning in markdown
+		f.hidden = true
+	case strings.HasPrefix(instr, "REF"), strings.HasPrefix(instr, "NOTEREF"):
+		if m := fieldRef.FindStringSubmatch(instr); m != nil {
+			f.link = zf.anchor(m[2])
+		}
+	case strings.HasPrefix(instr, "HYPERLINK"):
+		if m := fieldLink.FindStringSubmatch(instr); m != nil {
+			switch {
+			case m[1] != "":
+				f.link = zf.anchor(m[2])
+			case m[4] != "":
+				f.link = m[2] + zf.anchor(m[4])
+			default:
+				f.link = m[2]
+			}
+		}
+	}
+}
+
+// bookmark writes an html anchor for a referenced bookmark outside of headings,
+// headings already get their anchor from the markdown renderer.
+func (zf *file) bookmark(node *Node, w io.Writer) {
+	name, _ := attr(node.Attrs, "name")
+	if !zf.referenced[name] || zf.headingBookmarks[name] {
+		return
+	}
+	fmt.Fprintf(w, `<a id="%s"></a>`, zf.anchors[name])
+}
+
+// fldChar handles the begin, separate and end marks of a complex field.
+func (zf *file) fldChar(node *Node, w io.Writer) {
+	typ, _ := attr(node.Attrs, "fldCharType")
+	switch typ {
+	case "begin":
+		zf.fields = append(zf.fields, &field{})
+	case "separate":
+		if len(zf.fields) > 0 {
+			zf.beginFieldResult(zf.fields[len(zf.fields)-1], w)
+		}
+	case "end":
+		if len(zf.fields) > 0 {
+			zf.fields = zf.fields[:len(zf.fields)-1]
+		}
+	}
+}
@@ -0,0 +1,441 @@
+package docx2md
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sacquatella/tomd/tools"
+)
+
+// Sheets selects the sheets to convert by name or number, hidden sheets are
+// converted only when selected
+var Sheets []string
+
+// MaxRows limits the number of rows converted per sheet, 0 for no limit
+var MaxRows = 0
+
+// builtinFormats are the number formats predefined by Excel, locale dates
+// (27-36, 50-58) are rendered as ISO dates
+var builtinFormats = map[int]string{
+	1: "0", 2: "0.00", 3: "#,##0", 4: "#,##0.00", 9: "0%", 10: "0.00%", 11: "0.00E+00",
+	14: "yyyy-mm-dd", 15: "d-mmm-yy", 16: "d-mmm", 17: "mmm-yy",
+	18: "h:mm AM/PM", 19: "h:mm:ss AM/PM", 20: "h:mm", 21: "h:mm:ss", 22: "m/d/yy h:mm",
+	37: "#,##0", 38: "#,##0", 39: "#,##0.00", 40: "#,##0.00",
+	45: "mm:ss", 46: "[h]:mm:ss", 47: "mm:ss.0", 48: "##0.0E+0",
+}
+
+// workbook is the state shared by the sheets of a spreadsheet
+type workbook struct {
+	sharedStrings []string
+	numFmts       []string // format code of each cell style
+	date1904      bool
+}
+
+// sheet is the cells with a value of a worksheet, by row and column (0 based)
+type sheet struct {
+	cells   map[[2]int]string
+	merges  [][4]int
+	lastRow int
+	skipped int // rows with values beyond MaxRows
+}
+
+// text returns the text of a plain or rich text string.
+func (t *SheetText) text() string {
+	if len(t.R) == 0 {
+		return t.T
+	}
+	var s strings.Builder
+	for _, r := range t.R {
+		s.WriteString(r.T)
+	}
+	return s.String()
+}
+
+// parseRef returns the column and the row (0 based) of a cell reference like AB12.
+func parseRef(ref string) (int, int, bool) {
+	col, i := 0, 0
+	for ; i < len(ref); i++ {
+		c := ref[i] | 0x20 // lower case
+		if c < 'a' || c > 'z' {
+			break
+		}
+		col = col*26 + int(c-'a'+1)
+	}
+	row, err := strconv.Atoi(ref[i:])
+	if i == 0 || err != nil || row < 1 {
+		return 0, 0, false
+	}
+	return col - 1, row - 1, true
+}
+
+// cleanFormat returns the first section of a number format in lower case,
+// without literals, colors and locales.
+func cleanFormat(code string) string {
+	code = strings.ToLower(code)
+	var b strings.Builder
+	quoted := false
+	for i := 0; i < len(code); i++ {
+		c := code[i]
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == ';':
+			return b.String()
+		case c == '\\' || c == '_' || c == '*':
+			i++
+		case c == '[':
+			end := strings.IndexByte(code[i:], ']')
+			if end < 0 {
+				return b.String()
+			}
+			// elapsed times like [h] are kept
+			if inner := code[i+1 : i+end]; inner != "" && strings.Trim(inner, "hms") == "" {
+				b.WriteString(inner)
+			}
+			i += end
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// dateLayout returns the layout of a date or time number format.
+func dateLayout(format string) (string, bool) {
+	format = strings.ReplaceAll(format, "am/pm", "")
+	hasTime := strings.ContainsAny(format, "hs")
+	// m is a month unless it is used with hours or seconds
+	hasDate := strings.ContainsAny(format, "dy") || (strings.Contains(format, "m") && !hasTime)
+	switch {
+	case hasDate && hasTime:
+		return "2006-01-02 15:04:05", true
+	case hasDate:
+		return "2006-01-02", true
+	case hasTime:
+		return "15:04:05", true
+	}
+	return "", false
+}
+
+// excelTime converts a serial date of Excel.
+func excelTime(serial float64, date1904 bool) time.Time {
+	base := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	switch {
+	case date1904:
+		base = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	case serial < 60:
+		// Excel counts a 29 february 1900
+		base = time.Date(1899, 12, 31, 0, 0, 0, 0, time.UTC)
+	}
+	days := math.Floor(serial)
+	seconds := math.Round((serial - days) * 86400)
+	return base.AddDate(0, 0, int(days)).Add(time.Duration(seconds) * time.Second)
+}
+
+// numberValue formats a number with the decimals, percent or date of its format.
+func numberValue(v, code string, date1904 bool) string {
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return v
+	}
+	format := cleanFormat(code)
+	if format == "general" || format == "@" {
+		format = ""
+	}
+	if layout, ok := dateLayout(format); ok && f >= 0 && f < 2958466 {
+		return excelTime(f, date1904).Format(layout)
+	}
+	decimals := -1
+	if i := strings.IndexByte(format, '.'); i >= 0 {
+		decimals = 0
+		for _, c := range format[i+1:] {
+			if c != '0' && c != '#' {
+				break
+			}
+			decimals++
+		}
+	} else if strings.ContainsAny(format, "0#") {
+		decimals = 0
+	}
+	switch {
+	case strings.Contains(format, "e+") || strings.Contains(format, "e-"):
+		return strconv.FormatFloat(f, 'E', decimals, 64)
+	case strings.Contains(format, "%"):
+		return strconv.FormatFloat(f*100, 'f', decimals, 64) + "%"
+	}
+	return strconv.FormatFloat(f, 'f', decimals, 64)
+}
+
+// cellValue returns the text of a cell: shared or inline string, boolean,
+// error, cached value of a formula or formatted number.
+func (wb *workbook) cellValue(c *SheetCell) string {
+	switch c.Type {
+	case "s":
+		i, err := strconv.Atoi(strings.TrimSpace(c.V))
+		if err != nil || i < 0 || i >= len(wb.sharedStrings) {
+			return ""
+		}
+		return wb.sharedStrings[i]
+	case "inlineStr":
+		return c.Is.text()
+	case "b":
+		if strings.TrimSpace(c.V) == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	case "str", "e":
+		return c.V
+	case "d":
+		// ISO 8601 date
+		return strings.TrimSuffix(c.V, "T00:00:00")
+	}
+	code := ""
+	if c.S >= 0 && c.S < len(wb.numFmts) {
+		code = wb.numFmts[c.S]
+	}
+	return numberValue(strings.TrimSpace(c.V), code, wb.date1904)
+}
+
+// readSheet reads the cells of a worksheet. Rows are decoded one by one, and
+// only counted beyond MaxRows, to convert the beginning of huge sheets.
+func (wb *workbook) readSheet(f *zip.File) (*sheet, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	sh := &sheet{cells: make(map[[2]int]string), lastRow: -1}
+	d := xml.NewDecoder(rc)
+	rowIndex, read := 0, 0
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "row":
+			var row SheetRow
+			if err := d.DecodeElement(&row, &start); err != nil {
+				return nil, err
+			}
+			if row.R > 0 {
+				rowIndex = row.R - 1
+			}
+			values := make(map[int]string)
+			col := 0
+			for i := range row.C {
+				if c, _, ok := parseRef(row.C[i].Ref); ok {
+					col = c
+				}
+				if v := cellContent(tools.Escape(wb.cellValue(&row.C[i]), "\\*_~[]`")); v != "" {
+					values[col] = v
+				}
+				col++
+			}
+			switch {
+			case len(values) == 0:
+			case MaxRows > 0 && read >= MaxRows:
+				sh.skipped++
+			default:
+				read++
+				for col, v := range values {
+					sh.cells[[2]int{rowIndex, col}] = v
+				}
+				sh.lastRow = rowIndex
+			}
+			rowIndex++
+		case "mergeCell":
+			ref, _ := attr(start.Attr, "ref")
+			from, to, _ := strings.Cut(ref, ":")
+			c1, r1, ok1 := parseRef(from)
+			c2, r2, ok2 := parseRef(to)
+			if ok1 && ok2 {
+				sh.merges = append(sh.merges, [4]int{r1, c1, r2, c2})
+			}
+		}
+	}
+	return sh, nil
+}
+
+// rows returns the rows of the sheet without empty rows and columns. The value
+// of merged cells is repeated in every cell of the range.
+func (sh *sheet) rows() [][]string {
+	for _, m := range sh.merges {
+		v, ok := sh.cells[[2]int{m[0], m[1]}]
+		// merges are few but may cover whole columns
+		if !ok || (m[2]-m[0]+1)*(m[3]-m[1]+1) > 100000 {
+			continue
+		}
+		for r := m[0]; r <= m[2] && r <= sh.lastRow; r++ {
+			for c := m[1]; c <= m[3]; c++ {
+				sh.cells[[2]int{r, c}] = v
+			}
+		}
+	}
+
+	rowSet, colSet := make(map[int]bool), make(map[int]bool)
+	for k := range sh.cells {
+		rowSet[k[0]] = true
+		colSet[k[1]] = true
+	}
+	sorted := func(set map[int]bool) []int {
+		var keys []int
+		for k := range set {
+			keys = append(keys, k)
+		}
+		sort.Ints(keys)
+		return keys
+	}
+	cols := sorted(colSet)
+	var rows [][]string
+	for _, r := range sorted(rowSet) {
+		row := make([]string, len(cols))
+		for i, c := range cols {
+			row[i] = sh.cells[[2]int{r, c}]
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// selectedSheet reports whether a sheet is converted, from its name or number.
+func selectedSheet(name string, number int, state string) bool {
+	if len(Sheets) == 0 {
+		return state == "" || state == "visible"
+	}
+	for _, s := range Sheets {
+		s = strings.TrimSpace(s)
+		if strings.EqualFold(s, name) || s == strconv.Itoa(number) {
+			return true
+		}
+	}
+	return false
+}
+
+// Xlsx2md convert a xlsx file to markdown, each sheet is a table under a heading.
+func Xlsx2md(xlsxPath string) (string, tools.Metadata, error) {
+	r, err := zip.OpenReader(xlsxPath)
+	if err != nil {
+		return "", tools.Metadata{}, err
+	}
+	defer r.Close()
+
+	var prop CoreProperties
+	var book Workbook
+	var styles SheetStyles
+	var sharedStrings struct {
+		SI []SheetText `xml:"si"`
+	}
+	if err := unmarshalPart(r.File, "docProps/core.xml", &prop); err != nil {
+		return "", tools.Metadata{}, err
+	}
+	if err := unmarshalPart(r.File, "xl/workbook.xml", &book); err != nil {
+		return "", tools.Metadata{}, err
+	}
+	if err := unmarshalPart(r.File, "xl/styles.xml", &styles); err != nil {
+		return "", tools.Metadata{}, err
+	}
+	if err := unmarshalPart(r.File, "xl/sharedStrings.xml", &sharedStrings); err != nil {
+		return "", tools.Metadata{}, err
+	}
+	rels, err := readRels(r.File, "xl/workbook.xml")
+	if err != nil {
+		return "", tools.Metadata{}, err
+	}
+
+	wb := &workbook{date1904: book.WorkbookPr.Date1904}
+	for i := range sharedStrings.SI {
+		wb.sharedStrings = append(wb.sharedStrings, sharedStrings.SI[i].text())
+	}
+	custom := make(map[int]string)
+	for _, nf := range styles.NumFmts.NumFmt {
+		custom[nf.NumFmtID] = nf.FormatCode
+	}
+	for _, xf := range styles.CellXfs.Xf {
+		code, ok := custom[xf.NumFmtID]
+		if !ok {
+			code = builtinFormats[xf.NumFmtID]
+			if (xf.NumFmtID >= 27 && xf.NumFmtID <= 36) || (xf.NumFmtID >= 50 && xf.NumFmtID <= 58) {
+				code = "yyyy-mm-dd"
+			}
+		}
+		wb.numFmts = append(wb.numFmts, code)
+	}
+
+	var buf bytes.Buffer
+	for i, s := range book.Sheets.Sheet {
+		if !selectedSheet(s.Name, i+1, s.State) {
+			continue
+		}
+		var target string
+		for _, rel := range rels.Relationship {
+			if rel.ID == s.ID {
+				target = rel.Target
+			}
+		}
+		name := path.Join("xl", target)
+		if strings.HasPrefix(target, "/") {
+			name = strings.TrimPrefix(target, "/")
+		}
+		f := findFile(r.File, name)
+		if target == "" || f == nil {
+			log.Infof("Sheet %s not found in the workbook", s.Name)
+			continue
+		}
+		sh, err := wb.readSheet(f)
+		if err != nil {
+			return "", tools.Metadata{}, err
+		}
+		fmt.Fprintf(&buf, "# %s\n\n", tools.Escape(s.Name, "\\*_~[]`"))
+		if rows := sh.rows(); len(rows) > 0 {
+			writeTable(rows, &buf)
+		}
+		if sh.skipped > 0 {
+			fmt.Fprintf(&buf, "*%d more rows not shown*\n\n", sh.skipped)
+		}
+	}
+
+	var authors []string
+	meta := tools.Metadata{Title: prop.Title, Description: prop.Description, Authors: append(authors, prop.Creator)}
+	return buf.String(), meta, nil
+}
+
+// GetXlsx convert a xlsx file to markdown and add metadata header
+func GetXlsx(xlsxPath string, url string, customerId string, exportDir string, complements tools.Metadata) (tools.Page, error) {
+
+	markdown, meta, err := Xlsx2md(xlsxPath)
+	tools.CheckError(err)
+
+	// Add metadata header to markdown with title , doc_id,description , tags, site_url, authors, creation_date, last_update
+	metadata, metaDatas := tools.BuildFileMetadata(xlsxPath, url, customerId, meta, complements)
+
+	// Add metadata header to markdown
+	markdown = metadata + markdown
+
+	exportedFile := tools.BuildFilename(metaDatas.Title, exportDir, customerId)
+	// Écrire le Markdown dans un fichier
+	err = tools.WriteMarkdownToFile(markdown, exportedFile)
+	tools.CheckError(err)
+
+	return tools.Page{PageId: metaDatas.Doc_id, Url: metaDatas.Site_url, MdFile: exportedFile}, nil
+}
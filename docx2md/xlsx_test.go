@@ -0,0 +1,109 @@
+package docx2md
+
+import (
+	"strings"
+	"testing"
+)
+
+const xlsxNS = `xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+	`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`
+
+// buildXlsx write a workbook with a data sheet, a hidden sheet and a sheet of numbers
+func buildXlsx(t *testing.T) string {
+	sheet := func(rows, extra string) string {
+		return `<worksheet ` + xlsxNS + `><sheetData>` + rows + `</sheetData>` + extra + `</worksheet>`
+	}
+	return buildZip(t, "test.xlsx", map[string]string{
+		"xl/workbook.xml": `<workbook ` + xlsxNS + `><sheets>` +
+			`<sheet name="Data" sheetId="1" r:id="rId1"/>` +
+			`<sheet name="Secret_1*" sheetId="2" state="hidden" r:id="rId2"/>` +
+			`<sheet name="Numbers" sheetId="3" r:id="rId3"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="worksheet" Target="worksheets/sheet1.xml"/>` +
+			`<Relationship Id="rId2" Type="worksheet" Target="worksheets/sheet2.xml"/>` +
+			`<Relationship Id="rId3" Type="worksheet" Target="/xl/worksheets/sheet3.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst ` + xlsxNS + `><si><t>Region</t></si><si><t>Date</t></si>` +
+			`<si><r><t>Total</t></r><r><rPr><b/></rPr><t> (k€)</t></r></si><si><t>North</t></si></sst>`,
+		"xl/styles.xml": `<styleSheet ` + xlsxNS + `><numFmts count="1"><numFmt numFmtId="164" formatCode="dd/mm/yyyy;@"/></numFmts>` +
+			`<cellXfs><xf numFmtId="0"/><xf numFmtId="164"/><xf numFmtId="10"/><xf numFmtId="4"/><xf numFmtId="20"/></cellXfs></styleSheet>`,
+		// column B and row 2 are empty, North is merged on two rows
+		"xl/worksheets/sheet1.xml": sheet(
+			`<row r="1"><c r="A1" t="s"><v>0</v></c><c r="C1" t="s"><v>1</v></c><c r="D1" t="s"><v>2</v></c><c r="E1" t="inlineStr"><is><t>Ok</t></is></c></row>`+
+				`<row r="2"><c r="A2" s="1"/></row>`+
+				`<row r="3"><c r="A3" t="s"><v>3</v></c><c r="C3" s="1"><v>45292</v></c><c r="D3"><f>SUM(1,2)</f><v>12.5</v></c><c r="E3" t="b"><v>1</v></c></row>`+
+				`<row r="4"><c r="A4"/><c r="C4" s="4"><v>0.5</v></c><c r="D4" t="e"><f>1/0</f><v>#DIV/0!</v></c><c r="E4" t="str"><f>A3</f><v>North</v></c></row>`,
+			`<mergeCells count="1"><mergeCell ref="A3:A4"/></mergeCells>`),
+		"xl/worksheets/sheet2.xml": sheet(`<row r="1"><c r="A1" t="inlineStr"><is><t>a*b*c [x](y) __init__</t></is></c>`+
+			"<c r=\"B1\" t=\"inlineStr\"><is><t>```</t></is></c></row>", ""),
+		"xl/worksheets/sheet3.xml": sheet(
+			`<row r="1"><c r="A1" t="inlineStr"><is><t>Ratio</t></is></c><c r="B1" t="inlineStr"><is><t>Amount</t></is></c></row>`+
+				`<row r="2"><c r="A2" s="2"><v>0.1234</v></c><c r="B2" s="3"><v>1234.5</v></c></row>`+
+				`<row r="3"><c r="A3" s="2"><v>1</v></c><c r="B3"><v>0.30000000000000004</v></c></row>`, ""),
+	})
+}
+
+// TestXlsxToMd test sheets are converted to tables with typed values
+func TestXlsxToMd(t *testing.T) {
+	xlsxfile := buildXlsx(t)
+	defer func() { Sheets, MaxRows = nil, 0 }()
+
+	result, _, err := Xlsx2md(xlsxfile)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := "# Data\n\n" +
+		"|Region|Date      |Total (k€)|Ok   |\n" +
+		"|------|----------|----------|-----|\n" +
+		"|North |2024-01-01|12.5      |TRUE |\n" +
+		"|North |12:00:00  |#DIV/0!   |North|\n\n" +
+		"# Numbers\n\n" +
+		"|Ratio  |Amount             |\n" +
+		"|-------|-------------------|\n" +
+		"|12.34% |1234.50            |\n" +
+		"|100.00%|0.30000000000000004|\n\n"
+	if result != want {
+		t.Errorf("expected %q, got %q", want, result)
+	}
+
+	// hidden sheets are converted when selected, rows are limited, markdown characters are escaped
+	Sheets, MaxRows = []string{"secret_1*", "3"}, 2
+	result, _, err = Xlsx2md(xlsxfile)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want = "# Secret\\_1\\*\n\n|a\\*b\\*c \\[x\\](y) \\_\\_init\\_\\_|\\`\\`\\`|\n|-----------------------------|------|\n\n" +
+		"# Numbers\n\n|Ratio |Amount |\n|------|-------|\n|12.34%|1234.50|\n\n*1 more rows not shown*\n\n"
+	if result != want {
+		t.Errorf("expected %q, got %q", want, result)
+	}
+}
+
+// TestNumberValue test number formats
+func TestNumberValue(t *testing.T) {
+	tests := []struct {
+		v, code  string
+		date1904 bool
+		want     string
+	}{
+		{"3.5", "", false, "3.5"},
+		{"3.456", "0.0", false, "3.5"},
+		{"1234", "#,##0", false, "1234"},
+		{"0.25", "0%", false, "25%"},
+		{"12345", "0.00E+00", false, "1.23E+04"},
+		{"45292.75", "yyyy-mm-dd h:mm", false, "2024-01-01 18:00:00"},
+		{"1", "d/m/yy", false, "1900-01-01"},
+		{"0", "d/m/yy", true, "1904-01-01"},
+		{"45292", `[$-40C]d\ mmmm\ yyyy;@`, false, "2024-01-01"},
+		{"12", `0 "days"`, false, "12"},
+		{"-3", `[Red]0.00`, false, "-3.00"},
+		{"text", "0.00", false, "text"},
+	}
+	for _, tt := range tests {
+		if got := numberValue(tt.v, tt.code, tt.date1904); got != tt.want {
+			t.Errorf("%s with %q: expected %q, got %q", tt.v, tt.code, tt.want, got)
+		}
+	}
+	if !strings.HasPrefix(cleanFormat(`[h]:mm:ss`), "h:") {
+		t.Errorf("expected elapsed hours to be kept, got %q", cleanFormat(`[h]:mm:ss`))
+	}
+}
@@ -48,3 +48,60 @@ type Page struct {
 	Title  string
 	Url    string
 }
+
+// Image is an image extracted from a document, to be described. Data is empty
+// for images linked by the document.
+type Image struct {
+	Name string
+	Data []byte
+	Alt  string
+}
+
+// PdfGlyph is a glyph drawn on a PDF page, X and Y are the start of its baseline
+type PdfGlyph struct {
+	Font     string
+	FontSize float64
+	X, Y, W  float64
+	S        string
+}
+
+// PdfRect is a rectangle of a PDF page, X0 <= X1 and Y0 <= Y1
+type PdfRect struct {
+	X0, Y0, X1, Y1 float64
+}
+
+// PdfImage is an image drawn on a PDF page, its data is read by its key
+type PdfImage struct {
+	Key  string
+	Rect PdfRect
+}
+
+// PdfDest is a position in a PDF document, Top is +Inf for the top of the page
+type PdfDest struct {
+	Page int
+	Top  float64
+}
+
+// PdfLink is a link annotation of a PDF page: a web link when URI is set,
+// else a destination in the document
+type PdfLink struct {
+	Rect PdfRect
+	URI  string
+	Dest PdfDest
+}
+
+// PdfBookmark is an item of the outline of a PDF document
+type PdfBookmark struct {
+	Title string
+	Level int
+	Dest  PdfDest
+}
+
+// PdfContent is what is drawn on a PDF page: the glyphs, the rectangles of
+// tables rules, the images and the links
+type PdfContent struct {
+	Glyphs []PdfGlyph
+	Rects  []PdfRect
+	Images []PdfImage
+	Links  []PdfLink
+}
@@ -16,6 +16,7 @@ package tools
 
 import (
 	"context"
+	"github.com/abadojack/whatlanggo"
 	"github.com/ollama/ollama/api"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
@@ -28,28 +29,8 @@ import (
 // DescribeImg describe an image with Ollama API
 func DescribeImg(img string, lang string) (string, error) {
 
-	// override model if TOML_MODEL env variable is set
-	viper.SetDefault("Model", "llava:7b")
-	viper.SetEnvPrefix("tomd") // will be uppercased automatically
-	viper.BindEnv("Model")     // set env value with TOML_MODEL
-
 	var err error
 	var imgData []byte
-	var prompt string
-
-	switch lang {
-	case "French":
-		//prompt = "décrire cette image"
-		prompt = "Peux-tu décrire l'image suivante qui contient un schéma ? Mentionne les éléments clés, leur disposition et les relations entre eux."
-	case "German":
-		prompt = "beschreibe dieses Bild"
-	case "Italian":
-		prompt = "descrivi questa immagine"
-	case "English":
-		prompt = "describe this image"
-	default:
-		prompt = "describe this image"
-	}
 
 	if strings.HasPrefix(img, "http") {
 		resp, err := http.Get(img)
@@ -68,15 +49,54 @@ func DescribeImg(img string, lang string) (string, error) {
 		}
 	}
 	log.Info("Use ollama to describe image : ", img)
+	return DescribeImgData(imgData, lang)
+}
+
+// DescribeImgData describe image data with Ollama API
+func DescribeImgData(imgData []byte, lang string) (string, error) {
+
+	var prompt string
+
+	switch lang {
+	case "French":
+		//prompt = "décrire cette image"
+		prompt = "Peux-tu décrire l'image suivante qui contient un schéma ? Mentionne les éléments clés, leur disposition et les relations entre eux."
+	case "German":
+		prompt = "beschreibe dieses Bild"
+	case "Italian":
+		prompt = "descrivi questa immagine"
+	case "English":
+		prompt = "describe this image"
+	default:
+		prompt = "describe this image"
+	}
+
+	return askVisionModel(prompt, imgData)
+}
+
+// visionModel returns the vision model of Ollama
+func visionModel() string {
+	// override model if TOML_MODEL env variable is set
+	viper.SetDefault("Model", "llava:7b")
+	viper.SetEnvPrefix("tomd") // will be uppercased automatically
+	viper.BindEnv("Model")     // set env value with TOML_MODEL
+	return viper.GetString("Model")
+}
+
+// askVisionModel sends a prompt and an image to the vision model of Ollama
+func askVisionModel(prompt string, imgData []byte) (string, error) {
+
 	client, err := api.ClientFromEnvironment()
-	CheckError(err)
+	if err != nil {
+		return "", err
+	}
 
-	mymodel := viper.Get("Model")
-	log.Info("Use model  : ", mymodel.(string))
+	mymodel := visionModel()
+	log.Info("Use model  : ", mymodel)
 	log.Info("Use promt  : ", prompt)
 
 	req := &api.GenerateRequest{
-		Model:  mymodel.(string),
+		Model:  mymodel,
 		Prompt: prompt,
 		Images: []api.ImageData{imgData},
 	}
@@ -118,3 +138,36 @@ func imageDescriptionAsMd(imgList []string, lang string) string {
 	}
 	return markdown
 }
+
+// DocumentImagesAsMd add description of images extracted from a document to
+// markdown, the alternative text of an image is used when present
+func DocumentImagesAsMd(markdown string, images []Image) string {
+	lang := whatlanggo.Detect(markdown).Lang.String()
+	return markdown + "\n" + ImagesDescription(images, lang)
+}
+
+// ImagesDescription returns the description of images extracted from a
+// document in a language, as markdown references
+func ImagesDescription(images []Image, lang string) string {
+	var descriptions string
+	for _, img := range images {
+		if strings.HasSuffix(img.Name, ".svg") {
+			continue
+		}
+		mdDesc := img.Alt
+		if mdDesc == "" {
+			var err error
+			log.Info("compute Image: ", img.Name)
+			if img.Data != nil {
+				mdDesc, err = DescribeImgData(img.Data, lang)
+			} else {
+				mdDesc, err = DescribeImg(img.Name, lang)
+			}
+			if err != nil {
+				mdDesc = ""
+			}
+		}
+		descriptions += "\n[" + img.Name + "]: " + mdDesc + "\n"
+	}
+	return descriptions
+}
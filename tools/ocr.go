@@ -0,0 +1,187 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ollama/ollama/api"
+	log "github.com/sirupsen/logrus"
+)
+
+// PdfOcr is the OCR of the scanned pages of PDF files, the pages without text:
+// off, auto (tesseract when installed, else the vision model when Ollama
+// responds), ollama or tesseract
+var PdfOcr = "auto"
+
+// OcrLanguages are the tesseract languages of the scanned pages, like fra+eng
+var OcrLanguages = ""
+
+// OCR transcribes the text of the image of a scanned page, JPEG or PNG, as markdown
+type OCR interface {
+	Transcribe(image []byte) (string, error)
+}
+
+// ocrBackends builds the OCR backends by name
+var ocrBackends = map[string]func() (OCR, error){
+	"ollama": func() (OCR, error) {
+		return ollamaOCR{}, nil
+	},
+	"tesseract": func() (OCR, error) {
+		path, err := exec.LookPath("tesseract")
+		if err != nil {
+			return nil, fmt.Errorf("tesseract not found : %w", err)
+		}
+		return tesseractOCR{path: path, languages: OcrLanguages}, nil
+	},
+}
+
+// ollamaReady reports whether the Ollama server responds and has the vision model
+var ollamaReady = func() bool {
+	client, err := api.ClientFromEnvironment()
+	if err != nil {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = client.Show(ctx, &api.ShowRequest{Model: visionModel()})
+	return err == nil
+}
+
+// newOCR returns the OCR backend of PdfOcr, nil when off. Without backend
+// found by auto, the scans are kept as images.
+func newOCR() (OCR, error) {
+	switch PdfOcr {
+	case "off":
+		return nil, nil
+	case "auto":
+		if ocr, err := ocrBackends["tesseract"](); err == nil {
+			return ocr, nil
+		}
+		if ollamaReady() {
+			return ocrBackends["ollama"]()
+		}
+		log.Infof("No tesseract command nor Ollama vision model %s, scanned pages are kept as images", visionModel())
+		return nil, nil
+	}
+	backend, ok := ocrBackends[PdfOcr]
+	if !ok {
+		return nil, fmt.Errorf("unknown OCR %q, expected off, auto, ollama or tesseract", PdfOcr)
+	}
+	return backend()
+}
+
+// ollamaOCR asks the vision model of Ollama for the transcription
+type ollamaOCR struct{}
+
+// Transcribe returns the text of a page read by the vision model.
+func (ollamaOCR) Transcribe(image []byte) (string, error) {
+	prompt := "Transcribe all the text of this scanned page as markdown, in its original language. " +
+		"Keep the headings, lists and tables, do not describe the page and do not add any comment."
+	text, err := askVisionModel(prompt, image)
+	return strings.TrimSpace(text), err
+}
+
+// tesseractOCR runs the tesseract command
+type tesseractOCR struct {
+	path      string
+	languages string
+}
+
+// Transcribe returns the text of a page read by tesseract, in paragraphs.
+func (t tesseractOCR) Transcribe(image []byte) (string, error) {
+	args := []string{"stdin", "stdout"}
+	if t.languages != "" {
+		args = append(args, "-l", t.languages)
+	}
+	cmd := exec.Command(t.path, args...)
+	cmd.Stdin = bytes.NewReader(image)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%w : %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return textParagraphs(string(out)), nil
+}
+
+// textParagraphs joins the lines of the paragraphs of a plain text, separated
+// by empty lines, and the words hyphenated at the end of the lines.
+func textParagraphs(text string) string {
+	var paragraphs []string
+	for _, block := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		var words []string
+		for _, line := range strings.Split(block, "\n") {
+			for i, w := range strings.Fields(line) {
+				if n := len(words); i == 0 && n > 0 && hyphenated(words[n-1], w) {
+					words[n-1] = strings.TrimRight(words[n-1], "-\u00ad") + w
+					continue
+				}
+				words = append(words, w)
+			}
+		}
+		if len(words) > 0 {
+			paragraphs = append(paragraphs, strings.Join(words, " "))
+		}
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// scanLines transcribes the images of a page without text, from top to
+// bottom. The lines are nil without OCR or when nothing is read, and
+// placeholders until transcribe.
+func scanLines(ocr OCR, extractor PDFExtractor, images []PdfImage, number int, transcribe bool) []pdfLine {
+	if ocr == nil {
+		if transcribe {
+			log.Infof("Page %d has no text, its images are not transcribed without OCR", number)
+		}
+		return nil
+	}
+	if !transcribe {
+		var lines []pdfLine
+		for _, img := range images {
+			lines = append(lines, pdfLine{markdown: img.Key, x0: img.Rect.X0, x1: img.Rect.X1, y: img.Rect.Y1})
+		}
+		return lines
+	}
+	images = append([]PdfImage{}, images...)
+	sort.SliceStable(images, func(i, j int) bool {
+		return images[i].Rect.Y1 > images[j].Rect.Y1
+	})
+	var lines []pdfLine
+	for _, img := range images {
+		data, _, err := extractor.Image(img.Key)
+		if err != nil {
+			log.Infof("Error %s when extracting the scanned image of page %d", err, number)
+			continue
+		}
+		log.Infof("Transcribe the scanned image of page %d", number)
+		text, err := ocr.Transcribe(data)
+		if err != nil {
+			log.Infof("Error %s when transcribing the scanned image of page %d", err, number)
+			continue
+		}
+		if text = strings.TrimSpace(text); text != "" {
+			lines = append(lines, pdfLine{markdown: text, x0: img.Rect.X0, x1: img.Rect.X1, y: img.Rect.Y1})
+		}
+	}
+	return lines
+}
+
+// shiftHeadings moves the headings of a markdown text below the page headings.
+func shiftHeadings(markdown string, offset int) string {
+	if offset == 0 {
+		return markdown
+	}
+	lines := strings.Split(markdown, "\n")
+	for i, line := range lines {
+		if level := len(line) - len(strings.TrimLeft(line, "#")); level > 0 && strings.HasPrefix(line[level:], " ") {
+			lines[i] = strings.Repeat("#", min(level+offset, 6)) + line[level:]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,101 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeOCR transcribes the scans with its texts, in order
+type fakeOCR struct {
+	texts  []string
+	images [][]byte
+}
+
+func (f *fakeOCR) Transcribe(image []byte) (string, error) {
+	f.images = append(f.images, image)
+	if len(f.texts) == 0 {
+		return "", fmt.Errorf("nothing to read")
+	}
+	text := f.texts[0]
+	f.texts = f.texts[1:]
+	return text, nil
+}
+
+// TestExtractTextFromPDF_Scanned test the pages without text are transcribed by the OCR
+func TestExtractTextFromPDF_Scanned(t *testing.T) {
+	scan := "<< /Type /XObject /Subtype /Image /Width 2 /Height 2 /ColorSpace /DeviceGray /BitsPerComponent 8 /Length 4 >>\nstream\n\x00\x40\x80\xff\nendstream"
+	pdfFile := buildPdfDoc(t, pdfDoc{
+		pages: [][]pdfText{
+			{{"F1", 10, 72, 720, "Cover letter."}},
+			{{text: "q 612 0 0 792 0 0 cm /Scan Do Q"}},
+			{{text: "q 612 0 0 200 0 0 cm /Scan Do Q"}, {text: "q 612 0 0 200 0 500 cm /Scan Do Q"}, {text: "q 612 0 0 200 0 250 cm /Scan Do Q"}},
+		},
+		objects: []string{scan},
+		xobject: "/Scan 12 0 R",
+	})
+	ocr := &fakeOCR{texts: []string{"# Contract\n\nThe parties agree.", "Top of the page.", "  ", "Bottom of the page."}}
+	ocrBackends["fake"] = func() (OCR, error) { return ocr, nil }
+	defer func() {
+		delete(ocrBackends, "fake")
+		PdfOcr = "auto"
+	}()
+
+	PdfOcr = "fake"
+	want := "# Page 1\n\nCover letter.\n\n# Page 2\n\n## Contract\n\nThe parties agree.\n\n" +
+		"# Page 3\n\nTop of the page.\n\nBottom of the page.\n\n"
+	result, err := ExtractTextFromPDF(pdfFile)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result != want {
+		t.Errorf("expected %q, got %q", want, result)
+	}
+	// the images are read from top to bottom, the empty transcription is dropped
+	if len(ocr.images) != 4 || !bytes.HasPrefix(ocr.images[0], []byte("\x89PNG")) {
+		t.Errorf("expected 4 PNG images to transcribe, got %d", len(ocr.images))
+	}
+
+	// without OCR the scanned pages have no text
+	PdfOcr = "off"
+	want = "# Page 1\n\nCover letter.\n\n"
+	if result, err = ExtractTextFromPDF(pdfFile); result != want || err != nil {
+		t.Errorf("expected %q, got %q, %v", want, result, err)
+	}
+
+	// without tesseract nor Ollama, auto keeps the scans as images
+	tesseract, ready := ocrBackends["tesseract"], ollamaReady
+	defer func() { ocrBackends["tesseract"], ollamaReady = tesseract, ready }()
+	ocrBackends["tesseract"] = func() (OCR, error) { return nil, fmt.Errorf("tesseract not found") }
+	ollamaReady = func() bool { return false }
+	PdfOcr = "auto"
+	result, err = Pdf2md(pdfFile, filepath.Join(t.TempDir(), "test-assets"))
+	if err != nil || !strings.Contains(result, "# Page 2\n\n![](test-assets/page-2-image-1.png)") {
+		t.Errorf("expected the scan as an image, got %q, %v", result, err)
+	}
+
+	PdfOcr = "unknown"
+	if _, err = ExtractTextFromPDF(pdfFile); err == nil {
+		t.Errorf("expected an error for an unknown OCR")
+	}
+}
+
+// TestTextParagraphs test the plain text of tesseract is written in paragraphs
+func TestTextParagraphs(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"One line.\n", "One line."},
+		{"First line\nof a para-\ngraph.\n\nSecond one.\n\n\n", "First line of a paragraph.\n\nSecond one."},
+		{"A well-\nKnown name\r\n\r\nend", "A well- Known name\n\nend"},
+		{"\n \n", ""},
+	}
+	for _, test := range tests {
+		if got := textParagraphs(test.text); got != test.want {
+			t.Errorf("expected %q, got %q", test.want, got)
+		}
+	}
+}
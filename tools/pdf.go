@@ -1,84 +1,220 @@
 package tools
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 
-	"github.com/rsc/pdf"
-	log "github.com/sirupsen/logrus"
+	"github.com/abadojack/whatlanggo"
 )
 
-func GetPDF(pdfPath string, url string, customerId string, exportDir string, complements Metadata) (Page, error) {
+// PdfPages restricts the conversion of PDF files to some pages, like 3-10 or 1,4,8-
+var PdfPages string
 
-	markdown, err := ExtractTextFromPDF(pdfPath)
-	CheckError(err)
+// GetPDF converts a PDF file to markdown files with their metadata header: a
+// single file, or a file per part when the document is split.
+func GetPDF(pdfPath string, url string, customerId string, exportDir string, complements Metadata) ([]Page, error) {
 
-	// Add metadata header to markdown with title , doc_id,description , tags, site_url, authors, creation_date, last_update
-	metadata, metaDatas := BuildFileMetadata(pdfPath, url, customerId, Metadata{}, complements)
+	assetsDir := BuildAssetsDir(pdfPath, exportDir, customerId)
+	files := &pdfFiles{pdfPath: pdfPath, url: url, customerId: customerId, exportDir: exportDir, assetsDir: assetsDir, complements: complements}
+	split := pdfSplit{pages: PdfSplitPages, chapters: PdfSplitChapters}
+	err := convertPdf(pdfPath, assetsDir, split, files)
+	return files.pages, err
+}
 
-	// Add metadata header to markdown
-	markdown = metadata + markdown
+// ExtractTextFromPDF extract text from a PDF, without the images.
+func ExtractTextFromPDF(pdfPath string) (string, error) {
+	return Pdf2md(pdfPath, "")
+}
 
-	//exportedFile := exportDir + "/" + customerId + "-" + metaDatas.Title + ".md"
-	exportedFile := BuildFilename(metaDatas.Title, exportDir, customerId)
+// Pdf2md converts a PDF file to markdown. Images are saved in assetsDir,
+// created next to the markdown file, an empty assetsDir disables their extraction.
+func Pdf2md(pdfPath string, assetsDir string) (string, error) {
+	var markdown strings.Builder
+	if err := Pdf2mdWriter(pdfPath, assetsDir, &markdown); err != nil {
+		return "", err
+	}
+	return markdown.String(), nil
+}
 
-	// Écrire le Markdown dans un fichier
-	err = WriteMarkdownToFile(markdown, exportedFile)
-	CheckError(err)
+// Pdf2mdWriter converts a PDF file to markdown written in w page by page,
+// the whole document is never held in memory.
+func Pdf2mdWriter(pdfPath string, assetsDir string, w io.Writer) error {
+	return convertPdf(pdfPath, assetsDir, pdfSplit{}, &pdfStream{w: w})
+}
 
-	return Page{PageId: metaDatas.Doc_id, Url: metaDatas.Site_url, MdFile: exportedFile}, nil
+// pdfStream writes the markdown of a document in a writer, without parts
+type pdfStream struct {
+	w io.Writer
 }
 
-// ExtractTextFromPDF extract text from a PDF.
-// @todo: rewrite with https://github.com/ledongthuc/pdf lib. The current lib is not maintained anymore.
-func ExtractTextFromPDF(pdfPath string) (string, error) {
-	// Ouvrir le fichier PDF
-	file, err := os.Open(pdfPath)
-	if err != nil {
-		return "", fmt.Errorf("can't open PDF file   : %w", err)
+func (s *pdfStream) split(parts []pdfPart) []string {
+	return nil
+}
+
+func (s *pdfStream) start(part pdfPart) (io.Writer, error) {
+	return s.w, nil
+}
+
+func (s *pdfStream) end(images []string) error {
+	return nil
+}
+
+// pdfFiles writes the parts of a document in markdown files, each with its
+// metadata header and the description of its images
+type pdfFiles struct {
+	pdfPath, url, customerId, exportDir string
+	assetsDir                           string
+	complements                         Metadata
+	titles                              []string // by part
+	files                               []string // by part
+	file                                *os.File
+	writer                              *bufio.Writer
+	sample                              textSample // start of the part, for its language
+	meta                                Metadata   // of the current part
+	pages                               []Page
+}
+
+// textSample keeps the start of a text, to detect its language
+type textSample struct {
+	strings.Builder
+}
+
+func (s *textSample) Write(p []byte) (int, error) {
+	if room := 64*1024 - s.Len(); room > 0 {
+		s.Builder.Write(p[:min(room, len(p))])
 	}
-	defer file.Close()
+	return len(p), nil
+}
 
-	// Read PDF document
-	filestat, _ := file.Stat()
-	reader, err := pdf.NewReader(file, filestat.Size())
-	if err != nil {
-		return "", fmt.Errorf("can't read and parse PDF file : %w", err)
+// split names the files of the parts after the title of the document, and
+// the titles of the parts.
+func (f *pdfFiles) split(parts []pdfPart) []string {
+	_, meta := BuildFileMetadata(f.pdfPath, f.url, f.customerId, Metadata{}, f.complements)
+	used := make(map[string]int)
+	var names []string
+	for _, part := range parts {
+		title := meta.Title
+		if len(parts) > 1 && part.title != "" {
+			title = meta.Title + " - " + part.title
+		} else if len(parts) > 1 {
+			title = fmt.Sprintf("%s - %d", meta.Title, part.number)
+		}
+		file := BuildFilename(title, f.exportDir, f.customerId)
+		if used[file]++; used[file] > 1 {
+			// chapters with the same title
+			file = fmt.Sprintf("%s-%d.md", strings.TrimSuffix(file, ".md"), used[file])
+		}
+		f.titles, f.files = append(f.titles, title), append(f.files, file)
+		names = append(names, filepath.Base(file))
 	}
+	if len(parts) == 1 {
+		return nil
+	}
+	return names
+}
 
-	var textBuilder strings.Builder
+func (f *pdfFiles) start(part pdfPart) (io.Writer, error) {
+	// Add metadata header to markdown with title , doc_id,description , tags, site_url, authors, creation_date, last_update
+	complements := f.complements
+	complements.Title = f.titles[part.number-1]
+	metadata, meta := BuildFileMetadata(f.pdfPath, f.url, f.customerId, Metadata{}, complements)
 
-	// parse pages ti get text
-	for i := 1; i <= reader.NumPage(); i++ {
-		page := reader.Page(i)
-		if page.Content().Text == nil {
-			continue
-		}
+	// Écrire le Markdown dans un fichier
+	file, err := os.Create(f.files[part.number-1])
+	if err != nil {
+		return nil, err
+	}
+	f.file, f.writer, f.meta = file, bufio.NewWriter(file), meta
+	f.sample.Reset()
+	if _, err := f.writer.WriteString(metadata); err != nil {
+		return nil, err
+	}
+	f.pages = append(f.pages, Page{PageId: meta.Doc_id, Url: meta.Site_url, MdFile: f.files[part.number-1]})
+	return io.MultiWriter(f.writer, &f.sample), nil
+}
 
-		text := page.V.RawString()
-		log.Debugf("Page %d : %s\n", i, text)
+// end writes the description of the images of the part, read back one by
+// one from the assets folder, and closes its file.
+func (f *pdfFiles) end(images []string) error {
+	err := f.describe(images)
+	if err == nil {
+		err = f.writer.Flush()
+	}
+	if closeErr := f.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
 
-		textBuilder.WriteString(fmt.Sprintf("# Page %d\n\n", i)) //  Add Title for each page
+// describe writes the description of the images with --ia.
+func (f *pdfFiles) describe(images []string) error {
+	if !PdfDescribeImages || len(images) == 0 {
+		return nil
+	}
+	lang := whatlanggo.Detect(f.sample.String()).Lang.String()
+	if _, err := f.writer.WriteString("\n"); err != nil {
+		return err
+	}
+	for _, name := range images {
+		data, err := os.ReadFile(filepath.Join(f.assetsDir, filepath.Base(name)))
+		if err != nil {
+			return err
+		}
+		if _, err := f.writer.WriteString(ImagesDescription([]Image{{Name: name, Data: data}}, lang)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-		content := page.Content()
-		line := 0.0  // Manage line break base on Y position
-		nextX := 0.0 //  X position of next text
-		for _, text := range content.Text {
-			if text.Y != line {
-				textBuilder.WriteString("\n")
+// pageRange returns the pages to convert from a list of pages and of ranges,
+// like 3-10 or 1,4,8-. An empty list is all the pages.
+func pageRange(spec string, count int) ([]int, error) {
+	selected := make([]bool, count+1)
+	if strings.TrimSpace(spec) == "" {
+		for i := range selected {
+			selected[i] = true
+		}
+	}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		from, to, isRange := strings.Cut(part, "-")
+		first, last := 1, count
+		var err error
+		if from = strings.TrimSpace(from); from != "" || !isRange {
+			if first, err = strconv.Atoi(from); err != nil {
+				return nil, fmt.Errorf("invalid page range %q", part)
 			}
-			line = text.Y
-			if nextX < text.X {
-				textBuilder.WriteString(" ")
+		}
+		if !isRange {
+			last = first
+		} else if to = strings.TrimSpace(to); to != "" {
+			if last, err = strconv.Atoi(to); err != nil {
+				return nil, fmt.Errorf("invalid page range %q", part)
 			}
-			textBuilder.WriteString(text.S)
-			nextX = (text.X + text.W) + (text.W)*0.1
 		}
-		textBuilder.WriteString("\n\n") //  Add a line break between pages
+		if first < 1 || first > last || first > count {
+			return nil, fmt.Errorf("page range %q outside of the %d pages of the PDF file", part, count)
+		}
+		for i := first; i <= min(last, count); i++ {
+			selected[i] = true
+		}
 	}
-
-	return textBuilder.String(), nil
+	var pages []int
+	for i := 1; i <= count; i++ {
+		if selected[i] {
+			pages = append(pages, i)
+		}
+	}
+	return pages, nil
 }
 
 // WriteMarkdownToFile  writes markdown content to a file.
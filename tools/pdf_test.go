@@ -1,15 +1,29 @@
 package tools
 
 import (
+	"bytes"
+	"compress/zlib"
+	"crypto/md5"
+	"crypto/rc4"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 )
 
 // TestExtractTextFromPDF_ValidPdf test reads a PDF file and converts it to Markdown
 func TestExtractTextFromPDF_ValidPdf(t *testing.T) {
 
 	pdfFile := "../samples/test.pdf"
-	expectedMarkdown := "Exemple de texte en HTML\nTitre Two"
+	expectedMarkdown := "Exemple de texte en HTML\n\n### Titre Two"
 
 	result, err := ExtractTextFromPDF(pdfFile)
 	if err != nil {
@@ -21,3 +35,938 @@ func TestExtractTextFromPDF_ValidPdf(t *testing.T) {
 	}
 
 }
+
+// pdfText is a text drawn by buildPdf, with the font F1 (regular), F2 (bold) or F3 (italic),
+// or raw operators without font
+type pdfText struct {
+	font string
+	size float64
+	x, y float64
+	text string
+}
+
+// pdfItem is an outline item of buildPdfDoc, with a raw destination or action
+type pdfItem struct {
+	title, dest string
+	children    []pdfItem
+}
+
+// pdfLink is a link annotation of buildPdfDoc, with a raw destination or action
+type pdfLink struct {
+	rect   [4]float64
+	action string
+}
+
+// pdfDoc is a document written by buildPdfDoc
+type pdfDoc struct {
+	pages     [][]pdfText
+	links     [][]pdfLink // by page
+	outline   []pdfItem
+	catalog   string   // raw entries of the catalog
+	objects   []string // raw objects numbered after the pages
+	xobject   string   // raw XObject resources of the pages
+	encrypted bool     // RC4 encryption of the content streams
+	password  string   // user password of the encryption
+}
+
+// pdfPasswordPad pads the passwords of the standard security handler
+const pdfPasswordPad = "\x28\xbf\x4e\x5e\x4e\x75\x8a\x41\x64\x00\x4e\x56\xff\xfa\x01\x08" +
+	"\x2e\x2e\x00\xb6\xd0\x68\x3e\x80\x2f\x0c\xa9\xfe\x64\x53\x69\x7a"
+
+// rc4Encryption returns the encryption dictionary of a password, with 128
+// bits RC4 keys of revision 3, and the encryption of the streams of the objects.
+func rc4Encryption(password, id string) (string, func(obj int, data string) string) {
+	crypt := func(key []byte, data string, rounds int) string {
+		b := []byte(data)
+		for i := 0; i < rounds; i++ {
+			k := make([]byte, len(key))
+			for j := range key {
+				k[j] = key[j] ^ byte(i)
+			}
+			c, _ := rc4.NewCipher(k)
+			c.XORKeyStream(b, b)
+		}
+		return string(b)
+	}
+	hash := func(s string) []byte {
+		sum := md5.Sum([]byte(s))
+		for i := 0; i < 50; i++ {
+			sum = md5.Sum(sum[:])
+		}
+		return sum[:]
+	}
+	padded := (password + pdfPasswordPad)[:32]
+	owner := crypt(hash(padded), padded, 20)
+	key := hash(padded + owner + "\xfc\xff\xff\xff" + id)
+	check := md5.Sum([]byte(pdfPasswordPad + id))
+	user := crypt(key, string(check[:]), 20) + pdfPasswordPad[:16]
+	dict := fmt.Sprintf("<< /Filter /Standard /V 2 /R 3 /Length 128 /P -4 /O <%x> /U <%x> >>", owner, user)
+	return dict, func(obj int, data string) string {
+		objKey := md5.Sum(append(append([]byte{}, key...), byte(obj), byte(obj>>8), byte(obj>>16), 0, 0))
+		return crypt(objKey[:], data, 1)
+	}
+}
+
+// buildPdf write a PDF file with a page for each list of texts
+func buildPdf(t *testing.T, pages ...[]pdfText) string {
+	return buildPdfDoc(t, pdfDoc{pages: pages})
+}
+
+// buildPdfDoc write a PDF file, pages are the objects 6, 8, 10... followed by the raw objects
+func buildPdfDoc(t *testing.T, doc pdfDoc) string {
+	t.Helper()
+	var b bytes.Buffer
+	var offsets []int
+	obj := func(body string) {
+		offsets = append(offsets, b.Len())
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+	font := func(name string) string {
+		// all the glyphs are half an em wide
+		return "<< /Type /Font /Subtype /Type1 /BaseFont /" + name +
+			" /Encoding /WinAnsiEncoding /FirstChar 32 /LastChar 126 /Widths [" +
+			strings.TrimSpace(strings.Repeat("500 ", 95)) + "] >>"
+	}
+
+	// catalog, pages and fonts, then each page and its content, then the outline
+	// and the encryption dictionary
+	b.WriteString("%PDF-1.4\n")
+	id := "0123456789abcdef"
+	encryption, encrypt := rc4Encryption(doc.password, id)
+	var kids []string
+	for i := range doc.pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", 6+2*i))
+	}
+	root := 6 + 2*len(doc.pages) + len(doc.objects)
+	catalog := "<< /Type /Catalog /Pages 2 0 R " + doc.catalog
+	if len(doc.outline) > 0 {
+		catalog += fmt.Sprintf(" /Outlines %d 0 R", root)
+	}
+	obj(catalog + " >>")
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(doc.pages)))
+	obj(font("Helvetica"))
+	obj(font("Helvetica-Bold"))
+	obj(font("Helvetica-Oblique"))
+	for i, texts := range doc.pages {
+		var content strings.Builder
+		for _, tx := range texts {
+			if tx.font == "" {
+				content.WriteString(tx.text + "\n")
+				continue
+			}
+			text := strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`).Replace(tx.text)
+			fmt.Fprintf(&content, "BT /%s %g Tf %g %g Td (%s) Tj ET\n", tx.font, tx.size, tx.x, tx.y, text)
+		}
+		annots := ""
+		if i < len(doc.links) {
+			for _, l := range doc.links[i] {
+				annots += fmt.Sprintf("<< /Type /Annot /Subtype /Link /Rect [%g %g %g %g] %s >> ", l.rect[0], l.rect[1], l.rect[2], l.rect[3], l.action)
+			}
+		}
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Annots [%s] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R /F3 5 0 R >> /XObject << %s >> >> /Contents %d 0 R >>", annots, doc.xobject, 7+2*i))
+		data := content.String()
+		if doc.encrypted {
+			data = encrypt(7+2*i, data)
+		}
+		obj(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(data), data))
+	}
+	for _, o := range doc.objects {
+		obj(o)
+	}
+
+	// outline items are numbered depth first
+	type node struct {
+		id   int
+		item pdfItem
+		kids []*node
+	}
+	last := root
+	var number func(items []pdfItem) []*node
+	number = func(items []pdfItem) []*node {
+		var nodes []*node
+		for _, item := range items {
+			last++
+			n := &node{id: last, item: item}
+			n.kids = number(item.children)
+			nodes = append(nodes, n)
+		}
+		return nodes
+	}
+	var write func(parent int, nodes []*node)
+	write = func(parent int, nodes []*node) {
+		for i, n := range nodes {
+			item := fmt.Sprintf("<< /Title (%s) /Parent %d 0 R %s", n.item.title, parent, n.item.dest)
+			if i+1 < len(nodes) {
+				item += fmt.Sprintf(" /Next %d 0 R", nodes[i+1].id)
+			}
+			if len(n.kids) > 0 {
+				item += fmt.Sprintf(" /First %d 0 R /Last %d 0 R", n.kids[0].id, n.kids[len(n.kids)-1].id)
+			}
+			obj(item + " >>")
+			write(n.id, n.kids)
+		}
+	}
+	if nodes := number(doc.outline); len(nodes) > 0 {
+		obj(fmt.Sprintf("<< /Type /Outlines /First %d 0 R /Last %d 0 R >>", nodes[0].id, nodes[len(nodes)-1].id))
+		write(root, nodes)
+	}
+
+	trailer := fmt.Sprintf("/Size %d /Root 1 0 R", len(offsets)+1)
+	if doc.encrypted {
+		obj(encryption)
+		trailer = fmt.Sprintf("/Size %d /Root 1 0 R /Encrypt %d 0 R /ID [<%x> <%x>]", len(offsets)+1, len(offsets), id, id)
+	}
+
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, o := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", o)
+	}
+	fmt.Fprintf(&b, "trailer\n<< %s >>\nstartxref\n%d\n%%%%EOF\n", trailer, xref)
+
+	pdfFile := filepath.Join(t.TempDir(), "test.pdf")
+	if err := os.WriteFile(pdfFile, b.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return pdfFile
+}
+
+// TestExtractTextFromPDF_Layout test headings from font sizes, paragraphs and emphasis
+func TestExtractTextFromPDF_Layout(t *testing.T) {
+	pdfFile := buildPdf(t, []pdfText{
+		{"F2", 24, 72, 720, "Annual report"},
+		{"F1", 11, 72, 690, "The first paragraph is written"},
+		{"F1", 11, 72, 676, "on two lines."},
+		{"F1", 11, 72, 650, "A second paragraph with"},
+		{"F2", 11, 210, 650, "bold words"},
+		{"F1", 11, 276, 650, "and"},
+		{"F3", 11, 300, 650, "italic."},
+		{"F1", 16, 72, 610, "Results"},
+		{"F2", 11, 72, 585, "Key figures"},
+		{"F1", 11, 72, 571, "Sales grew by ten percent."},
+	}, []pdfText{
+		{"F1", 11, 72, 720, "Second page text."},
+	})
+	defer func() { PdfPageMarkers = false }()
+
+	tests := []struct {
+		markers bool
+		want    string
+	}{
+		{
+			markers: false,
+			want: "# Page 1\n\n## Annual report\n\nThe first paragraph is written on two lines.\n\n" +
+				"A second paragraph with **bold words** and *italic.*\n\n### Results\n\n#### Key figures\n\n" +
+				"Sales grew by ten percent.\n\n# Page 2\n\nSecond page text.\n\n",
+		},
+		{
+			markers: true,
+			want: "<!-- Page 1 -->\n\n# Annual report\n\nThe first paragraph is written on two lines.\n\n" +
+				"A second paragraph with **bold words** and *italic.*\n\n## Results\n\n### Key figures\n\n" +
+				"Sales grew by ten percent.\n\n<!-- Page 2 -->\n\nSecond page text.\n\n",
+		},
+	}
+	for _, tt := range tests {
+		PdfPageMarkers = tt.markers
+		result, err := ExtractTextFromPDF(pdfFile)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if result != tt.want {
+			t.Errorf("expected %q, got %q", tt.want, result)
+		}
+	}
+}
+
+// TestExtractTextFromPDF_Escape test the markdown characters of the text and of the links
+func TestExtractTextFromPDF_Escape(t *testing.T) {
+	defer func() { PdfPageMarkers = false }()
+	PdfPageMarkers = true
+	pdfFile := buildPdfDoc(t, pdfDoc{pages: [][]pdfText{{
+		{"F1", 11, 72, 720, "# not a heading"},
+		{"F1", 11, 72, 690, "- not a list"},
+		{"F1", 11, 72, 660, "2. not a numbered list"},
+		{"F1", 11, 72, 630, "a_b *c* [d] ~e~"},
+		{"F1", 11, 72, 600, "link"},
+	}}, links: [][]pdfLink{{
+		{[4]float64{70, 598, 100, 612}, "/A << /S /URI /URI (http://example.com/a_\\(b\\)) >>"},
+	}}})
+
+	want := "<!-- Page 1 -->\n\n\\# not a heading\n\n\\- not a list\n\n2\\. not a numbered list\n\n" +
+		"a\\_b \\*c\\* \\[d\\] \\~e\\~\n\n[link](http://example.com/a_\\(b\\))\n\n"
+	result, err := ExtractTextFromPDF(pdfFile)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result != want {
+		t.Errorf("expected %q, got %q", want, result)
+	}
+}
+
+// columnLayout lays out paragraphs in columns from the top of the page, with
+// a 10pt font (5pt per glyph) and a 12pt line spacing
+func columnLayout(top float64, columns [][]string, width, gutter float64) ([]pdfText, float64) {
+	var texts []pdfText
+	bottom := top
+	for col, paragraphs := range columns {
+		x := 72 + float64(col)*(width+gutter)
+		y := top
+		for _, paragraph := range paragraphs {
+			line := ""
+			for _, word := range strings.Fields(paragraph) {
+				if line != "" && float64(len(line)+1+len(word))*5 > width {
+					texts = append(texts, pdfText{"F1", 10, x, y, line})
+					line, y = "", y-12
+				}
+				line = strings.TrimSpace(line + " " + word)
+			}
+			texts = append(texts, pdfText{"F1", 10, x, y, line})
+			y -= 24
+		}
+		bottom = min(bottom, y)
+	}
+	return texts, bottom
+}
+
+// loremParagraph returns a paragraph of n words
+func loremParagraph(seed, n int) string {
+	words := strings.Fields("lorem ipsum dolor sit amet consectetur adipiscing elit sed do eiusmod tempor " +
+		"incididunt ut labore et dolore magna aliqua enim ad minim veniam quis nostrud exercitation")
+	var out []string
+	for i := 0; i < n; i++ {
+		out = append(out, words[(seed*7+i*3)%len(words)])
+	}
+	out[0] = fmt.Sprintf("P%d", seed)
+	return strings.Join(out, " ") + "."
+}
+
+// TestExtractTextFromPDF_Columns test the reading order of multi-column pages
+func TestExtractTextFromPDF_Columns(t *testing.T) {
+	two := [][]string{
+		{loremParagraph(1, 40), loremParagraph(2, 30)},
+		{loremParagraph(3, 35), loremParagraph(4, 45)},
+	}
+	three := [][]string{
+		{loremParagraph(5, 30)},
+		{loremParagraph(6, 25), loremParagraph(7, 12)},
+		{loremParagraph(8, 28)},
+	}
+	title := pdfText{"F2", 18, 72, 740, "Multi column layout regression"}
+	footer := "This footer note spans the two columns of the page at the bottom."
+
+	twoColumns, _ := columnLayout(700, two, 220, 24)
+	threeColumns, _ := columnLayout(740, three, 140, 18)
+	withFooter, bottom := columnLayout(700, two, 220, 24)
+	withFooter = append(withFooter, pdfText{"F1", 10, 72, bottom - 24, footer})
+	// labels and values are not columns of text
+	var form []pdfText
+	for i, kv := range [][2]string{{"Name", "Alice Martin"}, {"City", "Paris"}, {"Phone", "0102030405"}, {"Email", "alice@example.com"}} {
+		form = append(form, pdfText{"F1", 10, 72, 700 - float64(i)*12, kv[0]}, pdfText{"F1", 10, 300, 700 - float64(i)*12, kv[1]})
+	}
+
+	tests := []struct {
+		name  string
+		texts []pdfText
+		want  string
+	}{
+		{
+			name:  "two columns",
+			texts: append([]pdfText{title}, twoColumns...),
+			want: "# Multi column layout regression\n\n" + two[0][0] + "\n\n" + two[0][1] + "\n\n" +
+				two[1][0] + "\n\n" + two[1][1] + "\n\n",
+		},
+		{
+			name:  "three columns",
+			texts: threeColumns,
+			want:  three[0][0] + "\n\n" + three[1][0] + "\n\n" + three[1][1] + "\n\n" + three[2][0] + "\n\n",
+		},
+		{
+			name:  "spanning footer",
+			texts: append([]pdfText{title}, withFooter...),
+			want: "# Multi column layout regression\n\n" + two[0][0] + "\n\n" + two[0][1] + "\n\n" +
+				two[1][0] + "\n\n" + two[1][1] + "\n\n" + footer + "\n\n",
+		},
+		{
+			name:  "form",
+			texts: form,
+			want:  "Name Alice Martin City Paris Phone 0102030405 Email alice@example.com\n\n",
+		},
+	}
+	defer func() { PdfPageMarkers = false }()
+	PdfPageMarkers = true
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ExtractTextFromPDF(buildPdf(t, tt.texts))
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			want := "<!-- Page 1 -->\n\n" + tt.want
+			if result != want {
+				t.Errorf("expected %q, got %q", want, result)
+			}
+		})
+	}
+}
+
+// TestFindGutters_OffPage test glyphs far off the page do not size the histograms of the text area
+func TestFindGutters_OffPage(t *testing.T) {
+	row := func(x0, x1 float64) pdfLine {
+		words := []pdfWord{{text: "word", x0: x0, x1: x1, size: 10}}
+		return pdfLine{words: words, x0: x0, x1: x1, size: 10}
+	}
+	tests := [][]pdfLine{
+		{row(72, 300), row(72, 1e12), row(72, 300)},
+		{row(72, 300), row(math.Inf(-1), math.Inf(1)), row(72, 300)},
+		{row(72, 300), row(math.NaN(), 300), row(72, math.NaN())},
+	}
+	for _, rows := range tests {
+		if gutters := findGutters(rows); gutters != nil {
+			t.Errorf("expected no gutter, got %v", gutters)
+		}
+		alignedSeparators(rows)
+	}
+}
+
+// pdfRect draws the outline of a rectangle
+func pdfRect(x, y, w, h float64) pdfText {
+	return pdfText{text: fmt.Sprintf("%g %g %g %g re S", x, y, w, h)}
+}
+
+// TestExtractTextFromPDF_Tables test tables from ruling lines and from the alignment of the text
+func TestExtractTextFromPDF_Tables(t *testing.T) {
+	intro := pdfText{"F1", 10, 72, 740, "Prices of the office supplies."}
+	outro := pdfText{"F1", 10, 72, 600, "Prices include taxes."}
+	cols := []float64{72, 172, 232}
+	table := func(top float64, rows ...[]string) []pdfText {
+		var texts []pdfText
+		for i, row := range rows {
+			for j, cell := range row {
+				texts = append(texts, pdfText{"F1", 10, cols[j] + 2, top - 12*float64(i+1), cell})
+			}
+		}
+		return texts
+	}
+
+	// a grid of cells, with a cell on two lines
+	grid := []pdfText{intro, outro}
+	for _, top := range []float64{720, 690, 660} {
+		for j, width := range []float64{100, 60, 80} {
+			grid = append(grid, pdfRect(cols[j], top-30, width, 30))
+		}
+	}
+	grid = append(grid, table(720, []string{"Item", "Qty", "Price"})...)
+	grid = append(grid, table(690, []string{"Pen", "2", "1.50"})...)
+	grid = append(grid, table(660, []string{"Large", "10", "3.20"}, []string{"notebook"})...)
+
+	// rules above and below the header and at the bottom
+	rows := table(720, []string{"Item", "Qty", "Price"}, []string{"Pen", "2", "1.50"},
+		[]string{"Notebook", "10", "3.20"}, []string{"Stapler", "1", "7.90"})
+	booktabs := append([]pdfText{intro, outro, pdfRect(72, 722, 240, 0.5), pdfRect(72, 703, 240, 0.5), pdfRect(72, 666, 240, 0.5)}, rows...)
+	// a numbered list is not a table
+	stream := append([]pdfText{intro, outro}, rows...)
+	for i, item := range []string{"First step of the list", "Second step", "Third and last step"} {
+		stream = append(stream, pdfText{"F1", 10, 72, 560 - 12*float64(i), fmt.Sprintf("%d.", i+1)}, pdfText{"F1", 10, 90, 560 - 12*float64(i), item})
+	}
+
+	prices := "|Item    |Qty|Price|\n|--------|---|-----|\n|Pen     |2  |1.50 |\n|Notebook|10 |3.20 |\n|Stapler |1  |7.90 |\n\n"
+	tests := []struct {
+		name, mode string
+		texts      []pdfText
+		want       string
+	}{
+		{
+			name: "grid", mode: "lines", texts: grid,
+			want: "Prices of the office supplies.\n\n|Item          |Qty|Price|\n|--------------|---|-----|\n" +
+				"|Pen           |2  |1.50 |\n|Large notebook|10 |3.20 |\n\nPrices include taxes.\n\n",
+		},
+		{
+			name: "grid off", mode: "off", texts: grid,
+			want: "Prices of the office supplies.\n\nItem Qty Price\n\nPen 2 1.50\n\nLarge 10 3.20 notebook\n\nPrices include taxes.\n\n",
+		},
+		{
+			name: "horizontal rules", mode: "lines", texts: booktabs,
+			want: "Prices of the office supplies.\n\n" + prices + "Prices include taxes.\n\n",
+		},
+		{
+			name: "no rules", mode: "lines", texts: stream,
+			want: "Prices of the office supplies.\n\nItem Qty Price Pen 2 1.50 Notebook 10 3.20 Stapler 1 7.90\n\n" +
+				"Prices include taxes.\n\n1\\. First step of the list 2. Second step 3. Third and last step\n\n",
+		},
+		{
+			name: "aligned text", mode: "stream", texts: stream,
+			want: "Prices of the office supplies.\n\n" + prices +
+				"Prices include taxes.\n\n1\\. First step of the list 2. Second step 3. Third and last step\n\n",
+		},
+	}
+	defer func() { PdfPageMarkers, PdfTables = false, "lines" }()
+	PdfPageMarkers = true
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			PdfTables = tt.mode
+			result, err := ExtractTextFromPDF(buildPdf(t, tt.texts))
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			want := "<!-- Page 1 -->\n\n" + tt.want
+			if result != want {
+				t.Errorf("expected %q, got %q", want, result)
+			}
+		})
+	}
+
+	PdfTables = "stream"
+	result, err := ExtractTextFromPDF("../samples/test.pdf")
+	if want := "|Colonne 1|Colonne 2|Colonne 3|\n"; err != nil || !strings.Contains(result, want) {
+		t.Errorf("expected contains %q, got %q (%v)", want, result, err)
+	}
+
+	PdfTables = "grid"
+	if _, err := ExtractTextFromPDF(buildPdf(t, grid)); err == nil {
+		t.Errorf("expected an error for an unknown tables detection")
+	}
+}
+
+// TestExtractTextFromPDF_RunningLines test headers, footers and page numbers are removed and
+// paragraphs are joined across lines and pages
+func TestExtractTextFromPDF_RunningLines(t *testing.T) {
+	running := func(n int) []pdfText {
+		return []pdfText{
+			{"F1", 9, 72, 770, "ACME annual report 2024"},
+			{"F1", 9, 300, 40, fmt.Sprintf("Page %d of 3", n)},
+		}
+	}
+	pdfFile := buildPdf(t,
+		append(running(1),
+			pdfText{"F2", 16, 72, 720, "Introduction"},
+			pdfText{"F1", 10, 72, 696, "The company has grown and this para-"},
+			pdfText{"F1", 10, 72, 684, "graph goes on at the top of the"},
+		),
+		append(running(2),
+			pdfText{"F1", 10, 72, 720, "next page without a break."},
+			pdfText{"F1", 10, 72, 696, "A well-"},
+			pdfText{"F1", 10, 72, 684, "Known second paragraph."},
+		),
+		append(running(3),
+			pdfText{"F1", 10, 72, 720, "Last page of the report."},
+			pdfText{"F1", 10, 300, 700, "12"},
+		),
+	)
+	want := "# Page 1\n\n## Introduction\n\n" +
+		"The company has grown and this paragraph goes on at the top of the next page without a break.\n\n" +
+		"# Page 2\n\nA well- Known second paragraph.\n\n" +
+		"# Page 3\n\nLast page of the report.\n\n12\n\n"
+	result, err := ExtractTextFromPDF(pdfFile)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result != want {
+		t.Errorf("expected %q, got %q", want, result)
+	}
+}
+
+// TestExtractTextFromPDF_Outline test headings and table of contents from the outline, and links
+func TestExtractTextFromPDF_Outline(t *testing.T) {
+	pdfFile := buildPdfDoc(t, pdfDoc{
+		pages: [][]pdfText{{
+			{"F2", 18, 72, 720, "1 Introduction"},
+			{"F1", 10, 72, 700, "See the results for details and visit"},
+			{"F1", 10, 72, 688, "the website."},
+		}, {
+			{"F2", 18, 72, 720, "2 Results"},
+			{"F2", 12, 72, 690, "2.1 Sales of the"},
+			{"F2", 12, 72, 676, "year"},
+			{"F1", 10, 72, 650, "Sales grew."},
+			{"F2", 14, 72, 620, "Font heading"},
+			{"F1", 10, 72, 600, "Back to the top of page one."},
+		}},
+		links: [][]pdfLink{{
+			{[4]float64{110, 695, 150, 710}, "/A << /S /GoTo /D [8 0 R /XYZ 0 740 0] >>"},
+			{[4]float64{90, 683, 135, 698}, "/A << /S /URI /URI (https://example.com) >>"},
+		}, {
+			{[4]float64{70, 595, 220, 610}, "/Dest [6 0 R /Fit]"},
+		}},
+		outline: []pdfItem{
+			{title: "1 Introduction", dest: "/Dest [6 0 R /XYZ 0 740 0]"},
+			{title: "2 Results", dest: "/A << /S /GoTo /D [8 0 R /XYZ 0 740 0] >>", children: []pdfItem{
+				{title: "2.1 Sales of the year", dest: "/Dest [8 0 R /XYZ 0 705 0]"},
+			}},
+			{title: "Appendix", dest: "/Dest /appendix"},
+		},
+		catalog: "/Dests << /appendix [8 0 R /XYZ 0 560 0] >>",
+	})
+	defer func() { PdfPageMarkers, PdfToc = false, false }()
+	PdfPageMarkers, PdfToc = true, true
+
+	want := "- [1 Introduction](#1-introduction)\n- [2 Results](#2-results)\n" +
+		"  - [2.1 Sales of the year](#21-sales-of-the-year)\n- [Appendix](#appendix)\n\n" +
+		"<!-- Page 1 -->\n\n<a id=\"page-1\"></a>\n\n# 1 Introduction\n\n" +
+		"See the [results](#2-results) for details and visit the [website.](https://example.com)\n\n" +
+		"<!-- Page 2 -->\n\n# 2 Results\n\n## 2.1 Sales of the year\n\nSales grew.\n\n### Font heading\n\n" +
+		"[Back to the top of page one.](#page-1)\n\n# Appendix\n\n"
+	result, err := ExtractTextFromPDF(pdfFile)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result != want {
+		t.Errorf("expected %q, got %q", want, result)
+	}
+}
+
+// TestExtractTextFromPDF_OutlineLoop test a malformed outline with items linked in a loop, without destinations
+func TestExtractTextFromPDF_OutlineLoop(t *testing.T) {
+	pdfFile := buildPdfDoc(t, pdfDoc{
+		pages: [][]pdfText{{{"F1", 11, 72, 720, "Text."}}},
+		objects: []string{
+			"<< /Type /Outlines /First 9 0 R /Last 10 0 R >>",
+			"<< /Title (A) /Parent 8 0 R /Next 10 0 R /First 9 0 R >>",
+			"<< /Title (B) /Parent 8 0 R /Next 9 0 R >>",
+		},
+		catalog: "/Outlines 8 0 R",
+	})
+	done := make(chan error, 1)
+	go func() {
+		_, err := ExtractTextFromPDF(pdfFile)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the outline loop to end")
+	}
+}
+
+// TestPdf2md_Images test images are saved at their position, repeated logos are dropped
+func TestPdf2md_Images(t *testing.T) {
+	photo := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	var jpg bytes.Buffer
+	if err := jpeg.Encode(&jpg, photo, nil); err != nil {
+		t.Fatal(err)
+	}
+	var pixels bytes.Buffer
+	zw := zlib.NewWriter(&pixels)
+	zw.Write([]byte{255, 0, 0, 0, 0, 255})
+	zw.Close()
+	stream := func(dict, data string) string {
+		return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+	}
+
+	pdfFile := buildPdfDoc(t, pdfDoc{
+		pages: [][]pdfText{{
+			{text: "q 40 0 0 40 72 740 cm /Logo Do Q"},
+			{"F1", 10, 72, 700, "Figure one shows the trend."},
+			{text: "q 8 0 0 8 300 700 cm /Logo Do Q"},
+			{text: "q 200 0 0 100 72 560 cm /Im1 Do Q"},
+			{"F1", 10, 72, 540, "After the figure."},
+		}, {
+			{text: "q 40 0 0 40 72 740 cm /Logo Do Q"},
+			{"F1", 10, 72, 700, "Second page."},
+			{text: "q 1 0 0 1 72 600 cm /Fm1 Do Q"},
+		}},
+		objects: []string{
+			stream("/Type /XObject /Subtype /Image /Width 4 /Height 4 /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /DCTDecode", jpg.String()),
+			stream("/Type /XObject /Subtype /Image /Width 2 /Height 1 /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /FlateDecode", pixels.String()),
+			stream("/Type /XObject /Subtype /Image /Width 2 /Height 2 /ColorSpace /DeviceGray /BitsPerComponent 8", "\x00\x40\x80\xff"),
+			stream("/Type /XObject /Subtype /Form /BBox [0 0 100 50] /Resources << /XObject << /Im2 11 0 R >> >>", "q 100 0 0 50 0 0 cm /Im2 Do Q"),
+		},
+		xobject: "/Im1 10 0 R /Logo 12 0 R /Fm1 13 0 R",
+	})
+	defer func() { PdfPageMarkers = false }()
+	PdfPageMarkers = true
+
+	assetsDir := filepath.Join(t.TempDir(), "test-assets")
+	result, err := Pdf2md(pdfFile, assetsDir)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := "<!-- Page 1 -->\n\nFigure one shows the trend.\n\n![](test-assets/page-1-image-2.jpg)\n\nAfter the figure.\n\n" +
+		"<!-- Page 2 -->\n\nSecond page.\n\n![](test-assets/page-2-image-1.png)\n\n"
+	if result != want {
+		t.Errorf("expected %q, got %q", want, result)
+	}
+
+	// JPEG images are copied, other images are converted to PNG
+	files, _ := os.ReadDir(assetsDir)
+	if len(files) != 2 {
+		t.Errorf("expected 2 files in the assets folder, got %d", len(files))
+	}
+	b, err := os.ReadFile(filepath.Join(assetsDir, "page-1-image-2.jpg"))
+	if err != nil || !bytes.Equal(b, jpg.Bytes()) {
+		t.Errorf("expected the JPEG image to be copied, got %v", err)
+	}
+	f, err := os.Open(filepath.Join(assetsDir, "page-2-image-1.png"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got := color.RGBAModel.Convert(img.At(1, 0)); got != (color.RGBA{0, 0, 255, 255}) {
+		t.Errorf("expected a blue pixel, got %v", got)
+	}
+
+	// without assets folder, images are ignored
+	if result, _ = ExtractTextFromPDF(pdfFile); strings.Contains(result, "![") {
+		t.Errorf("expected no image, got %q", result)
+	}
+
+	// an image with a wrong length is lost, not the page
+	badLength := buildPdfDoc(t, pdfDoc{
+		pages: [][]pdfText{{
+			{"F1", 10, 72, 700, "Broken figure."},
+			{text: "q 200 0 0 100 72 560 cm /Im1 Do Q"},
+		}},
+		objects: []string{
+			"<< /Type /XObject /Subtype /Image /Width 4 /Height 4 /ColorSpace /DeviceRGB /BitsPerComponent 8 " +
+				"/Filter /DCTDecode /Length 4000000000000 >>\nstream\n" + jpg.String() + "\nendstream",
+		},
+		xobject: "/Im1 10 0 R",
+	})
+	result, err = Pdf2md(badLength, filepath.Join(t.TempDir(), "test-assets"))
+	if err != nil || result != "<!-- Page 1 -->\n\nBroken figure.\n\n" {
+		t.Errorf("expected the text without the image, got %q, %v", result, err)
+	}
+
+	// the samples are not decoded beyond the size of the image, too large
+	// images are lost
+	var bomb bytes.Buffer
+	zw = zlib.NewWriter(&bomb)
+	zw.Write(make([]byte, 1<<20))
+	zw.Close()
+	for _, size := range []string{"/Width 2 /Height 2", "/Width 100000 /Height 100000", "/Width -2 /Height 2"} {
+		bombFile := buildPdfDoc(t, pdfDoc{
+			pages:   [][]pdfText{{{"F1", 10, 72, 700, "Figure."}}},
+			objects: []string{stream("/Type /XObject /Subtype /Image "+size+" /ColorSpace /DeviceGray /BitsPerComponent 8 /Filter /FlateDecode", bomb.String())},
+			xobject: "/Im1 8 0 R",
+		})
+		data, err := os.ReadFile(bombFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := openPdf(bytes.NewReader(data), int64(len(data)), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, ext, err := r.imageData(r.reader.Page(1).Resources().Key("XObject").Key("Im1"))
+		if size == "/Width 2 /Height 2" {
+			if err != nil || ext != ".png" {
+				t.Errorf("expected a PNG image, got %q, %v", ext, err)
+			}
+			continue
+		}
+		if err == nil || b != nil {
+			t.Errorf("expected an error for %s, got %v", size, err)
+		}
+	}
+}
+
+// TestPageRange test the lists of pages and ranges of --pages
+func TestPageRange(t *testing.T) {
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{"", "[1 2 3 4 5 6 7 8 9 10]"},
+		{"3-5", "[3 4 5]"},
+		{"8-", "[8 9 10]"},
+		{"-2", "[1 2]"},
+		{"7, 1,2-3,2", "[1 2 3 7]"},
+		{"9-20", "[9 10]"},
+		{"0-3", "error"},
+		{"11", "error"},
+		{"5-3", "error"},
+		{"a-b", "error"},
+	}
+	for _, test := range tests {
+		pages, err := pageRange(test.spec, 10)
+		got := fmt.Sprint(pages)
+		if err != nil {
+			got = "error"
+		}
+		if got != test.want {
+			t.Errorf("expected %q, got %q for %q", test.want, got, test.spec)
+		}
+	}
+}
+
+// TestExtractTextFromPDF_Pages test the conversion of some pages
+func TestExtractTextFromPDF_Pages(t *testing.T) {
+	var pages [][]pdfText
+	for _, text := range []string{"One", "Two", "Three", "Four"} {
+		pages = append(pages, []pdfText{{"F1", 10, 72, 720, text + "."}})
+	}
+	pdfFile := buildPdf(t, pages...)
+	defer func() { PdfPages = "" }()
+
+	PdfPages = "2-3"
+	want := "# Page 2\n\nTwo.\n\n# Page 3\n\nThree.\n\n"
+	result, err := ExtractTextFromPDF(pdfFile)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result != want {
+		t.Errorf("expected %q, got %q", want, result)
+	}
+
+	PdfPages = "5-"
+	if _, err := ExtractTextFromPDF(pdfFile); err == nil {
+		t.Errorf("expected an error for pages outside of the document")
+	}
+}
+
+// TestExtractTextFromPDF_Malformed test broken cross-reference tables and pages are errors, not panics
+func TestExtractTextFromPDF_Malformed(t *testing.T) {
+	pdfFile := buildPdf(t,
+		[]pdfText{{"F1", 10, 72, 720, "First page."}},
+		[]pdfText{{text: "BT /F1 10 Tf 72 Td (Broken) Tj ET"}},
+		[]pdfText{{"F1", 10, 72, 720, "Third page."}},
+	)
+	data, err := os.ReadFile(pdfFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	xref := bytes.Index(data, []byte("xref\n"))
+	write := func(b []byte) string {
+		name := filepath.Join(t.TempDir(), "malformed.pdf")
+		if err := os.WriteFile(name, b, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return name
+	}
+
+	// the text of the malformed page is lost, not the document
+	want := "# Page 1\n\nFirst page.\n\n# Page 3\n\nThird page.\n\n"
+	tests := []struct {
+		name string
+		file string
+	}{
+		{"valid xref", pdfFile},
+		{"wrong offsets", write(append(append([]byte{}, data[:xref]...),
+			bytes.ReplaceAll(data[xref:], []byte(" 00000 n"), []byte("0 00000 n"))...))},
+		{"wrong startxref", write(bytes.Replace(data, []byte(fmt.Sprintf("startxref\n%d", xref)), []byte("startxref\n12"), 1))},
+		{"no xref", write(append(data[:xref:xref], "%%EOF\n"...))},
+		{"no xref, objects across the chunks", write(slices.Concat(data[:9], []byte("%"+strings.Repeat("x", repairChunk-13)+"\n"),
+			data[9:xref], []byte("%%EOF\n")))},
+	}
+	for _, test := range tests {
+		result, err := ExtractTextFromPDF(test.file)
+		if err != nil {
+			t.Errorf("expected no error, got %v for %s", err, test.name)
+		}
+		if result != want {
+			t.Errorf("expected %q, got %q for %s", want, result, test.name)
+		}
+	}
+
+	// pages are identified by their object
+	file, err := os.Open(pdfFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	r, err := openPdf(file, int64(len(data)), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := objectRef(r.reader.Page(2).V); got != "8 0 R" || r.pages[got] != 2 {
+		t.Errorf("expected page 2 to be %q, got %q", "8 0 R", got)
+	}
+
+	// without a readable page, the document is an error
+	broken := buildPdf(t, []pdfText{{text: "BT /F1 10 Tf 72 Td (Broken) Tj ET"}})
+	if _, err := ExtractTextFromPDF(broken); err == nil || !strings.Contains(err.Error(), "bad Td") {
+		t.Errorf("expected an error for the malformed page, got %v", err)
+	}
+	if _, err := ExtractTextFromPDF(write([]byte("%PDF-1.4\nnot a PDF\n%%EOF\n"))); err == nil {
+		t.Errorf("expected an error for a file without objects")
+	}
+}
+
+// TestPdfLibraryInternals pin the behaviour of the pdf library used by
+// objectRef, which reads the reference of a value by reflection, and by
+// streamOffset, which parses the description of a stream
+func TestPdfLibraryInternals(t *testing.T) {
+	pdfFile := buildPdf(t, []pdfText{{"F1", 10, 72, 720, "First page."}})
+	data, err := os.ReadFile(pdfFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(pdfFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	r, err := openPdf(file, int64(len(data)), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the same object read twice has the same reference, direct values have the
+	// reference of their object and values outside of any object are described
+	page := r.reader.Page(1).V
+	kid := r.reader.Trailer().Key("Root").Key("Pages").Key("Kids").Index(0)
+	if got, kidRef := objectRef(page), objectRef(kid); got != "6 0 R" || kidRef != got {
+		t.Errorf("expected page and kid to be %q, got %q and %q", "6 0 R", got, kidRef)
+	}
+	if got := objectRef(page.Key("MediaBox")); got != "6 0 R" {
+		t.Errorf("expected a direct value to be %q, got %q", "6 0 R", got)
+	}
+	if got, want := objectRef(r.reader.Trailer()), r.reader.Trailer().String(); got != want {
+		t.Errorf("expected the trailer to be %q, got %q", want, got)
+	}
+
+	// the offset of a stream is the first byte after the stream keyword
+	contents := page.Key("Contents")
+	at := bytes.Index(data, []byte("7 0 obj\n"))
+	want := int64(at + bytes.Index(data[at:], []byte("stream\n")) + len("stream\n"))
+	if !strings.HasSuffix(contents.String(), fmt.Sprintf("@%d", want)) {
+		t.Errorf("expected the stream description to end with @%d, got %q", want, contents.String())
+	}
+	if got, err := r.streamOffset(contents); err != nil || got != want {
+		t.Errorf("expected offset %d, got %d (%v)", want, got, err)
+	}
+	raw, err := r.rawStream(contents)
+	if err != nil || !strings.Contains(string(raw), "(First page.) Tj") {
+		t.Errorf("expected the raw content stream, got %q (%v)", raw, err)
+	}
+}
+
+// TestExtractTextFromPDF_Encrypted test the password of encrypted files
+func TestExtractTextFromPDF_Encrypted(t *testing.T) {
+	pdfFile := buildPdfDoc(t, pdfDoc{
+		pages:     [][]pdfText{{{"F1", 10, 72, 720, "Confidential text."}}},
+		encrypted: true,
+		password:  "secret",
+	})
+	defer func() { PdfPassword = "" }()
+
+	if _, err := ExtractTextFromPDF(pdfFile); err == nil || !strings.Contains(err.Error(), "--pdf-password") {
+		t.Errorf("expected a password error, got %v", err)
+	}
+	PdfPassword = "wrong"
+	if _, err := ExtractTextFromPDF(pdfFile); err == nil {
+		t.Errorf("expected an error for a wrong password")
+	}
+	PdfPassword = "secret"
+	result, err := ExtractTextFromPDF(pdfFile)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if want := "Confidential text.\n\n"; !strings.HasSuffix(result, want) {
+		t.Errorf("expected %q, got %q", want, result)
+	}
+
+	// an empty user password opens the file without password
+	pdfFile = buildPdfDoc(t, pdfDoc{
+		pages:     [][]pdfText{{{"F1", 10, 72, 720, "Restricted text."}}},
+		encrypted: true,
+	})
+	if result, err := ExtractTextFromPDF(pdfFile); err != nil || !strings.Contains(result, "Restricted text.") {
+		t.Errorf("expected the text, got %q, %v", result, err)
+	}
+}
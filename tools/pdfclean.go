@@ -0,0 +1,127 @@
+package tools
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// pageNumbers matches the numbers of the running headers and footers
+var pageNumbers = regexp.MustCompile(`\d+`)
+
+// runningKey identifies a running line by its position and its text, without the page numbers.
+func runningKey(line pdfLine) string {
+	return fmt.Sprintf("%.0f %s%s", line.y, pageNumbers.ReplaceAllString(line.text(), "#"), line.source)
+}
+
+// edgeLines returns the lines at the top and at the bottom of a page, where
+// the headers and footers are.
+func edgeLines(lines []pdfLine) map[int]bool {
+	index := make([]int, len(lines))
+	for i := range index {
+		index[i] = i
+	}
+	sort.SliceStable(index, func(a, b int) bool {
+		return lines[index[a]].y > lines[index[b]].y
+	})
+	if len(index) > 6 {
+		index = append(index[:3], index[len(index)-3:]...)
+	}
+	edges := make(map[int]bool)
+	for _, i := range index {
+		if lines[i].table == nil && lines[i].markdown == "" {
+			edges[i] = true
+		}
+	}
+	return edges
+}
+
+// pdfRunning finds the headers, footers and page numbers: the lines found at
+// the same position on most pages
+type pdfRunning struct {
+	count map[string]int // pages by running key
+	pages int
+}
+
+// newPdfRunning returns an empty count of the lines of the pages.
+func newPdfRunning() *pdfRunning {
+	return &pdfRunning{count: make(map[string]int)}
+}
+
+// add counts the lines at the edges of a page, and returns their keys.
+func (r *pdfRunning) add(lines []pdfLine) map[string]bool {
+	r.pages++
+	seen := make(map[string]bool)
+	for i := range edgeLines(lines) {
+		seen[runningKey(lines[i])] = true
+	}
+	for key := range seen {
+		r.count[key]++
+	}
+	return seen
+}
+
+// running reports whether the lines of a key are found on most pages, once
+// all the pages are counted.
+func (r *pdfRunning) running(key string) bool {
+	n := r.count[key]
+	return r.pages >= 2 && n >= 2 && 2*n > r.pages
+}
+
+// remove returns the lines of a page without its running lines, once all
+// the pages are counted.
+func (r *pdfRunning) remove(lines []pdfLine) []pdfLine {
+	if r.pages < 2 {
+		return lines
+	}
+	edges := edgeLines(lines)
+	var kept []pdfLine
+	for i, line := range lines {
+		if edges[i] && r.running(runningKey(line)) {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return kept
+}
+
+// hyphenated reports whether a word is split at the end of a line, before
+// the end of the word on the next line.
+func hyphenated(end, next string) bool {
+	start := strings.TrimRight(end, "-\u00ad")
+	last, _ := utf8.DecodeLastRuneInString(start)
+	first, _ := utf8.DecodeRuneInString(next)
+	return start != end && unicode.IsLetter(last) && unicode.IsLower(first)
+}
+
+// paragraphWords returns the words of the lines of a paragraph, joining the
+// words split at the end of the lines.
+func paragraphWords(lines []pdfLine) []pdfWord {
+	var words []pdfWord
+	for i, line := range lines {
+		for j, w := range line.words {
+			if n := len(words); i > 0 && j == 0 && n > 0 && hyphenated(words[n-1].text, w.text) {
+				words[n-1].text = strings.TrimRight(words[n-1].text, "-\u00ad") + w.text
+				continue
+			}
+			words = append(words, w)
+		}
+	}
+	return words
+}
+
+// continues reports whether a paragraph goes on with the first paragraph of
+// the next page: it does not end a sentence and the next one starts in lowercase.
+func (s *pdfStats) continues(last, next []pdfLine) bool {
+	if last[0].block() || next[0].block() || roundSize(last[0].size) != roundSize(next[0].size) ||
+		s.headingLevel(last) > 0 || s.headingLevel(next) > 0 {
+		return false
+	}
+	words := last[len(last)-1].words
+	end, _ := utf8.DecodeLastRuneInString(strings.TrimRight(words[len(words)-1].text, `"')]»”’`))
+	first, _ := utf8.DecodeRuneInString(next[0].words[0].text)
+	return !strings.ContainsRune(".!?:;…", end) && unicode.IsLower(first)
+}
@@ -0,0 +1,204 @@
+package tools
+
+import (
+	"math"
+)
+
+// pdfGutter is the blank space between two columns of a page
+type pdfGutter struct {
+	x0, x1 float64
+}
+
+// middle returns the center of the gutter.
+func (g pdfGutter) middle() float64 {
+	return (g.x0 + g.x1) / 2
+}
+
+// crosses reports whether a word is drawn over the gutter, words of the
+// columns may overlap its edges.
+func (g pdfGutter) crosses(w pdfWord) bool {
+	return w.x0 < g.middle() && w.x1 > g.middle()
+}
+
+// pdfMaxWidth is the largest width of a page in points, 200 inches in the PDF
+// specification. Text areas are clamped to it, whatever the positions of the glyphs.
+const pdfMaxWidth = 14400
+
+// clampX returns a position limited to the largest page, NaN at 0.
+func clampX(x float64) float64 {
+	if math.IsNaN(x) {
+		return 0
+	}
+	return min(max(x, -pdfMaxWidth), pdfMaxWidth)
+}
+
+// textArea returns the horizontal extent of the rows, at most the largest
+// page, and their mean font size.
+func textArea(rows []pdfLine) (minX, maxX, size float64) {
+	minX, maxX = clampX(rows[0].x0), clampX(rows[0].x1)
+	for _, row := range rows {
+		minX, maxX = min(minX, clampX(row.x0)), max(maxX, clampX(row.x1))
+		size += row.size
+	}
+	return minX, min(maxX, minX+pdfMaxWidth), size / float64(len(rows))
+}
+
+// cover marks the points of the text area covered by the words of a row.
+func cover(covered []bool, words []pdfWord, minX float64) {
+	for _, w := range words {
+		from := max(int(clampX(w.x0)-minX), 0)
+		to := min(int(math.Ceil(clampX(w.x1)-minX)), len(covered))
+		for x := from; x < to; x++ {
+			covered[x] = true
+		}
+	}
+}
+
+// findGutters finds the columns of a page from the histogram of the horizontal
+// positions covered by the rows: a gutter is a band crossed by a few rows only,
+// like titles spanning the columns. Columns must look like text: at least 3
+// lines of several words, filling the column.
+func findGutters(rows []pdfLine) []pdfGutter {
+	if len(rows) < 3 {
+		return nil
+	}
+	minX, maxX, size := textArea(rows)
+
+	// rows covering each point of the text area
+	coverage := make([]int, int(math.Ceil(maxX-minX))+1)
+	covered := make([]bool, len(coverage))
+	for _, row := range rows {
+		clear(covered)
+		cover(covered, row.words, minX)
+		for x, ok := range covered {
+			if ok {
+				coverage[x]++
+			}
+		}
+	}
+	threshold := len(rows) / 5
+	var gutters []pdfGutter
+	start := -1
+	for x := 0; x <= len(coverage); x++ {
+		if x < len(coverage) && coverage[x] <= threshold {
+			if start < 0 {
+				start = x
+			}
+			continue
+		}
+		// bands on the edges of the text area are margins
+		if start > 0 && x < len(coverage) && float64(x-start) >= size {
+			gutters = append(gutters, pdfGutter{minX + float64(start), minX + float64(x)})
+		}
+		start = -1
+	}
+
+	// gutters between columns which are not text are dropped, the narrowest first
+	for len(gutters) > 0 && !textColumns(rows, gutters, minX, maxX) {
+		narrowest := 0
+		for i, g := range gutters {
+			if g.x1-g.x0 < gutters[narrowest].x1-gutters[narrowest].x0 {
+				narrowest = i
+			}
+		}
+		gutters = append(gutters[:narrowest], gutters[narrowest+1:]...)
+	}
+	return gutters
+}
+
+// textColumns reports whether all the columns separated by the gutters contain text.
+func textColumns(rows []pdfLine, gutters []pdfGutter, minX, maxX float64) bool {
+	lines := make([]int, len(gutters)+1)
+	words := make([]int, len(gutters)+1)
+	fill := make([]float64, len(gutters)+1)
+	for _, row := range rows {
+		segments, spanning := splitRow(row, gutters)
+		if spanning || row.block() {
+			continue
+		}
+		for col, seg := range segments {
+			if len(seg.words) == 0 {
+				continue
+			}
+			left, right := minX, maxX
+			if col > 0 {
+				left = gutters[col-1].x1
+			}
+			if col < len(gutters) {
+				right = gutters[col].x0
+			}
+			lines[col]++
+			words[col] += len(seg.words)
+			fill[col] += (seg.x1 - seg.x0) / (right - left)
+		}
+	}
+	for col := range lines {
+		if lines[col] < 3 || words[col] < 3*lines[col] || fill[col] < 0.5*float64(lines[col]) {
+			return false
+		}
+	}
+	return true
+}
+
+// splitRow splits a row in a segment by column. It reports a row spanning
+// columns when a word, a table or an image is drawn over a gutter.
+func splitRow(row pdfLine, gutters []pdfGutter) ([]pdfLine, bool) {
+	segments := make([]pdfLine, len(gutters)+1)
+	if row.block() {
+		col := 0
+		for _, g := range gutters {
+			if row.x0 < g.middle() && row.x1 > g.middle() {
+				return nil, true
+			}
+			if row.x0 >= g.middle() {
+				col++
+			}
+		}
+		segments[col] = row
+		return segments, false
+	}
+	for _, w := range row.words {
+		col := 0
+		for _, g := range gutters {
+			if g.crosses(w) {
+				return nil, true
+			}
+			if w.x0 >= g.middle() {
+				col++
+			}
+		}
+		segments[col].add(w)
+	}
+	return segments, false
+}
+
+// readingOrder sorts the rows of a page column by column. Rows spanning the
+// columns, like titles, end the columns above them.
+func readingOrder(rows []pdfLine, gutters []pdfGutter) []pdfLine {
+	if len(gutters) == 0 {
+		return rows
+	}
+	var lines []pdfLine
+	columns := make([][]pdfLine, len(gutters)+1)
+	flush := func() {
+		for col := range columns {
+			lines = append(lines, columns[col]...)
+			columns[col] = nil
+		}
+	}
+	for _, row := range rows {
+		segments, spanning := splitRow(row, gutters)
+		if spanning {
+			flush()
+			lines = append(lines, row)
+			continue
+		}
+		for col, seg := range segments {
+			if len(seg.words) > 0 || seg.block() {
+				columns[col] = append(columns[col], seg)
+			}
+		}
+	}
+	flush()
+	return lines
+}
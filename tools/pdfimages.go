@@ -0,0 +1,406 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+	log "github.com/sirupsen/logrus"
+)
+
+// PdfDescribeImages adds a description of the images of PDF files at the end
+// of the markdown, built by the vision model
+var PdfDescribeImages = false
+
+// pdfImages saves the images of the pages of a PDF document
+type pdfImages struct {
+	extractor PDFExtractor
+	dir       string
+	links     map[string]string // link by image key
+	keys      map[string]string // image key by link
+	data      map[string][]byte // image by link, of the current page
+	saved     map[string]bool   // links of the written images
+}
+
+// newPdfImages returns the images of a document saved in dir, an empty dir
+// disables the extraction.
+func newPdfImages(extractor PDFExtractor, dir string) *pdfImages {
+	return &pdfImages{
+		extractor: extractor, dir: dir, links: make(map[string]string), keys: make(map[string]string),
+		data: make(map[string][]byte), saved: make(map[string]bool),
+	}
+}
+
+// pageLines returns the images drawn on a page as lines placed at the top of
+// the images, named by page. Until extract, the lines are placeholders and
+// the images are not read.
+func (pi *pdfImages) pageLines(images []PdfImage, number int, extract bool) []pdfLine {
+	if pi.dir == "" {
+		return nil
+	}
+	var lines []pdfLine
+	count := 0
+	for _, img := range images {
+		link, ok := pi.links[img.Key]
+		if !extract {
+			link = img.Key
+		} else if !ok {
+			b, ext, err := pi.extractor.Image(img.Key)
+			if err != nil {
+				log.Infof("Error %s when extracting an image of page %d", err, number)
+				continue
+			}
+			count++
+			link = fmt.Sprintf("%s/page-%d-image-%d%s", filepath.Base(pi.dir), number, count, ext)
+			pi.links[img.Key], pi.keys[link], pi.data[link] = link, img.Key, b
+		}
+		lines = append(lines, pdfLine{image: link, source: img.Key, x0: img.Rect.X0, x1: img.Rect.X1, y: img.Rect.Y1})
+	}
+	return lines
+}
+
+// matrix is a PDF transformation matrix [a b c d e f]
+type matrix [6]float64
+
+// mul returns the transformation m then n.
+func (m matrix) mul(n matrix) matrix {
+	return matrix{
+		m[0]*n[0] + m[1]*n[2], m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2], m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4], m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+// pageImages returns the images drawn on a page. Small images like bullets
+// are ignored.
+func (r *pdfReader) pageImages(page pdf.Page, number int) (images []PdfImage) {
+	defer func() {
+		// the images of a malformed page are lost, not the text
+		if err := recover(); err != nil {
+			log.Infof("Error %v when reading the images of page %d", err, number)
+		}
+	}()
+	contents := page.V.Key("Contents")
+	if contents.Kind() != pdf.Array {
+		r.draw(contents, page.Resources(), matrix{1, 0, 0, 1, 0, 0}, &images, 0)
+	}
+	for i := 0; i < contents.Len(); i++ {
+		r.draw(contents.Index(i), page.Resources(), matrix{1, 0, 0, 1, 0, 0}, &images, 0)
+	}
+	return images
+}
+
+// draw interprets a content stream and adds the images it draws, in forms too.
+func (r *pdfReader) draw(strm, resources pdf.Value, ctm matrix, images *[]PdfImage, depth int) {
+	var stack []matrix
+	pdf.Interpret(strm, func(stk *pdf.Stack, op string) {
+		args := make([]pdf.Value, stk.Len())
+		for i := len(args) - 1; i >= 0; i-- {
+			args[i] = stk.Pop()
+		}
+		switch op {
+		case "q":
+			stack = append(stack, ctm)
+		case "Q":
+			if n := len(stack); n > 0 {
+				ctm, stack = stack[n-1], stack[:n-1]
+			}
+		case "cm":
+			if len(args) == 6 {
+				var m matrix
+				for i := range m {
+					m[i] = args[i].Float64()
+				}
+				ctm = m.mul(ctm)
+			}
+		case "Do":
+			if len(args) != 1 {
+				return
+			}
+			xobj := resources.Key("XObject").Key(args[0].Name())
+			switch xobj.Key("Subtype").Name() {
+			case "Image":
+				if img, ok := r.image(xobj, ctm); ok {
+					*images = append(*images, img)
+				}
+			case "Form":
+				if depth < 5 {
+					form := ctm
+					if m := xobj.Key("Matrix"); m.Len() == 6 {
+						var fm matrix
+						for i := range fm {
+							fm[i] = m.Index(i).Float64()
+						}
+						form = fm.mul(ctm)
+					}
+					res := xobj.Key("Resources")
+					if res.IsNull() {
+						res = resources
+					}
+					r.draw(xobj, res, form, images, depth+1)
+				}
+			}
+		}
+	})
+}
+
+// image returns an image drawn in the unit square transformed by ctm.
+func (r *pdfReader) image(xobj pdf.Value, ctm matrix) (PdfImage, bool) {
+	// corners of the unit square
+	x0, x1 := min(ctm[4], ctm[4]+ctm[0]+ctm[2]), max(ctm[4], ctm[4]+ctm[0]+ctm[2])
+	y0, y1 := min(ctm[5], ctm[5]+ctm[1]+ctm[3]), max(ctm[5], ctm[5]+ctm[1]+ctm[3])
+	if x1-x0 < 16 || y1-y0 < 16 || xobj.Key("ImageMask").Bool() {
+		return PdfImage{}, false
+	}
+	key := xobj.String()
+	r.images[key] = xobj
+	return PdfImage{Key: key, Rect: PdfRect{X0: x0, Y0: y0, X1: x1, Y1: y1}}, true
+}
+
+// streamOffset returns the offset of the data of a stream in the file. The
+// library has no reader of undecoded streams and only gives the offset in
+// the description of the stream, "<<dict>>@offset": the offset is checked
+// against the stream keyword before the data.
+func (r *pdfReader) streamOffset(strm pdf.Value) (int64, error) {
+	desc := strm.String()
+	at := strings.LastIndex(desc, "@")
+	offset, err := strconv.ParseInt(desc[at+1:], 10, 64)
+	if at < 0 || err != nil || offset < 8 || offset > r.size {
+		return 0, fmt.Errorf("no stream offset in %.40s", desc)
+	}
+	keyword := make([]byte, 8)
+	if _, err := r.file.ReadAt(keyword, offset-8); err != nil {
+		return 0, err
+	}
+	if !bytes.Contains(keyword, []byte("stream")) {
+		return 0, fmt.Errorf("no stream at offset %d", offset)
+	}
+	return offset, nil
+}
+
+// rawStream returns the data of a stream without decoding it, its length is
+// checked against the size of the file before reading.
+func (r *pdfReader) rawStream(strm pdf.Value) ([]byte, error) {
+	if r.encrypted {
+		return nil, fmt.Errorf("encrypted stream")
+	}
+	offset, err := r.streamOffset(strm)
+	if err != nil {
+		return nil, err
+	}
+	length := strm.Key("Length").Int64()
+	if length < 0 || length > r.size-offset {
+		return nil, fmt.Errorf("stream length %d beyond the end of the file", length)
+	}
+	b := make([]byte, length)
+	if _, err := r.file.ReadAt(b, offset); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// imageData returns an image as a JPEG file for DCT images, or as a PNG file
+// for the raw pixels of the other images.
+func (r *pdfReader) imageData(xobj pdf.Value) (b []byte, ext string, err error) {
+	filter := xobj.Key("Filter")
+	if filter.Kind() == pdf.Array && filter.Len() == 1 {
+		filter = filter.Index(0)
+	}
+	switch filter.Name() {
+	case "DCTDecode":
+		b, err = r.rawStream(xobj)
+		if err == nil && !bytes.HasPrefix(b, []byte{0xff, 0xd8}) {
+			err = fmt.Errorf("not a JPEG image")
+		}
+		return b, ".jpg", err
+	case "FlateDecode", "":
+		if !filter.IsNull() && filter.Kind() != pdf.Name {
+			break
+		}
+		// the size of the samples is known before decoding, the stream is not
+		// read beyond it
+		format, err := readImageFormat(xobj)
+		if err != nil {
+			return nil, "", err
+		}
+		pixels, err := io.ReadAll(io.LimitReader(xobj.Reader(), int64(format.stride*format.height)))
+		if err != nil {
+			return nil, "", err
+		}
+		img, err := decodePixels(format, pixels)
+		if err != nil {
+			return nil, "", err
+		}
+		var buf bytes.Buffer
+		err = png.Encode(&buf, img)
+		return buf.Bytes(), ".png", err
+	}
+	return nil, "", fmt.Errorf("unsupported image filter %v", filter)
+}
+
+// colorSpace returns the number of components of a color space, and the
+// palette of indexed colors.
+func colorSpace(cs pdf.Value) (int, color.Palette, error) {
+	name := cs.Name()
+	if cs.Kind() == pdf.Array {
+		name = cs.Index(0).Name()
+	}
+	switch name {
+	case "DeviceGray", "CalGray", "G":
+		return 1, nil, nil
+	case "DeviceRGB", "CalRGB", "RGB":
+		return 3, nil, nil
+	case "DeviceCMYK", "CMYK":
+		return 4, nil, nil
+	case "ICCBased":
+		return int(cs.Index(1).Key("N").Int64()), nil, nil
+	case "Indexed", "I":
+		base, _, err := colorSpace(cs.Index(1))
+		if err != nil {
+			return 0, nil, err
+		}
+		lookup := []byte(cs.Index(3).RawString())
+		if cs.Index(3).Kind() == pdf.Stream {
+			// 256 colors of 4 components at most
+			if lookup, err = io.ReadAll(io.LimitReader(cs.Index(3).Reader(), 256*4)); err != nil {
+				return 0, nil, err
+			}
+		}
+		var palette color.Palette
+		for i := 0; i <= int(cs.Index(2).Int64()) && (i+1)*base <= len(lookup); i++ {
+			palette = append(palette, pixelColor(lookup[i*base:(i+1)*base]))
+		}
+		return 1, palette, nil
+	}
+	return 0, nil, fmt.Errorf("unsupported color space %v", cs)
+}
+
+// pixelColor returns the color of a pixel of 8 bits components.
+func pixelColor(c []byte) color.Color {
+	switch len(c) {
+	case 1:
+		return color.Gray{Y: c[0]}
+	case 3:
+		return color.RGBA{R: c[0], G: c[1], B: c[2], A: 255}
+	case 4:
+		k := 255 - int(c[3])
+		return color.RGBA{
+			R: uint8((255 - int(c[0])) * k / 255), G: uint8((255 - int(c[1])) * k / 255),
+			B: uint8((255 - int(c[2])) * k / 255), A: 255,
+		}
+	}
+	return color.Black
+}
+
+// maxImageSize limits the size of the decoded samples of an image
+const maxImageSize = 1 << 28
+
+// imageFormat is the layout of the samples of an image.
+type imageFormat struct {
+	width, height, bpc, components int
+	stride                         int // bytes by row
+	palette                        color.Palette
+}
+
+// readImageFormat reads the layout of the samples of an image. Components have
+// 8 bits, gray and indexed images may have 1, 2 or 4 bits.
+func readImageFormat(xobj pdf.Value) (imageFormat, error) {
+	width, height := xobj.Key("Width").Int64(), xobj.Key("Height").Int64()
+	bpc := int(xobj.Key("BitsPerComponent").Int64())
+	components, palette, err := colorSpace(xobj.Key("ColorSpace"))
+	if err != nil {
+		return imageFormat{}, err
+	}
+	if components <= 0 || components > 4 || (bpc != 8 && (components != 1 || bpc <= 0 || 8%bpc != 0)) {
+		return imageFormat{}, fmt.Errorf("unsupported image of %d bits per component", bpc)
+	}
+	if width <= 0 || height <= 0 || width > maxImageSize || height > maxImageSize ||
+		(width*int64(components*bpc)+7)/8*height > maxImageSize {
+		return imageFormat{}, fmt.Errorf("unsupported image of %dx%d pixels", width, height)
+	}
+	return imageFormat{
+		width: int(width), height: int(height), bpc: bpc, components: components,
+		stride: int((width*int64(components*bpc) + 7) / 8), palette: palette,
+	}, nil
+}
+
+// decodePixels builds an image from its decoded samples.
+func decodePixels(f imageFormat, pixels []byte) (image.Image, error) {
+	width, height, bpc, components, stride, palette := f.width, f.height, f.bpc, f.components, f.stride, f.palette
+	if len(pixels) < stride*height {
+		return nil, fmt.Errorf("truncated image data")
+	}
+
+	rect := image.Rect(0, 0, width, height)
+	var img interface {
+		image.Image
+		Set(x, y int, c color.Color)
+	}
+	if palette != nil {
+		img = image.NewPaletted(rect, palette)
+	} else if components == 1 {
+		img = image.NewGray(rect)
+	} else {
+		img = image.NewRGBA(rect)
+	}
+	for y := 0; y < height; y++ {
+		row := pixels[y*stride : (y+1)*stride]
+		for x := 0; x < width; x++ {
+			if bpc == 8 {
+				c := row[x*components : (x+1)*components]
+				if palette != nil {
+					img.(*image.Paletted).SetColorIndex(x, y, c[0])
+				} else {
+					img.Set(x, y, pixelColor(c))
+				}
+				continue
+			}
+			// samples packed in bytes, high bits first
+			bit := x * bpc
+			v := (row[bit/8] >> (8 - bpc - bit%8)) & (1<<bpc - 1)
+			if palette != nil {
+				img.(*image.Paletted).SetColorIndex(x, y, v)
+			} else {
+				img.Set(x, y, color.Gray{Y: v * uint8(255/(1<<bpc-1))})
+			}
+		}
+	}
+	return img, nil
+}
+
+// save writes the images used by the lines of a page in the assets folder,
+// and returns the links of the new ones. Their data is not kept.
+func (pi *pdfImages) save(lines []pdfLine) ([]string, error) {
+	defer clear(pi.data)
+	var images []string
+	for _, line := range lines {
+		if line.image == "" || pi.saved[line.image] {
+			continue
+		}
+		b, ok := pi.data[line.image]
+		if !ok {
+			// an image read on a previous page
+			var err error
+			if b, _, err = pi.extractor.Image(pi.keys[line.image]); err != nil {
+				return nil, err
+			}
+		}
+		if err := os.MkdirAll(pi.dir, 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(filepath.Join(pi.dir, filepath.Base(line.image)), b, 0644); err != nil {
+			return nil, err
+		}
+		pi.saved[line.image] = true
+		images = append(images, line.image)
+	}
+	return images, nil
+}
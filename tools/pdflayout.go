@@ -0,0 +1,175 @@
+package tools
+
+import (
+	"sort"
+	"strings"
+)
+
+// pdfWord is a word of a PDF page, built from the glyphs of a text line
+type pdfWord struct {
+	text   string
+	x0, x1 float64
+	y      float64
+	size   float64
+	bold   bool
+	italic bool
+	link   string // target of a link annotation
+}
+
+// pdfLine is a line of words sharing the same baseline
+type pdfLine struct {
+	words    []pdfWord
+	x0, x1   float64
+	y        float64
+	size     float64
+	table    [][]string // cells of a table found on the page
+	image    string     // link of an image drawn on the page
+	source   string     // key of the image in the document
+	heading  int        // level of a heading of the outline
+	markdown string     // text of a scanned image, transcribed by OCR
+}
+
+// fontStyle returns the weight and the slant of a font from its name, like
+// Times-BoldItalic or Arial,Bold.
+func fontStyle(font string) (bool, bool) {
+	name := strings.ToLower(font)
+	bold := false
+	for _, s := range []string{"bold", "black", "heavy", "semibold", "demi"} {
+		if strings.Contains(name, s) {
+			bold = true
+		}
+	}
+	italic := strings.Contains(name, "italic") || strings.Contains(name, "oblique")
+	return bold, italic
+}
+
+// glyphWidth returns the width of a glyph, estimated when the font has no widths.
+func glyphWidth(t PdfGlyph) float64 {
+	if t.W > 0 {
+		return t.W
+	}
+	return t.FontSize * 0.5 * float64(len([]rune(t.S)))
+}
+
+// text returns the words of the line separated by spaces.
+func (l *pdfLine) text() string {
+	words := make([]string, len(l.words))
+	for i, w := range l.words {
+		words[i] = w.text
+	}
+	return strings.Join(words, " ")
+}
+
+// block reports whether the line is a table, an image or a transcribed scan,
+// placed as a whole.
+func (l *pdfLine) block() bool {
+	return l.table != nil || l.image != "" || l.markdown != ""
+}
+
+// add appends a word to the line and updates its bounds.
+func (l *pdfLine) add(w pdfWord) {
+	if len(l.words) == 0 || w.x0 < l.x0 {
+		l.x0 = w.x0
+	}
+	if len(l.words) == 0 || w.x1 > l.x1 {
+		l.x1 = w.x1
+	}
+	if len(l.words) == 0 || w.size > l.size {
+		l.size = w.size
+		l.y = w.y
+	}
+	l.words = append(l.words, w)
+}
+
+// pageWords groups the glyphs of a page into words. Glyphs are drawn one by
+// one without spaces: a word ends on a gap, a change of baseline or of font.
+func pageWords(texts []PdfGlyph) []pdfWord {
+	var words []pdfWord
+	var cur *pdfWord
+	var font string
+	for _, t := range texts {
+		if strings.TrimSpace(t.S) == "" || t.FontSize <= 0 {
+			cur = nil
+			continue
+		}
+		if cur != nil {
+			gap := t.X - cur.x1
+			sameLine := abs(t.Y-cur.y) < 0.3*t.FontSize
+			if sameLine && font == t.Font && gap < 0.15*t.FontSize && gap > -0.5*t.FontSize {
+				cur.text += t.S
+				cur.x1 = t.X + glyphWidth(t)
+				continue
+			}
+		}
+		bold, italic := fontStyle(t.Font)
+		words = append(words, pdfWord{
+			text: t.S, x0: t.X, x1: t.X + glyphWidth(t), y: t.Y,
+			size: t.FontSize, bold: bold, italic: italic,
+		})
+		cur = &words[len(words)-1]
+		font = t.Font
+	}
+	return words
+}
+
+// pageLines returns the lines of a page in reading order: from top to bottom,
+// and column by column for multi-column layouts. Tables and images are single
+// lines.
+func pageLines(content PdfContent, images []pdfLine) []pdfLine {
+	rows := pageTables(baselineRows(pageWords(content.Glyphs)), content.Rects)
+	rows = append(rows, images...)
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].y > rows[j].y
+	})
+	return readingOrder(rows, findGutters(rows))
+}
+
+// baselineRows groups words into lines, from top to bottom. Words of a same
+// line are sorted from left to right.
+func baselineRows(words []pdfWord) []pdfLine {
+	// words of the same baseline may be drawn in any order
+	sort.SliceStable(words, func(i, j int) bool {
+		return words[i].y > words[j].y
+	})
+	var lines []pdfLine
+	for _, w := range words {
+		if n := len(lines); n > 0 && abs(lines[n-1].y-w.y) < 0.3*max(w.size, lines[n-1].size) {
+			lines[n-1].add(w)
+			continue
+		}
+		var line pdfLine
+		line.add(w)
+		lines = append(lines, line)
+	}
+	for i := range lines {
+		sort.SliceStable(lines[i].words, func(a, b int) bool {
+			return lines[i].words[a].x0 < lines[i].words[b].x0
+		})
+		// glyphs of a word may be split by a font change inside the word
+		lines[i].words = joinWords(lines[i].words)
+	}
+	return lines
+}
+
+// joinWords merges the consecutive words of a line without gap.
+func joinWords(words []pdfWord) []pdfWord {
+	var out []pdfWord
+	for _, w := range words {
+		if n := len(out); n > 0 && w.x0-out[n-1].x1 < 0.15*w.size && w.x0 >= out[n-1].x0 &&
+			out[n-1].bold == w.bold && out[n-1].italic == w.italic {
+			out[n-1].text += w.text
+			out[n-1].x1 = max(out[n-1].x1, w.x1)
+			continue
+		}
+		out = append(out, w)
+	}
+	return out
+}
+
+// abs returns the absolute value of x.
+func abs(x float64) float64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,155 @@
+package tools
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// PdfToc writes a markdown table of contents built from the outline of PDF files
+var PdfToc bool
+
+// pdfEntry is a bookmark of the outline of a PDF document
+type pdfEntry struct {
+	title string
+	level int
+	page  int
+	top   float64 // +Inf for the top of the page
+	slug  string
+}
+
+// pdfOutline is the outline of a PDF document and the targets of its internal links
+type pdfOutline struct {
+	entries    []pdfEntry
+	depth      int
+	slugs      map[string]int
+	referenced map[int]bool          // pages targeted by links, outside of the headings
+	file       func(page int) string // file of a page in another part of a split document
+}
+
+// matchKey returns the letters and digits of a text, to compare titles and lines.
+func matchKey(s string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// newPdfOutline returns the outline of a document from its bookmarks. Page
+// headings are anchors too, their slugs are reserved.
+func newPdfOutline(bookmarks []PdfBookmark, numPage int) *pdfOutline {
+	o := &pdfOutline{slugs: make(map[string]int), referenced: make(map[int]bool)}
+	for i := 1; i <= numPage && !PdfPageMarkers; i++ {
+		o.slugs[fmt.Sprintf("page-%d", i)]++
+	}
+	for _, b := range bookmarks {
+		slug := UniqueSlug(o.slugs, b.Title)
+		level := min(b.Level, 6)
+		o.entries = append(o.entries, pdfEntry{title: b.Title, level: level, page: b.Dest.Page, top: b.Dest.Top, slug: slug})
+		o.depth = max(o.depth, level)
+	}
+	return o
+}
+
+// anchor returns the link to a destination: the heading of the outline at
+// this position, or else the page.
+func (o *pdfOutline) anchor(page int, top float64) string {
+	for _, e := range o.entries {
+		if e.page == page && (e.top == top || abs(e.top-top) <= 20) {
+			return o.target(page) + "#" + e.slug
+		}
+	}
+	o.referenced[page] = true
+	return fmt.Sprintf("%s#page-%d", o.target(page), page)
+}
+
+// target returns the file of a page for the links from another part, empty
+// in the same part.
+func (o *pdfOutline) target(page int) string {
+	if o.file == nil {
+		return ""
+	}
+	return o.file(page)
+}
+
+// addLinks sets the targets of the link annotations of a page on the words
+// they cover: web links, or internal links to an anchor.
+func (o *pdfOutline) addLinks(links []PdfLink, lines []pdfLine) {
+	for _, link := range links {
+		target := link.URI
+		if target == "" {
+			target = o.anchor(link.Dest.Page, link.Dest.Top)
+		}
+		r := link.Rect
+		for l := range lines {
+			for j, w := range lines[l].words {
+				if center := (w.x0 + w.x1) / 2; center >= r.X0 && center <= r.X1 && w.y >= r.Y0 && w.y <= r.Y1 {
+					lines[l].words[j].link = target
+				}
+			}
+		}
+	}
+}
+
+// addHeadings places the headings of the outline on a page: the lines of the
+// title below the destination become a heading, or the title is inserted
+// when it is not found.
+func (o *pdfOutline) addHeadings(page *pdfPage) {
+	for _, e := range o.entries {
+		if e.page != page.number {
+			continue
+		}
+		title := matchKey(e.title)
+		start := len(page.lines)
+		for i, line := range page.lines {
+			if !line.block() && line.heading == 0 && line.y <= e.top+2 {
+				start = i
+				break
+			}
+		}
+		heading := pdfLine{words: []pdfWord{{text: e.title}}, y: e.top, heading: e.level}
+		at, end := start, start
+		for i := start; i < len(page.lines) && at == end; i++ {
+			text := ""
+			for j := i; j < len(page.lines) && !page.lines[j].block() && page.lines[j].heading == 0; j++ {
+				text += matchKey(page.lines[j].text())
+				if text == "" || !strings.HasPrefix(title, text) {
+					break
+				}
+				if text == title {
+					// the title may be written on several lines
+					heading = page.lines[i]
+					heading.heading = e.level
+					for _, line := range page.lines[i+1 : j+1] {
+						heading.words = append(heading.words, line.words...)
+					}
+					at, end = i, j+1
+					break
+				}
+			}
+		}
+		lines := append([]pdfLine{}, page.lines[:at]...)
+		lines = append(lines, heading)
+		page.lines = append(lines, page.lines[end:]...)
+	}
+}
+
+// writeToc writes the table of contents built from the outline, for the
+// converted pages.
+func (o *pdfOutline) writeToc(w io.Writer, pages []int) {
+	converted := make(map[int]bool)
+	for _, page := range pages {
+		converted[page] = true
+	}
+	for _, e := range o.entries {
+		if !converted[e.page] {
+			continue
+		}
+		fmt.Fprintf(w, "%s- [%s](%s#%s)\n", strings.Repeat("  ", e.level-1), Escape(e.title, pdfEscaped), o.target(e.page), e.slug)
+	}
+	fmt.Fprint(w, "\n")
+}
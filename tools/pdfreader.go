@@ -0,0 +1,428 @@
+package tools
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+	log "github.com/sirupsen/logrus"
+)
+
+// PdfPassword is the user password of encrypted PDF files
+var PdfPassword string
+
+// PDFExtractor reads the content of a PDF document. The errors of malformed
+// documents are returned, the parser never panics.
+type PDFExtractor interface {
+	// NumPage returns the number of pages of the document
+	NumPage() int
+	// Page returns what is drawn on a page, numbered from 1
+	Page(number int) (PdfContent, error)
+	// Image returns an image of a page as a JPEG or a PNG file, and its extension
+	Image(key string) ([]byte, string, error)
+	// Outline returns the bookmarks of the document, depth first
+	Outline() ([]PdfBookmark, error)
+}
+
+// pdfReader is the PDFExtractor of the github.com/ledongthuc/pdf parser
+type pdfReader struct {
+	file      io.ReaderAt
+	size      int64
+	reader    *pdf.Reader
+	encrypted bool
+	pages     map[string]int       // page number by object reference
+	images    map[string]pdf.Value // image streams by key
+}
+
+// pdfObject matches the start of the objects of a PDF file
+var pdfObject = regexp.MustCompile(`(?:^|\s)(\d+)\s+(\d+)\s+obj\b`)
+
+// NewPDFExtractor opens a PDF document, with its user password when it is
+// encrypted. A broken cross-reference table is rebuilt from the objects of the file.
+func NewPDFExtractor(file io.ReaderAt, size int64, password string) (PDFExtractor, error) {
+	r, err := openPdf(file, size, password)
+	if errors.Is(err, pdf.ErrInvalidPassword) {
+		if password == "" {
+			return nil, fmt.Errorf("%w, the password is set with --pdf-password", err)
+		}
+		return nil, err
+	}
+	if err == nil {
+		return r, nil
+	}
+
+	xref, ok := repairXref(file, size)
+	if !ok {
+		return nil, err
+	}
+	repaired := &appendedFile{file: file, size: size, tail: xref}
+	r, repairErr := openPdf(repaired, size+int64(len(xref)), password)
+	if repairErr != nil {
+		return nil, err
+	}
+	log.Infof("Error %s when reading the PDF file, cross-reference table rebuilt", err)
+	return r, nil
+}
+
+// objectRef returns the reference of an indirect object, like "12 0 R". The
+// library keeps it unexported, it is read by reflection. Direct objects are
+// identified by their content.
+func objectRef(v pdf.Value) string {
+	ptr := reflect.ValueOf(v).FieldByName("ptr")
+	if !ptr.IsValid() || ptr.NumField() < 2 || ptr.Field(0).Uint() == 0 {
+		return v.String()
+	}
+	return fmt.Sprintf("%d %d R", ptr.Field(0).Uint(), ptr.Field(1).Uint())
+}
+
+// recoverError turns a panic of the parser into an error.
+func recoverError(err *error) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("malformed PDF: %v", r)
+	}
+}
+
+// openPdf opens a document and reads its page tree, where most broken
+// cross-reference tables fail.
+func openPdf(file io.ReaderAt, size int64, password string) (r *pdfReader, err error) {
+	defer recoverError(&err)
+	tried := false
+	reader, err := pdf.NewReaderEncrypted(file, size, func() string {
+		// the empty password is tried first, then the given one
+		if tried {
+			return ""
+		}
+		tried = true
+		return password
+	})
+	if err != nil {
+		return nil, err
+	}
+	r = &pdfReader{
+		file: file, size: size, reader: reader, encrypted: !reader.Trailer().Key("Encrypt").IsNull(),
+		pages: make(map[string]int), images: make(map[string]pdf.Value),
+	}
+	if reader.NumPage() == 0 {
+		return nil, fmt.Errorf("malformed PDF: no page found")
+	}
+	for i := 1; i <= reader.NumPage(); i++ {
+		page := reader.Page(i)
+		if page.V.Kind() != pdf.Dict {
+			return nil, fmt.Errorf("malformed PDF: page %d not found", i)
+		}
+		r.pages[objectRef(page.V)] = i
+	}
+	return r, nil
+}
+
+// pdfTrailerRef, pdfID and pdfCatalog match the entries of the trailer and
+// the catalog, for the repair of the cross-reference table
+var (
+	pdfTrailerRef = regexp.MustCompile(`/(Root|Info|Encrypt)\s+(\d+\s+\d+\s+R)`)
+	pdfID         = regexp.MustCompile(`/ID\s*\[[^\]]*\]`)
+	pdfCatalog    = regexp.MustCompile(`/Type\s*/Catalog\b`)
+)
+
+// The file is scanned by chunks of repairChunk bytes, read with repairOverlap
+// bytes of the chunks around them so that the matches across chunks are found
+const (
+	repairChunk   = 1 << 20
+	repairOverlap = 1024
+)
+
+// appendedFile is a file followed by the bytes appended to it, without copy
+type appendedFile struct {
+	file io.ReaderAt
+	size int64
+	tail []byte
+}
+
+func (f *appendedFile) ReadAt(p []byte, off int64) (int, error) {
+	n := 0
+	if off < f.size {
+		var err error
+		n, err = f.file.ReadAt(p[:min(int64(len(p)), f.size-off)], off)
+		if err != nil && err != io.EOF {
+			return n, err
+		}
+		off += int64(n)
+	}
+	if n < len(p) && off >= f.size && off-f.size < int64(len(f.tail)) {
+		n += copy(p[n:], f.tail[off-f.size:])
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// scanPdf reads a file by chunks and calls found with the matches of the
+// regular expressions starting in each chunk, at the offset of their first
+// group in the file.
+func scanPdf(file io.ReaderAt, size int64, found func(re *regexp.Regexp, m [][]byte, offset int64), res ...*regexp.Regexp) error {
+	buf := make([]byte, repairChunk+2*repairOverlap)
+	for start := int64(0); start < size; start += repairChunk {
+		from := max(start-repairOverlap, 0)
+		n, err := file.ReadAt(buf[:min(int64(len(buf)), size-from)], from)
+		if err != nil && err != io.EOF {
+			return err
+		}
+		chunk := buf[:n]
+		for _, re := range res {
+			for _, m := range re.FindAllSubmatchIndex(chunk, -1) {
+				// the matches of the overlaps are found with the chunks around
+				if at := from + int64(m[0]); at < start || at >= start+repairChunk {
+					continue
+				}
+				groups := make([][]byte, len(m)/2)
+				for i := range groups {
+					if m[2*i] >= 0 {
+						groups[i] = chunk[m[2*i]:m[2*i+1]]
+					}
+				}
+				// the offset of the first group, without the spaces before it
+				at := m[0]
+				if len(m) > 2 && m[2] >= 0 {
+					at = m[2]
+				}
+				found(re, groups, from+int64(at))
+			}
+		}
+	}
+	return nil
+}
+
+// repairXref returns a cross-reference table of the objects found in a PDF
+// file, to append to the file. The last definition of an object wins like in
+// incremental updates. The objects of object streams are not found. The
+// file is read by chunks, it is never held in memory.
+func repairXref(file io.ReaderAt, size int64) ([]byte, bool) {
+	offsets := make(map[int]int64)
+	gens := make(map[int]int)
+	count := 1
+	var starts []int64            // offsets of the objects, in the file order
+	ids := make(map[int64]string) // reference of an object by offset
+	refs := make(map[string]string)
+	id, catalog := "", int64(-1)
+	err := scanPdf(file, size, func(re *regexp.Regexp, m [][]byte, offset int64) {
+		switch re {
+		case pdfObject:
+			n, err1 := strconv.Atoi(string(m[1]))
+			gen, err2 := strconv.Atoi(string(m[2]))
+			if err1 != nil || err2 != nil || n <= 0 || n > 1e7 || gen > 65535 {
+				return
+			}
+			offsets[n], gens[n] = offset, gen
+			count = max(count, n+1)
+			starts = append(starts, offset)
+			ids[offset] = fmt.Sprintf("%d %d R", n, gen)
+		case pdfTrailerRef:
+			// the entries of the last trailer or cross-reference stream
+			refs[string(m[1])] = string(m[2])
+		case pdfID:
+			id = string(m[0])
+		case pdfCatalog:
+			if catalog < 0 {
+				catalog = offset
+			}
+		}
+	}, pdfObject, pdfTrailerRef, pdfID, pdfCatalog)
+	if err != nil || len(offsets) == 0 {
+		return nil, false
+	}
+	slices.Sort(starts)
+
+	trailer := ""
+	for _, key := range []string{"Root", "Info", "Encrypt"} {
+		if ref, ok := refs[key]; ok {
+			trailer += fmt.Sprintf(" /%s %s", key, ref)
+		}
+	}
+	if id != "" {
+		trailer += " " + id
+	}
+	if refs["Root"] == "" {
+		// the catalog is the object holding its type
+		i, _ := slices.BinarySearch(starts, catalog)
+		if catalog < 0 || i == 0 {
+			return nil, false
+		}
+		trailer += " /Root " + ids[starts[i-1]]
+	}
+
+	var b bytes.Buffer
+	b.WriteString("\n")
+	xref := size + int64(b.Len())
+	fmt.Fprintf(&b, "xref\n0 %d\n", count)
+	for n := 0; n < count; n++ {
+		if off, ok := offsets[n]; ok {
+			fmt.Fprintf(&b, "%010d %05d n \n", off, gens[n])
+		} else {
+			b.WriteString("0000000000 65535 f \n")
+		}
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d%s >>\nstartxref\n%d\n%%%%EOF\n", count, trailer, xref)
+	return b.Bytes(), true
+}
+
+// NumPage returns the number of pages of the document.
+func (r *pdfReader) NumPage() int {
+	return len(r.pages)
+}
+
+// Page returns the glyphs, rectangles, images and links of a page.
+func (r *pdfReader) Page(number int) (content PdfContent, err error) {
+	defer recoverError(&err)
+	page := r.reader.Page(number)
+	c := page.Content()
+	for _, t := range c.Text {
+		content.Glyphs = append(content.Glyphs, PdfGlyph{Font: t.Font, FontSize: t.FontSize, X: t.X, Y: t.Y, W: t.W, S: t.S})
+	}
+	for _, rect := range c.Rect {
+		content.Rects = append(content.Rects, PdfRect{
+			X0: min(rect.Min.X, rect.Max.X), Y0: min(rect.Min.Y, rect.Max.Y),
+			X1: max(rect.Min.X, rect.Max.X), Y1: max(rect.Min.Y, rect.Max.Y),
+		})
+	}
+	content.Images = r.pageImages(page, number)
+	content.Links = r.pageLinks(page)
+	return content, nil
+}
+
+// Image returns an image found on a page.
+func (r *pdfReader) Image(key string) (b []byte, ext string, err error) {
+	defer recoverError(&err)
+	xobj, ok := r.images[key]
+	if !ok {
+		return nil, "", fmt.Errorf("unknown image %s", key)
+	}
+	return r.imageData(xobj)
+}
+
+// Outline returns the bookmarks of the document.
+func (r *pdfReader) Outline() (bookmarks []PdfBookmark, err error) {
+	defer recoverError(&err)
+	visited := make(map[string]bool)
+	return r.readEntries(r.reader.Trailer().Key("Root").Key("Outlines"), 1, visited, nil), nil
+}
+
+// readEntries reads the children of an outline item, depth first. Items are
+// read once: a loop in a malformed outline must end.
+func (r *pdfReader) readEntries(item pdf.Value, level int, visited map[string]bool, bookmarks []PdfBookmark) []PdfBookmark {
+	for child := item.Key("First"); child.Kind() == pdf.Dict && len(visited) < 10000 && level < 64; child = child.Key("Next") {
+		ref := objectRef(child)
+		if visited[ref] {
+			break
+		}
+		visited[ref] = true
+		title := strings.TrimSpace(child.Key("Title").Text())
+		dest := child.Key("Dest")
+		if action := child.Key("A"); dest.IsNull() && action.Key("S").Name() == "GoTo" {
+			dest = action.Key("D")
+		}
+		if d, ok := r.destination(dest); ok && title != "" {
+			bookmarks = append(bookmarks, PdfBookmark{Title: title, Level: level, Dest: d})
+		}
+		bookmarks = r.readEntries(child, level+1, visited, bookmarks)
+	}
+	return bookmarks
+}
+
+// named returns a named destination, from the Dests dictionary or the Dests name tree.
+func (r *pdfReader) named(name string) pdf.Value {
+	root := r.reader.Trailer().Key("Root")
+	if dest := root.Key("Dests").Key(name); !dest.IsNull() {
+		return dest
+	}
+	var search func(node pdf.Value, depth int) pdf.Value
+	search = func(node pdf.Value, depth int) pdf.Value {
+		names := node.Key("Names")
+		for i := 0; i+1 < names.Len(); i += 2 {
+			if names.Index(i).RawString() == name {
+				return names.Index(i + 1)
+			}
+		}
+		kids := node.Key("Kids")
+		for i := 0; i < kids.Len() && depth < 32; i++ {
+			if dest := search(kids.Index(i), depth+1); !dest.IsNull() {
+				return dest
+			}
+		}
+		return pdf.Value{}
+	}
+	return search(root.Key("Names").Key("Dests"), 0)
+}
+
+// destination returns the page and the top position of a destination.
+func (r *pdfReader) destination(dest pdf.Value) (PdfDest, bool) {
+	switch dest.Kind() {
+	case pdf.String:
+		dest = r.named(dest.RawString())
+	case pdf.Name:
+		dest = r.named(dest.Name())
+	}
+	if dest.Kind() == pdf.Dict {
+		dest = dest.Key("D")
+	}
+	if dest.Kind() != pdf.Array || dest.Len() < 2 {
+		return PdfDest{}, false
+	}
+	page := 0
+	if ref := dest.Index(0); ref.Kind() == pdf.Integer {
+		page = int(ref.Int64()) + 1
+	} else {
+		page = r.pages[objectRef(ref)]
+	}
+	if page == 0 {
+		return PdfDest{}, false
+	}
+	top := math.Inf(1)
+	position := map[string]int{"XYZ": 3, "FitH": 2, "FitBH": 2, "FitR": 5}
+	if i, ok := position[dest.Index(1).Name()]; ok {
+		if v := dest.Index(i); v.Kind() == pdf.Integer || v.Kind() == pdf.Real {
+			top = v.Float64()
+		}
+	}
+	return PdfDest{Page: page, Top: top}, true
+}
+
+// pageLinks returns the link annotations of a page: web links, or internal
+// links to a destination.
+func (r *pdfReader) pageLinks(page pdf.Page) []PdfLink {
+	var links []PdfLink
+	annots := page.V.Key("Annots")
+	for i := 0; i < annots.Len(); i++ {
+		annot := annots.Index(i)
+		rect := annot.Key("Rect")
+		if annot.Key("Subtype").Name() != "Link" || rect.Len() != 4 {
+			continue
+		}
+		link := PdfLink{Rect: PdfRect{
+			X0: min(rect.Index(0).Float64(), rect.Index(2).Float64()), Y0: min(rect.Index(1).Float64(), rect.Index(3).Float64()),
+			X1: max(rect.Index(0).Float64(), rect.Index(2).Float64()), Y1: max(rect.Index(1).Float64(), rect.Index(3).Float64()),
+		}}
+		dest := annot.Key("Dest")
+		switch action := annot.Key("A"); action.Key("S").Name() {
+		case "URI":
+			link.URI = action.Key("URI").RawString()
+		case "GoTo":
+			dest = action.Key("D")
+		}
+		ok := link.URI != ""
+		if !ok {
+			link.Dest, ok = r.destination(dest)
+		}
+		if ok {
+			links = append(links, link)
+		}
+	}
+	return links
+}
@@ -0,0 +1,308 @@
+package tools
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// PdfPageMarkers replaces the "Page N" headings of PDF files by HTML comments,
+// so that the text of the pages is continuous
+var PdfPageMarkers bool
+
+// pdfPage is the text lines of a PDF page in reading order, and their paragraphs
+type pdfPage struct {
+	number     int
+	lines      []pdfLine
+	paragraphs [][]pdfLine
+	anchor     bool     // the page is the target of links
+	images     []string // links of the images saved for the page
+}
+
+// pdfCounts is the characters and line spacings of lines
+type pdfCounts struct {
+	chars  map[float64]int    // characters by font size
+	deltas map[[2]float64]int // distance between the baselines of lines, by font size
+}
+
+// newPdfCounts returns empty counts.
+func newPdfCounts() *pdfCounts {
+	return &pdfCounts{chars: make(map[float64]int), deltas: make(map[[2]float64]int)}
+}
+
+// pdfStats is the font statistics of a PDF document, used to find headings
+type pdfStats struct {
+	pdfCounts
+	edges   map[string]*pdfCounts // counts of the lines at the edges of the pages, by running key
+	body    float64
+	leading float64
+	levels  map[float64]int // heading level by font size
+	bold    int             // heading level of bold lines of the body size
+	shift   int             // levels of the outline headings, above the font headings
+}
+
+// roundSize rounds a font size to half points.
+func roundSize(size float64) float64 {
+	return math.Round(size*2) / 2
+}
+
+// newPdfStats returns empty statistics.
+func newPdfStats() *pdfStats {
+	return &pdfStats{pdfCounts: *newPdfCounts(), edges: make(map[string]*pdfCounts), levels: make(map[float64]int)}
+}
+
+// counts returns the counts of the line of a key at the edges of the page,
+// or the counts of the document.
+func (s *pdfStats) counts(line pdfLine, edges map[string]bool) *pdfCounts {
+	if len(edges) == 0 {
+		return &s.pdfCounts
+	}
+	key := runningKey(line)
+	if !edges[key] {
+		return &s.pdfCounts
+	}
+	if s.edges[key] == nil {
+		s.edges[key] = newPdfCounts()
+	}
+	return s.edges[key]
+}
+
+// add counts the characters of the lines of a page by font size. The lines
+// at the edges of the page are counted by key until the running lines are
+// known.
+func (s *pdfStats) add(lines []pdfLine, edges map[string]bool) {
+	for i, line := range lines {
+		if line.heading > 0 {
+			continue
+		}
+		counts := s.counts(line, edges)
+		for _, w := range line.words {
+			counts.chars[roundSize(w.size)] += len([]rune(w.text))
+		}
+		if size := roundSize(line.size); i > 0 && roundSize(lines[i-1].size) == size && !line.block() && !lines[i-1].block() {
+			counts.deltas[[2]float64{size, math.Round(lines[i-1].y - line.y)}]++
+		}
+	}
+}
+
+// compute finds the body size, the line spacing and the heading levels: the
+// most used size is the body text, bigger sizes are headings, below the
+// headings of the outline. The running lines are not counted.
+func (s *pdfStats) compute(r *pdfRunning) {
+	for key, counts := range s.edges {
+		if r.running(key) {
+			continue
+		}
+		for size, n := range counts.chars {
+			s.chars[size] += n
+		}
+		for delta, n := range counts.deltas {
+			s.deltas[delta] += n
+		}
+	}
+	clear(s.edges)
+	for size, n := range s.chars {
+		if n > s.chars[s.body] || (n == s.chars[s.body] && size < s.body) {
+			s.body = size
+		}
+	}
+	s.leading = 1.2 * s.body
+	best := 0
+	for key, n := range s.deltas {
+		// the line spacing of single spaced body text
+		delta := key[1]
+		if key[0] == s.body && delta >= s.body*0.9 && delta <= s.body*2 && (n > best || (n == best && delta < s.leading)) {
+			s.leading, best = delta, n
+		}
+	}
+	var sizes []float64
+	for size := range s.chars {
+		if size > s.body+0.5 {
+			sizes = append(sizes, size)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(sizes)))
+	for i, size := range sizes {
+		s.levels[size] = min(i+1+s.shift, 6)
+	}
+	s.bold = min(len(sizes)+1+s.shift, 6)
+}
+
+// lineSpacing returns the usual distance between lines of the given size.
+func (s *pdfStats) lineSpacing(size float64) float64 {
+	if s.body == 0 {
+		return 1.2 * size
+	}
+	return s.leading * size / s.body
+}
+
+// allBold reports whether all the words of a line are bold.
+func (l *pdfLine) allBold() bool {
+	for _, w := range l.words {
+		if !w.bold {
+			return false
+		}
+	}
+	return len(l.words) > 0
+}
+
+// paragraphs groups the lines of a page in paragraphs: a paragraph ends on a
+// larger space between lines, a change of font size or of weight. Tables and
+// images are paragraphs of their own.
+func (s *pdfStats) paragraphs(lines []pdfLine) [][]pdfLine {
+	var paragraphs [][]pdfLine
+	for i, line := range lines {
+		if i > 0 {
+			prev := lines[i-1]
+			delta := prev.y - line.y
+			if !prev.block() && !line.block() && prev.heading == 0 && line.heading == 0 && delta > 0 && delta <= 1.3*s.lineSpacing(max(prev.size, line.size)) &&
+				roundSize(prev.size) == roundSize(line.size) && prev.allBold() == line.allBold() {
+				paragraphs[len(paragraphs)-1] = append(paragraphs[len(paragraphs)-1], line)
+				continue
+			}
+		}
+		paragraphs = append(paragraphs, []pdfLine{line})
+	}
+	return paragraphs
+}
+
+// headingLevel returns the heading level of a paragraph, 0 for body text.
+func (s *pdfStats) headingLevel(paragraph []pdfLine) int {
+	if paragraph[0].block() {
+		return 0
+	}
+	if paragraph[0].heading > 0 {
+		return paragraph[0].heading
+	}
+	length := 0
+	bold := true
+	for _, line := range paragraph {
+		length += len([]rune(line.text()))
+		bold = bold && line.allBold()
+	}
+	if level, ok := s.levels[roundSize(paragraph[0].size)]; ok && length <= 200 {
+		return level
+	}
+	// short bold lines of the body size
+	if bold && len(paragraph) == 1 && length <= 100 && roundSize(paragraph[0].size) == s.body {
+		return s.bold
+	}
+	return 0
+}
+
+// pdfEscaped are the markdown characters escaped in the text of PDF files
+const pdfEscaped = "\\*_~[]`"
+
+// blockStart matches the start of a paragraph read as a heading, a quote or a
+// list item
+var blockStart = regexp.MustCompile(`^(?:[#>]|[-+](?:\s|$)|\d+[.)](?:\s|$))`)
+
+// escapeBlockStart escapes the start of a paragraph that is not a heading, a
+// quote or a list item.
+func escapeBlockStart(s string) string {
+	if m := blockStart.FindStringIndex(s); m != nil {
+		i := m[1] - 1
+		for i > 0 && !strings.ContainsRune("#>-+.)", rune(s[i])) {
+			i--
+		}
+		return s[:i] + `\` + s[i:]
+	}
+	return s
+}
+
+// styledText returns the words of the lines with bold and italic runs, and
+// the links over the words.
+func styledText(lines []pdfLine) string {
+	words := paragraphWords(lines)
+	var parts []string
+	for i := 0; i < len(words); {
+		j := i
+		for j < len(words) && words[j].link == words[i].link {
+			j++
+		}
+		text := styledWords(words[i:j])
+		if link := words[i].link; link != "" {
+			text = "[" + text + "](" + Escape(link, "()") + ")"
+		}
+		parts = append(parts, text)
+		i = j
+	}
+	return strings.Join(parts, " ")
+}
+
+// styledWords returns the words with bold and italic runs.
+func styledWords(words []pdfWord) string {
+	var parts []string
+	var run []string
+	var bold, italic bool
+	flush := func() {
+		if len(run) == 0 {
+			return
+		}
+		marker := ""
+		if bold {
+			marker += "**"
+		}
+		if italic {
+			marker += "*"
+		}
+		parts = append(parts, marker+strings.Join(run, " ")+marker)
+		run = nil
+	}
+	for _, w := range words {
+		if w.bold != bold || w.italic != italic {
+			flush()
+			bold, italic = w.bold, w.italic
+		}
+		run = append(run, Escape(w.text, pdfEscaped))
+	}
+	flush()
+	return strings.Join(parts, " ")
+}
+
+// plainLines returns the words of the lines, without formatting.
+func plainLines(lines []pdfLine) string {
+	var text []string
+	for _, w := range paragraphWords(lines) {
+		text = append(text, Escape(w.text, pdfEscaped))
+	}
+	return strings.Join(text, " ")
+}
+
+// writePdfPage writes a page as markdown, under a "Page N" heading or after a
+// page marker.
+func (s *pdfStats) writePdfPage(page pdfPage, w io.Writer) {
+	offset := 0
+	if PdfPageMarkers {
+		fmt.Fprintf(w, "<!-- Page %d -->\n\n", page.number)
+		if page.anchor {
+			fmt.Fprintf(w, "<a id=\"page-%d\"></a>\n\n", page.number)
+		}
+	} else {
+		fmt.Fprintf(w, "# Page %d\n\n", page.number) //  Add Title for each page
+		offset = 1
+	}
+	for _, paragraph := range page.paragraphs {
+		if table := paragraph[0].table; table != nil {
+			writeMdTable(table, w)
+			fmt.Fprint(w, "\n")
+			continue
+		}
+		if image := paragraph[0].image; image != "" {
+			fmt.Fprintf(w, "![](%s)\n\n", image)
+			continue
+		}
+		if markdown := paragraph[0].markdown; markdown != "" {
+			fmt.Fprintf(w, "%s\n\n", shiftHeadings(markdown, offset))
+			continue
+		}
+		if level := s.headingLevel(paragraph); level > 0 {
+			fmt.Fprintf(w, "%s %s\n\n", strings.Repeat("#", min(level+offset, 6)), plainLines(paragraph))
+			continue
+		}
+		fmt.Fprintf(w, "%s\n\n", escapeBlockStart(styledText(paragraph)))
+	}
+}
@@ -0,0 +1,301 @@
+package tools
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// PdfSplitPages splits the markdown of PDF files in a file per N pages, 0 writes a single file
+var PdfSplitPages int
+
+// PdfSplitChapters splits the markdown of PDF files in a file per chapter, the first level of the outline
+var PdfSplitChapters bool
+
+// PdfProgress reports the conversion of PDF files after each page: the pass,
+// 1 or 2, and the pages done
+var PdfProgress func(pass, done, total int)
+
+// pdfSplit is how the markdown of a document is split in parts
+type pdfSplit struct {
+	pages    int
+	chapters bool
+}
+
+// pdfPart is a part of the markdown of a document, from its first page
+type pdfPart struct {
+	number int    // from 1
+	first  int    // first page
+	title  string // title of the chapter or range of pages, empty before the first chapter
+}
+
+// pdfOutput receives the markdown of a document, part by part
+type pdfOutput interface {
+	// split receives the parts before they are written, and returns their
+	// files for the links between the parts
+	split(parts []pdfPart) []string
+	// start returns the writer of a part
+	start(part pdfPart) (io.Writer, error)
+	// end ends the current part, with the links of its saved images
+	end(images []string) error
+}
+
+// pdfConverter converts a PDF document holding one page at a time, in two
+// passes over the pages: the running lines, the links and the font
+// statistics, then the markdown.
+type pdfConverter struct {
+	extractor PDFExtractor
+	ocr       OCR
+	outline   *pdfOutline
+	images    *pdfImages
+	running   *pdfRunning
+	stats     *pdfStats
+	pages     []int       // pages to write
+	parts     []pdfPart   // parts of the markdown
+	partOf    map[int]int // index of the part by page
+	current   int         // index of the part of the page being read, for its links
+}
+
+// convertPdf writes the markdown of a PDF file in out. Images are saved in
+// assetsDir, an empty assetsDir disables their extraction.
+func convertPdf(pdfPath string, assetsDir string, split pdfSplit, out pdfOutput) error {
+	if err := checkPdfTables(); err != nil {
+		return err
+	}
+
+	// Ouvrir le fichier PDF
+	file, err := os.Open(pdfPath)
+	if err != nil {
+		return fmt.Errorf("can't open PDF file   : %w", err)
+	}
+	defer file.Close()
+
+	// Read PDF document
+	filestat, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("can't open PDF file   : %w", err)
+	}
+	extractor, err := NewPDFExtractor(file, filestat.Size(), PdfPassword)
+	if err != nil {
+		return fmt.Errorf("can't read and parse PDF file : %w", err)
+	}
+	numbers, err := pageRange(PdfPages, extractor.NumPage())
+	if err != nil {
+		return err
+	}
+	ocr, err := newOCR()
+	if err != nil {
+		return err
+	}
+	bookmarks, err := extractor.Outline()
+	if err != nil {
+		log.Infof("Error %s when reading the outline of the PDF file", err)
+	}
+
+	// headings depend on the outline and on the font sizes of the whole document
+	c := &pdfConverter{
+		extractor: extractor, ocr: ocr, outline: newPdfOutline(bookmarks, extractor.NumPage()),
+		images: newPdfImages(extractor, assetsDir), running: newPdfRunning(), stats: newPdfStats(),
+	}
+	c.stats.shift = c.outline.depth
+	if err := c.analyse(numbers); err != nil {
+		return err
+	}
+	c.split(split)
+	if files := out.split(c.parts); files != nil {
+		c.outline.file = func(page int) string {
+			if part, ok := c.partOf[page]; ok && part != c.current {
+				return files[part]
+			}
+			return ""
+		}
+	}
+	return c.write(out)
+}
+
+// progress reports a page done.
+func (c *pdfConverter) progress(pass, done, total int) {
+	if PdfProgress != nil {
+		PdfProgress(pass, done, total)
+	}
+}
+
+// readPage returns the lines of a page, false for a page without content.
+// Until the last pass, scans and images are placeholders. A malformed page
+// is lost, not the document: its error is logged.
+func (c *pdfConverter) readPage(number int, last bool) (pdfPage, bool, error) {
+	content, err := c.extractor.Page(number)
+	if err != nil {
+		log.Infof("Error %s when reading page %d", err, number)
+		return pdfPage{}, false, fmt.Errorf("page %d : %w", number, err)
+	}
+	log.Debugf("Page %d : %d glyphs, %d images\n", number, len(content.Glyphs), len(content.Images))
+	var images []pdfLine
+	if content.Glyphs == nil && content.Images != nil {
+		// a scanned page
+		images = scanLines(c.ocr, c.extractor, content.Images, number, last)
+	}
+	if images == nil {
+		images = c.images.pageLines(content.Images, number, last)
+	}
+	if content.Glyphs == nil && images == nil {
+		return pdfPage{}, false, nil
+	}
+	lines := pageLines(content, images)
+	c.outline.addLinks(content.Links, lines)
+	return pdfPage{number: number, lines: lines}, true, nil
+}
+
+// preparePage returns the lines of a page to write, without the running
+// lines, with the headings of the outline. A malformed page is skipped like
+// in the first pass.
+func (c *pdfConverter) preparePage(number int) (pdfPage, bool) {
+	page, ok, _ := c.readPage(number, true)
+	if !ok {
+		return page, false
+	}
+	page.lines = c.running.remove(page.lines)
+	c.outline.addHeadings(&page)
+	return page, true
+}
+
+// analyse counts the running lines and the font statistics, and finds the
+// pages to write and the targets of the links.
+func (c *pdfConverter) analyse(numbers []int) error {
+	var pageErr error
+	for i, number := range numbers {
+		page, ok, err := c.readPage(number, false)
+		if err != nil && pageErr == nil {
+			pageErr = err
+		}
+		if ok {
+			c.pages = append(c.pages, number)
+			edges := c.running.add(page.lines)
+			c.outline.addHeadings(&page)
+			c.stats.add(page.lines, edges)
+		}
+		c.progress(1, i+1, len(numbers))
+	}
+	if len(c.pages) == 0 && pageErr != nil {
+		return fmt.Errorf("can't read PDF pages : %w", pageErr)
+	}
+	c.stats.compute(c.running)
+	return nil
+}
+
+// split divides the pages in parts: a part per N pages, or a part per chapter
+// starting at the page of the chapter.
+func (c *pdfConverter) split(split pdfSplit) {
+	var chapters []pdfEntry
+	for _, e := range c.outline.entries {
+		if e.level == 1 && split.chapters {
+			chapters = append(chapters, e)
+		}
+	}
+	sort.SliceStable(chapters, func(i, j int) bool {
+		return chapters[i].page < chapters[j].page
+	})
+
+	c.partOf = make(map[int]int)
+	c.parts = []pdfPart{{number: 1}}
+	last := 0 // last page of the current part
+	for i, number := range c.pages {
+		title, chapter := "", false
+		for ; len(chapters) > 0 && chapters[0].page <= number; chapters = chapters[1:] {
+			if !chapter {
+				title, chapter = chapters[0].title, true
+			}
+		}
+		part := &c.parts[len(c.parts)-1]
+		switch {
+		case i == 0:
+			part.first, part.title = number, title
+		case chapter, split.pages > 0 && i%split.pages == 0:
+			if split.pages > 0 {
+				part.title = fmt.Sprintf("pages %d-%d", part.first, last)
+			}
+			c.parts = append(c.parts, pdfPart{number: len(c.parts) + 1, first: number, title: title})
+		}
+		c.partOf[number] = len(c.parts) - 1
+		last = number
+	}
+	if split.pages > 0 && len(c.parts) > 1 {
+		part := &c.parts[len(c.parts)-1]
+		part.title = fmt.Sprintf("pages %d-%d", part.first, last)
+	}
+}
+
+// write writes the pages part by part. A page is written once the next one
+// is read: a paragraph cut by the page break ends on its first page.
+func (c *pdfConverter) write(out pdfOutput) error {
+	var w io.Writer
+	var images []string
+	writing := -1 // part being written
+	flush := func(page pdfPage) error {
+		if part := c.partOf[page.number]; part != writing {
+			if writing >= 0 {
+				if err := out.end(images); err != nil {
+					return err
+				}
+			}
+			images, writing = nil, part
+			var err error
+			if w, err = out.start(c.parts[part]); err != nil {
+				return err
+			}
+			if PdfToc && part == 0 && len(c.outline.entries) > 0 {
+				// the links of the table of contents start from the first part
+				reading := c.current
+				c.current = 0
+				c.outline.writeToc(w, c.pages)
+				c.current = reading
+			}
+		}
+		page.anchor = c.outline.referenced[page.number]
+		c.stats.writePdfPage(page, w)
+		images = append(images, page.images...)
+		return nil
+	}
+
+	var pending *pdfPage
+	for i, number := range c.pages {
+		c.current = c.partOf[number]
+		page, ok := c.preparePage(number)
+		var err error
+		if page.images, err = c.images.save(page.lines); err != nil {
+			return err
+		}
+		if ok {
+			page.paragraphs = c.stats.paragraphs(page.lines)
+			if pending != nil && c.partOf[pending.number] == c.current {
+				prev, cur := pending.paragraphs, page.paragraphs
+				if len(prev) > 0 && len(cur) > 0 && c.stats.continues(prev[len(prev)-1], cur[0]) {
+					prev[len(prev)-1] = append(prev[len(prev)-1], cur[0]...)
+					page.paragraphs = cur[1:]
+				}
+			}
+			if pending != nil {
+				if err := flush(*pending); err != nil {
+					return err
+				}
+			}
+			pending = &page
+		}
+		c.progress(2, i+1, len(c.pages))
+	}
+	if pending != nil {
+		if err := flush(*pending); err != nil {
+			return err
+		}
+	}
+	if writing < 0 {
+		// a document without text still has its part
+		if _, err := out.start(c.parts[0]); err != nil {
+			return err
+		}
+	}
+	return out.end(images)
+}
@@ -0,0 +1,105 @@
+package tools
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// splitPdf builds a document of four pages with two chapters, and a link from
+// the first chapter to the second
+func splitPdf(t *testing.T) string {
+	return buildPdfDoc(t, pdfDoc{
+		pages: [][]pdfText{
+			{{"F2", 18, 72, 720, "Installation"}, {"F1", 10, 72, 700, "Read the usage first."}},
+			{{"F1", 10, 72, 700, "Copy the binary."}},
+			{{"F2", 18, 72, 720, "Usage"}, {"F1", 10, 72, 700, "Run the command."}},
+			{{"F1", 10, 72, 700, "Check the result."}},
+		},
+		links: [][]pdfLink{{
+			{[4]float64{70, 695, 200, 710}, "/Dest [10 0 R /XYZ 0 740 0]"},
+		}},
+		outline: []pdfItem{
+			{title: "Installation", dest: "/Dest [6 0 R /XYZ 0 740 0]"},
+			{title: "Usage", dest: "/Dest [10 0 R /XYZ 0 740 0]"},
+		},
+	})
+}
+
+// TestPdf2mdWriter test the markdown is written page by page, with the progress of each pass
+func TestPdf2mdWriter(t *testing.T) {
+	pdfFile := splitPdf(t)
+	var calls []int
+	PdfProgress = func(pass, done, total int) {
+		if total != 4 || done < 1 || done > total {
+			t.Errorf("expected a page of 4, got %d/%d", done, total)
+		}
+		calls = append(calls, pass)
+	}
+	defer func() { PdfProgress = nil }()
+
+	var w bytes.Buffer
+	if err := Pdf2mdWriter(pdfFile, "", &w); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := "# Page 1\n\n## Installation\n\n[Read the usage first.](#usage)\n\n# Page 2\n\nCopy the binary.\n\n" +
+		"# Page 3\n\n## Usage\n\nRun the command.\n\n# Page 4\n\nCheck the result.\n\n"
+	if w.String() != want {
+		t.Errorf("expected %q, got %q", want, w.String())
+	}
+	if len(calls) != 8 || calls[0] != 1 || calls[7] != 2 {
+		t.Errorf("expected 8 pages done in 2 passes, got %v", calls)
+	}
+}
+
+// TestGetPDF_Split test the markdown files per N pages and per chapter, with the links between the files
+func TestGetPDF_Split(t *testing.T) {
+	pdfFile := splitPdf(t)
+	defer func() { PdfSplitPages, PdfSplitChapters = 0, false }()
+
+	tests := []struct {
+		pages    int
+		chapters bool
+		want     map[string]string // content by file
+	}{
+		{0, false, map[string]string{
+			"pdf-manual.md": "---\n# Page 1\n\n## Installation\n\n[Read the usage first.](#usage)\n",
+		}},
+		{3, false, map[string]string{
+			"pdf-manual-pages-1-3.md": "title: manual - pages 1-3\n",
+			"pdf-manual-pages-4-4.md": "# Page 4\n\nCheck the result.\n",
+		}},
+		{0, true, map[string]string{
+			"pdf-manual-instalation.md": "[Read the usage first.](pdf-manual-usage.md#usage)\n\n# Page 2\n\nCopy the binary.\n\n",
+			"pdf-manual-usage.md":       "---\n# Page 3\n\n## Usage\n\nRun the command.\n",
+		}},
+	}
+	for _, test := range tests {
+		PdfSplitPages, PdfSplitChapters = test.pages, test.chapters
+		dir := t.TempDir()
+		manual := filepath.Join(dir, "manual.pdf")
+		if err := os.Rename(pdfFile, manual); err != nil {
+			t.Fatal(err)
+		}
+		pages, err := GetPDF(manual, "", "pdf", dir, Metadata{})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(pages) != len(test.want) {
+			t.Errorf("expected %d files, got %d", len(test.want), len(pages))
+		}
+		for _, page := range pages {
+			b, err := os.ReadFile(page.MdFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, ok := test.want[filepath.Base(page.MdFile)]
+			if !ok || !strings.HasPrefix(string(b), "---\n") || !strings.Contains(string(b), want) {
+				t.Errorf("expected %s to contain %q, got %q", filepath.Base(page.MdFile), want, b)
+			}
+		}
+		pdfFile = manual
+	}
+}
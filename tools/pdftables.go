@@ -0,0 +1,414 @@
+package tools
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// PdfTables is the detection of the tables of PDF files: off, lines for the
+// tables drawn with ruling lines, stream to also find the tables without
+// lines from the alignment of the text
+var PdfTables = "lines"
+
+// pdfRule is a ruling line of a page, from a thin rectangle or the border of a cell
+type pdfRule struct {
+	horizontal bool
+	pos        float64 // y of horizontal rules, x of vertical rules
+	from, to   float64
+}
+
+// listMarker matches the bullets and numbers of list items
+var listMarker = regexp.MustCompile(`^([•·▪‣◦*–-]|\d+[.)]|[a-zA-Z][.)])$`)
+
+// checkPdfTables checks the table detection mode.
+func checkPdfTables() error {
+	switch PdfTables {
+	case "off", "lines", "stream":
+		return nil
+	}
+	return fmt.Errorf("unknown PDF tables detection %q, expected off, lines or stream", PdfTables)
+}
+
+// rulings returns the ruling lines drawn with rectangles. Big rectangles are
+// cells, their four borders are rules.
+func rulings(rects []PdfRect) []pdfRule {
+	const thin = 2.0
+	var rules []pdfRule
+	for _, r := range rects {
+		x0, x1, y0, y1 := r.X0, r.X1, r.Y0, r.Y1
+		switch w, h := x1-x0, y1-y0; {
+		case h <= thin && w > thin:
+			rules = append(rules, pdfRule{true, (y0 + y1) / 2, x0, x1})
+		case w <= thin && h > thin:
+			rules = append(rules, pdfRule{false, (x0 + x1) / 2, y0, y1})
+		case w > thin && h > thin:
+			rules = append(rules, pdfRule{true, y0, x0, x1}, pdfRule{true, y1, x0, x1},
+				pdfRule{false, x0, y0, y1}, pdfRule{false, x1, y0, y1})
+		}
+	}
+	return rules
+}
+
+// touches reports whether two rules belong to the same table: crossing rules,
+// or horizontal rules of the same width.
+func (r pdfRule) touches(o pdfRule) bool {
+	const tol = 2.0
+	if r.horizontal && o.horizontal {
+		return abs(r.from-o.from) <= tol && abs(r.to-o.to) <= tol
+	}
+	if r.horizontal == o.horizontal {
+		return false
+	}
+	h, v := r, o
+	if !h.horizontal {
+		h, v = o, r
+	}
+	return v.pos >= h.from-tol && v.pos <= h.to+tol && h.pos >= v.from-tol && h.pos <= v.to+tol
+}
+
+// ruleGroups groups the rules of each table.
+func ruleGroups(rules []pdfRule) [][]pdfRule {
+	parent := make([]int, len(rules))
+	for i := range parent {
+		parent[i] = i
+	}
+	var root func(i int) int
+	root = func(i int) int {
+		if parent[i] != i {
+			parent[i] = root(parent[i])
+		}
+		return parent[i]
+	}
+	for i := range rules {
+		for j := i + 1; j < len(rules); j++ {
+			if rules[i].touches(rules[j]) {
+				parent[root(j)] = root(i)
+			}
+		}
+	}
+	groups := make(map[int][]pdfRule)
+	var order []int
+	for i, r := range rules {
+		k := root(i)
+		if _, ok := groups[k]; !ok {
+			order = append(order, k)
+		}
+		groups[k] = append(groups[k], r)
+	}
+	var out [][]pdfRule
+	for _, k := range order {
+		out = append(out, groups[k])
+	}
+	return out
+}
+
+// distinct returns the sorted positions, without the positions closer than 2pt.
+func distinct(positions []float64) []float64 {
+	sort.Float64s(positions)
+	var out []float64
+	for _, p := range positions {
+		if n := len(out); n == 0 || p-out[n-1] > 2 {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// interior returns the positions inside the bounds.
+func interior(positions []float64, from, to float64) []float64 {
+	var out []float64
+	for _, p := range positions {
+		if p > from+2 && p < to-2 {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// alignedSeparators returns the blank bands crossing all the rows, between the
+// columns of a table.
+func alignedSeparators(rows []pdfLine) []float64 {
+	if len(rows) == 0 {
+		return nil
+	}
+	minX, maxX, size := textArea(rows)
+	covered := make([]bool, int(math.Ceil(maxX-minX))+1)
+	for _, row := range rows {
+		cover(covered, row.words, minX)
+	}
+	var separators []float64
+	start := -1
+	for x := 0; x < len(covered); x++ {
+		if !covered[x] {
+			if start < 0 {
+				start = x
+			}
+			continue
+		}
+		// wider than the spaces between words
+		if start > 0 && float64(x-start) >= 0.3*size {
+			separators = append(separators, minX+float64(start+x)/2)
+		}
+		start = -1
+	}
+	return separators
+}
+
+// columnOf returns the column of a word.
+func columnOf(w pdfWord, separators []float64) int {
+	center := (w.x0 + w.x1) / 2
+	col := 0
+	for _, s := range separators {
+		if center > s {
+			col++
+		}
+	}
+	return col
+}
+
+// splitRows reports whether all the rows have words in several columns.
+func splitRows(rows []pdfLine, separators []float64) bool {
+	for _, row := range rows {
+		cols := make(map[int]bool)
+		for _, w := range row.words {
+			cols[columnOf(w, separators)] = true
+		}
+		if len(cols) < 2 {
+			return false
+		}
+	}
+	return true
+}
+
+// alignedTable reports whether rows split by blank bands are a table, not
+// columns of text or a list.
+func alignedTable(rows []pdfLine, separators []float64) bool {
+	if len(separators) == 0 {
+		return false
+	}
+	minX, maxX := rows[0].x0, rows[0].x1
+	gutters := make([]pdfGutter, len(separators))
+	for i, s := range separators {
+		gutters[i] = pdfGutter{s, s}
+	}
+	markers := true
+	for _, row := range rows {
+		minX, maxX = min(minX, row.x0), max(maxX, row.x1)
+		first := row.words[0]
+		markers = markers && columnOf(first, separators) == 0 && listMarker.MatchString(first.text)
+	}
+	return !markers && !textColumns(rows, gutters, minX, maxX)
+}
+
+// tableCells places the words of the rows in the cells of a table. Rows are
+// split by the horizontal rules, or by line without rules. Empty rows and
+// columns are dropped.
+func tableCells(rows []pdfLine, separators, rules []float64) [][]string {
+	var cells [][]string
+	index := -1
+	for i, row := range rows {
+		r := i
+		if rules != nil {
+			// rules above the row
+			r = 0
+			for _, y := range rules {
+				if y > row.y {
+					r++
+				}
+			}
+		}
+		if r != index {
+			cells = append(cells, make([]string, len(separators)+1))
+			index = r
+		}
+		cur := cells[len(cells)-1]
+		for _, w := range row.words {
+			col := columnOf(w, separators)
+			cur[col] = strings.TrimSpace(cur[col] + " " + w.text)
+		}
+	}
+
+	used := make([]bool, len(separators)+1)
+	var out [][]string
+	for _, row := range cells {
+		if strings.Join(row, "") == "" {
+			continue
+		}
+		for col, c := range row {
+			used[col] = used[col] || c != ""
+		}
+		out = append(out, row)
+	}
+	for i, row := range out {
+		var kept []string
+		for col, c := range row {
+			if used[col] {
+				kept = append(kept, c)
+			}
+		}
+		out[i] = kept
+	}
+	if len(out) < 2 || len(out[0]) < 2 {
+		return nil
+	}
+	return out
+}
+
+// tableLine returns a line holding a table, placed at its first row.
+func tableLine(rows []pdfLine, cells [][]string) pdfLine {
+	line := pdfLine{table: cells, x0: rows[0].x0, x1: rows[0].x1, y: rows[0].y, size: rows[0].size}
+	for _, row := range rows {
+		line.x0, line.x1 = min(line.x0, row.x0), max(line.x1, row.x1)
+	}
+	return line
+}
+
+// ruledTables finds the tables drawn with ruling lines and replaces their words
+// by the tables. Columns without vertical rules are found from the alignment.
+func ruledTables(rows []pdfLine, rects []PdfRect) []pdfLine {
+	var tables []pdfLine
+	for _, group := range ruleGroups(rulings(rects)) {
+		var ys, xs []float64
+		x0, x1, y0, y1 := math.Inf(1), math.Inf(-1), math.Inf(1), math.Inf(-1)
+		for _, r := range group {
+			if r.horizontal {
+				ys = append(ys, r.pos)
+				x0, x1, y0, y1 = min(x0, r.from), max(x1, r.to), min(y0, r.pos), max(y1, r.pos)
+			} else {
+				xs = append(xs, r.pos)
+				x0, x1, y0, y1 = min(x0, r.pos), max(x1, r.pos), min(y0, r.from), max(y1, r.to)
+			}
+		}
+		if len(distinct(ys)) < 2 {
+			continue
+		}
+
+		// words inside the table
+		var inside, outside []pdfLine
+		for _, row := range rows {
+			if row.block() {
+				outside = append(outside, row)
+				continue
+			}
+			var in, out pdfLine
+			for _, w := range row.words {
+				if center := (w.x0 + w.x1) / 2; center > x0 && center < x1 && w.y > y0 && w.y < y1 {
+					in.add(w)
+				} else {
+					out.add(w)
+				}
+			}
+			if len(in.words) > 0 {
+				inside = append(inside, in)
+			}
+			if len(out.words) > 0 {
+				outside = append(outside, out)
+			}
+		}
+		if len(inside) == 0 {
+			continue
+		}
+
+		separators := interior(distinct(xs), x0, x1)
+		var rules []float64
+		if len(separators) > 0 {
+			rules = interior(distinct(ys), y0, y1)
+		} else if separators = alignedSeparators(inside); !alignedTable(inside, separators) {
+			continue
+		}
+		if cells := tableCells(inside, separators, rules); cells != nil {
+			tables = append(tables, tableLine(inside, cells))
+			rows = outside
+		}
+	}
+	rows = append(rows, tables...)
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].y > rows[j].y
+	})
+	return rows
+}
+
+// streamTables finds the tables without lines: consecutive rows split by the
+// same blank bands.
+func streamTables(rows []pdfLine) []pdfLine {
+	var out []pdfLine
+	for i := 0; i < len(rows); {
+		end := i
+		var separators []float64
+		for j := i + 1; j <= len(rows); j++ {
+			if rows[j-1].block() {
+				break
+			}
+			// rows of a table are close
+			if j > i+1 {
+				prev, row := rows[j-2], rows[j-1]
+				if delta := prev.y - row.y; delta <= 0 || delta > 2.5*max(prev.size, row.size) {
+					break
+				}
+			}
+			s := alignedSeparators(rows[i:j])
+			if len(s) == 0 || !splitRows(rows[i:j], s) {
+				break
+			}
+			end, separators = j, s
+		}
+		if end-i >= 3 && alignedTable(rows[i:end], separators) {
+			if cells := tableCells(rows[i:end], separators, nil); cells != nil {
+				out = append(out, tableLine(rows[i:end], cells))
+				i = end
+				continue
+			}
+		}
+		out = append(out, rows[i])
+		i++
+	}
+	return out
+}
+
+// pageTables replaces the words of the tables of a page by the tables.
+func pageTables(rows []pdfLine, rects []PdfRect) []pdfLine {
+	switch PdfTables {
+	case "lines":
+		return ruledTables(rows, rects)
+	case "stream":
+		return streamTables(ruledTables(rows, rects))
+	}
+	return rows
+}
+
+// writeMdTable writes a table as markdown, the first row is the header.
+func writeMdTable(rows [][]string, w io.Writer) {
+	widths := make([]int, len(rows[0]))
+	for j := range widths {
+		widths[j] = 3
+	}
+	for _, row := range rows {
+		for j, c := range row {
+			widths[j] = max(widths[j], runewidth.StringWidth(escapeCell(c)))
+		}
+	}
+	for i, row := range rows {
+		for j, c := range row {
+			c = escapeCell(c)
+			fmt.Fprint(w, "|", c, strings.Repeat(" ", widths[j]-runewidth.StringWidth(c)))
+		}
+		fmt.Fprint(w, "|\n")
+		if i == 0 {
+			for _, width := range widths {
+				fmt.Fprint(w, "|", strings.Repeat("-", width))
+			}
+			fmt.Fprint(w, "|\n")
+		}
+	}
+}
+
+// escapeCell escapes the pipes of a table cell.
+func escapeCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
@@ -368,6 +368,52 @@ func BuildFilename(title string, dir string, id string) string {
 	return filename
 }
 
+// BuildAssetsDir build the folder storing the images of a document, next to its markdown files
+func BuildAssetsDir(docPath string, dir string, id string) string {
+	name := strings.TrimSuffix(filepath.Base(docPath), filepath.Ext(docPath))
+	return strings.TrimSuffix(BuildFilename(name, dir, id), ".md") + "-assets"
+}
+
+// Escape escapes the characters in a string using the given set of characters.
+func Escape(s, set string) string {
+	replacer := []string{}
+	for _, r := range []rune(set) {
+		rs := string(r)
+		replacer = append(replacer, rs, `\`+rs)
+	}
+	return strings.NewReplacer(replacer...).Replace(s)
+}
+
+// Slugify build a github like anchor from a heading text
+func Slugify(s string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(s)) {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '_', r == '-':
+			sb.WriteRune(r)
+		case r == ' ':
+			sb.WriteRune('-')
+		}
+	}
+	return sb.String()
+}
+
+// UniqueSlug returns the anchor of a heading text, with a number suffix when
+// it is already in use, and counts it in slugs.
+func UniqueSlug(slugs map[string]int, s string) string {
+	base := Slugify(s)
+	slug := base
+	// the suffix of a heading may be the slug of another one
+	for n := slugs[base]; slugs[slug] > 0; n++ {
+		slug = fmt.Sprintf("%s-%d", base, n)
+	}
+	slugs[base]++
+	if slug != base {
+		slugs[slug]++
+	}
+	return slug
+}
+
 // RemoveAccents remove accents from a string
 func RemoveAccents(s string) string {
 	// transform to NFD unicode format
@@ -43,3 +43,24 @@ func TestReplaceAllMultipleChars_RemovesConsecutiveChars(t *testing.T) {
 		t.Errorf("expected %s, got %s", expected, result)
 	}
 }
+
+// TestUniqueSlug test the anchors of headings with the same text
+func TestUniqueSlug(t *testing.T) {
+	slugs := map[string]int{"page-1": 1}
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Intro", "intro"},
+		{"Intro", "intro-1"},
+		{"Intro-1", "intro-1-1"},
+		{"Intro", "intro-2"},
+		{"Page 1", "page-1-1"},
+		{"Été, 2024 !", "été-2024-"},
+	}
+	for _, test := range tests {
+		if got := UniqueSlug(slugs, test.text); got != test.want {
+			t.Errorf("expected %q, got %q", test.want, got)
+		}
+	}
+}